@@ -0,0 +1,233 @@
+// Package analytics aggregates the paradata of a survey wave -
+// page finishing times and current pages of all participants -
+// into a dropout funnel and time-on-page statistics per page;
+// overall and broken down by device, language and login attributes.
+package analytics
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/store"
+)
+
+// PageStatsT contains funnel and timing of one page;
+// durations are in seconds
+type PageStatsT struct {
+	Page     int `json:"page"`     // zero based
+	Reached  int `json:"reached"`  // participants who got to this page
+	Finished int `json:"finished"` // participants who left this page forward
+	Dropped  int `json:"dropped"`  // unfinished participants, who are still on this page
+
+	Timings int     `json:"timings"` // number of durations
+	Q1      float64 `json:"q1"`
+	Median  float64 `json:"median"`
+	Q3      float64 `json:"q3"`
+}
+
+// SegmentT is the funnel for a subset of participants -
+// or for all participants, if Dimension is empty
+type SegmentT struct {
+	Dimension    string       `json:"dimension,omitempty"` // mobile, lang_code or the name of a login attribute
+	Value        string       `json:"value,omitempty"`
+	Participants int          `json:"participants"`
+	Completed    int          `json:"completed"` // closed questionnaires
+	Pages        []PageStatsT `json:"pages"`
+
+	durations [][]float64
+}
+
+// ReportT is the analytics of a survey wave
+type ReportT struct {
+	SurveyID   string        `json:"survey_id"`
+	WaveID     string        `json:"wave_id"`
+	Total      *SegmentT     `json:"total"`
+	Breakdowns []*SegmentT   `json:"breakdowns"`
+	Unreadable []UnreadableT `json:"unreadable,omitempty"` // not counted anywhere
+}
+
+// UnreadableT is a response file which could not be loaded
+type UnreadableT struct {
+	Key   string `json:"key"`
+	Error string `json:"error"`
+}
+
+// mobileLabel - see qst.QuestionnaireT.Mobile
+func mobileLabel(m int) string {
+	switch m {
+	case 1:
+		return "desktop"
+	case 2:
+		return "mobile"
+	}
+	return "unknown"
+}
+
+// CollectorT aggregates questionnaires one by one;
+// see Add() and Report()
+type CollectorT struct {
+	attrs    []string // login attributes to break down by; nil for all
+	total    *SegmentT
+	segments map[string]*SegmentT // key dimension:value
+}
+
+// NewCollector breaks down by the login attributes attrs;
+// for attrs == nil by all login attributes - except survey_id and wave_id
+func NewCollector(attrs []string) *CollectorT {
+	return &CollectorT{
+		attrs:    attrs,
+		total:    &SegmentT{},
+		segments: map[string]*SegmentT{},
+	}
+}
+
+func (c *CollectorT) segment(dim, val string) *SegmentT {
+	key := dim + ":" + val
+	if _, ok := c.segments[key]; !ok {
+		c.segments[key] = &SegmentT{Dimension: dim, Value: val}
+	}
+	return c.segments[key]
+}
+
+// Add aggregates the paradata of q
+func (c *CollectorT) Add(q *qst.QuestionnaireT) {
+
+	c.total.add(q)
+	c.segment("mobile", mobileLabel(q.Mobile)).add(q)
+	c.segment("lang_code", q.LangCode).add(q)
+
+	if c.attrs != nil {
+		for _, k := range c.attrs {
+			c.segment(k, q.Attrs[k]).add(q)
+		}
+		return
+	}
+	for k, v := range q.Attrs {
+		if k == "survey_id" || k == "wave_id" {
+			continue
+		}
+		c.segment(k, v).add(q)
+	}
+}
+
+// add counts q into the funnel of the segment
+func (sg *SegmentT) add(q *qst.QuestionnaireT) {
+
+	for len(sg.Pages) < len(q.Pages) {
+		sg.Pages = append(sg.Pages, PageStatsT{Page: len(sg.Pages)})
+		sg.durations = append(sg.durations, nil)
+	}
+
+	sg.Participants++
+	closed := !q.ClosingTime.IsZero()
+	if closed {
+		sg.Completed++
+	}
+
+	// furthest page - Finished is set when leaving a page forward
+	furthest := q.CurrPage
+	for i, p := range q.Pages {
+		if !p.Finished.IsZero() && i+1 > furthest {
+			furthest = i + 1
+		}
+	}
+	if furthest > len(q.Pages)-1 {
+		furthest = len(q.Pages) - 1
+	}
+
+	for i, p := range q.Pages {
+		if i <= furthest {
+			sg.Pages[i].Reached++
+		}
+		if !p.Finished.IsZero() {
+			sg.Pages[i].Finished++
+		}
+		if !closed && i == furthest {
+			sg.Pages[i].Dropped++
+		}
+		// the first page has no start time
+		if i > 0 && !p.Finished.IsZero() && !q.Pages[i-1].Finished.IsZero() {
+			dur := p.Finished.Sub(q.Pages[i-1].Finished).Seconds()
+			if dur >= 0 {
+				sg.durations[i] = append(sg.durations[i], dur)
+			}
+		}
+	}
+}
+
+// quantile of sorted values; linear interpolation between closest ranks
+func quantile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := p * float64(len(sorted)-1)
+	lo := int(pos)
+	if lo+1 >= len(sorted) {
+		return sorted[lo]
+	}
+	return sorted[lo] + (pos-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
+
+func (sg *SegmentT) computeTimings() {
+	for i := range sg.Pages {
+		durs := sg.durations[i]
+		sort.Float64s(durs)
+		sg.Pages[i].Timings = len(durs)
+		sg.Pages[i].Q1 = quantile(durs, 0.25)
+		sg.Pages[i].Median = quantile(durs, 0.5)
+		sg.Pages[i].Q3 = quantile(durs, 0.75)
+	}
+}
+
+// Report computes the timing statistics;
+// breakdowns are sorted by dimension and value
+func (c *CollectorT) Report(surveyID, waveID string) *ReportT {
+
+	rep := &ReportT{
+		SurveyID: surveyID,
+		WaveID:   waveID,
+		Total:    c.total,
+	}
+	c.total.computeTimings()
+	for _, sg := range c.segments {
+		sg.computeTimings()
+		rep.Breakdowns = append(rep.Breakdowns, sg)
+	}
+	sort.Slice(rep.Breakdowns, func(i, j int) bool {
+		if rep.Breakdowns[i].Dimension != rep.Breakdowns[j].Dimension {
+			return rep.Breakdowns[i].Dimension < rep.Breakdowns[j].Dimension
+		}
+		return rep.Breakdowns[i].Value < rep.Breakdowns[j].Value
+	})
+	return rep
+}
+
+// Wave reports on all participants of a survey wave - dropouts being the point;
+// response files which cannot be loaded are listed in Unreadable;
+// attrs as in NewCollector()
+func Wave(surveyID, waveID string, attrs []string) (*ReportT, error) {
+
+	pth := path.Join(qst.BasePath(), surveyID, waveID)
+	entries, err := store.Get().List(pth)
+	if err != nil {
+		return nil, fmt.Errorf("could not read directory %v: %v", pth, err)
+	}
+
+	c := NewCollector(attrs)
+	unreadable := []UnreadableT{}
+	for _, info := range entries {
+		q, err := qst.Load1(info.Key)
+		if err != nil {
+			unreadable = append(unreadable, UnreadableT{Key: info.Key, Error: err.Error()})
+			continue
+		}
+		c.Add(q)
+	}
+	rep := c.Report(surveyID, waveID)
+	if len(unreadable) > 0 {
+		rep.Unreadable = unreadable
+	}
+	return rep, nil
+}
@@ -0,0 +1,121 @@
+package analytics
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/store"
+)
+
+func TestFunnel(t *testing.T) {
+
+	t0 := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	// newQ has three pages; page i < finished is left after secs[i] seconds
+	newQ := func(finished int, secs []int, closed bool, lc string) *qst.QuestionnaireT {
+		q := &qst.QuestionnaireT{LangCode: lc, Attrs: map[string]string{"survey_id": "fmt", "country": lc}}
+		ts := t0
+		for i := 0; i < 3; i++ {
+			p := q.AddPage()
+			if i < finished {
+				ts = ts.Add(time.Duration(secs[i]) * time.Second)
+				p.Finished = ts
+			}
+		}
+		q.CurrPage = finished
+		if q.CurrPage > 2 {
+			q.CurrPage = 2
+		}
+		if closed {
+			q.ClosingTime = ts
+		}
+		return q
+	}
+
+	c := NewCollector(nil)
+	c.Add(newQ(0, nil, false, "de"))             // dropped on page 0
+	c.Add(newQ(1, []int{5}, false, "de"))        // dropped on page 1
+	c.Add(newQ(3, []int{5, 10, 20}, true, "en")) // completed
+	c.Add(newQ(3, []int{5, 30, 40}, true, "de")) // completed
+	c.Add(newQ(2, []int{5, 50}, false, "en"))    // dropped on page 2
+	rep := c.Report("fmt", "2020-05")
+
+	tot := rep.Total
+	if tot.Participants != 5 || tot.Completed != 2 {
+		t.Errorf("participants %v, completed %v", tot.Participants, tot.Completed)
+	}
+	wantReached := []int{5, 4, 3}
+	wantDropped := []int{1, 1, 1}
+	for i, p := range tot.Pages {
+		if p.Reached != wantReached[i] || p.Dropped != wantDropped[i] {
+			t.Errorf("page %v: reached %v, dropped %v - want %v, %v", i, p.Reached, p.Dropped, wantReached[i], wantDropped[i])
+		}
+	}
+	// page 1 durations 10, 30, 50
+	if p := tot.Pages[1]; p.Timings != 3 || p.Median != 30 || p.Q1 != 20 || p.Q3 != 40 {
+		t.Errorf("page 1 timings: %+v", p)
+	}
+	if p := tot.Pages[0]; p.Timings != 0 {
+		t.Errorf("page 0 has no start time - got %v timings", p.Timings)
+	}
+
+	found := false
+	for _, sg := range rep.Breakdowns {
+		if sg.Dimension == "survey_id" {
+			t.Errorf("survey_id must not be broken down")
+		}
+		if sg.Dimension == "lang_code" && sg.Value == "en" {
+			found = true
+			if sg.Participants != 2 || sg.Completed != 1 {
+				t.Errorf("lang_code en: participants %v, completed %v", sg.Participants, sg.Completed)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("no breakdown by lang_code en")
+	}
+}
+
+func TestWave(t *testing.T) {
+
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+
+	dir := path.Join(qst.BasePath(), "fmt", "2020-05")
+	for i, mobile := range []int{0, 1, 2, 2} {
+		q := &qst.QuestionnaireT{UserID: fmt.Sprint(10001 + i), Mobile: mobile}
+		q.AddPage()
+		if err := q.Save1Unconditionally(path.Join(dir, q.UserID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Get().Write(path.Join(dir, "10009.json"), []byte(`{"user_id": `)); err != nil {
+		t.Fatal(err)
+	}
+
+	rep, err := Wave("fmt", "2020-05", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Total.Participants != 4 {
+		t.Errorf("want 4 participants - got %v", rep.Total.Participants)
+	}
+	if len(rep.Unreadable) != 1 || rep.Unreadable[0].Key != path.Join(dir, "10009.json") || rep.Unreadable[0].Error == "" {
+		t.Errorf("want 10009.json unreadable - got %+v", rep.Unreadable)
+	}
+
+	got := map[string]int{}
+	for _, sg := range rep.Breakdowns {
+		if sg.Dimension == "mobile" {
+			got[sg.Value] = sg.Participants
+		}
+	}
+	if want := map[string]int{"unknown": 1, "desktop": 1, "mobile": 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mobile breakdown: want %v - got %v", want, got)
+	}
+}
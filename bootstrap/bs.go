@@ -5,19 +5,36 @@
 package bootstrap
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"time"
 
 	"github.com/zew/go-questionnaire/cfg"
 	"github.com/zew/go-questionnaire/cloudio"
 	"github.com/zew/go-questionnaire/lgn"
 	"github.com/zew/go-questionnaire/sessx"
+	"github.com/zew/go-questionnaire/store"
 	"github.com/zew/go-questionnaire/tpl"
 	"github.com/zew/util"
 )
 
+// OpenStore applies storage_driver_url and database_url of the loaded config;
+// env vars STORAGE_DRIVER_URL and DATABASE_URL take precedence.
+func OpenStore() error {
+	if u := cfg.Get().StorageDriverURL; u != "" && os.Getenv("STORAGE_DRIVER_URL") == "" {
+		cloudio.SetStorageURL(u)
+	}
+	if u := cfg.Get().DatabaseURL; u != "" && os.Getenv("DATABASE_URL") == "" {
+		if err := store.Open(u); err != nil {
+			return fmt.Errorf("error opening store %v: %v", u, err)
+		}
+	}
+	return nil
+}
+
 // Config loads configuration and logins according to flags or env vars.
 func Config() {
 
@@ -63,6 +80,10 @@ func Config() {
 		log.Printf("opened reader to cloud config %v", fileName)
 		cfg.Load(r)
 
+		if err := OpenStore(); err != nil {
+			log.Fatal(err)
+		}
+
 		err = cloudio.MarshalWriteFile(cfg.Example(), "config-example.json")
 		if err != nil {
 			log.Printf("config example save: %v", err)
@@ -71,25 +92,10 @@ func Config() {
 
 	{
 		lgn.LgnsPath = fl.ByKey("lgn").Val
-		fileName := lgn.LgnsPath
-		r, bucketClose, err := cloudio.Open(fileName)
+		err := lgn.LoadFromStore()
 		if err != nil {
-			log.Fatalf("error opening writer to %v: %v", fileName, err)
+			log.Fatalf("error reading logins %v: %v", lgn.LgnsPath, err)
 		}
-		defer func() {
-			err := r.Close()
-			if err != nil {
-				log.Printf("error closing writer to bucket to %v: %v", fileName, err)
-			}
-		}()
-		defer func() {
-			err := bucketClose()
-			if err != nil {
-				log.Printf("error closing bucket of writer to %v: %v", fileName, err)
-			}
-		}()
-		log.Printf("opened reader to cloud config %v", fileName)
-		lgn.Load(r)
 
 		err = cloudio.MarshalWriteFile(lgn.Example(), "logins-example.json")
 		if err != nil {
@@ -6,6 +6,7 @@
 package cfg
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"log"
@@ -70,6 +71,17 @@ type ConfigT struct {
 
 	CPUProfile string `json:"cpu_profile"` // CPUProfile - output filename
 
+	// StorageDriverURL is a gocloud blob URL, i.e. s3://my-bucket?endpoint=minio.local:9000 - see package cloudio;
+	// applied after loading this config - thus logins and responses are read from there, as is this config on reload;
+	// env STORAGE_DRIVER_URL takes precedence and also applies to loading this config
+	StorageDriverURL string `json:"storage_driver_url,omitempty"`
+	CacheTemplates   bool   `json:"cache_templates,omitempty"` // keep questionnaire templates in memory - reloaded on modification
+
+	// DatabaseURL stores questionnaires and logins in a database instead of JSON files,
+	// i.e. sqlite://app-bucket/go-questionnaire.db - see package store;
+	// env DATABASE_URL takes precedence
+	DatabaseURL string `json:"database_url,omitempty"`
+
 	Mp     trl.Map     `json:"translations_generic"` // Mp     - multi language strings for entire application -       [key].Tr(lc)
 	MpSite trl.MapSite `json:"translations_site"`    // MpSite - multi language strings for specific survey -    [site][key].Tr(lc)
 
@@ -80,6 +92,7 @@ type ConfigT struct {
 	AllowSkipForward  bool                         `json:"allow_skip_forward"`            // AllowSkipForward- skipping back always allowed, skipping forward is configurable
 	AnonymousSurveyID string                       `json:"anonymous_survey_id,omitempty"` // AnonymousSurveyID - anonymous login - redirect / forward url
 	Profiles          map[string]map[string]string `json:"profiles"`                      // Profiles are sets of attributes, selected by the `p` parameter at login, containing key-values which are copied into the logged in user's attributes
+	ChangeLog         map[string]bool              `json:"change_log,omitempty"`          // ChangeLog - survey type => keep a log of all answer changes per participant
 	DirectLoginRanges []directLoginRangeT          `json:"direct_login_ranges,omitempty"` // DirectLoginRanges - user id to language preselection for direct login
 
 }
@@ -272,7 +285,7 @@ func Example() *ConfigT {
 		ReadHeaderTimeOut:      10,
 		WriteTimeOut:           60,
 		TimeOutUsual:           10,
-		TimeOutExceptions:      []string{"transferrer-endpoint", "download/", "download-stream/"},
+		TimeOutExceptions:      []string{"transferrer-endpoint", "export", "download/", "download-stream/"},
 		MaxPostSize:            int64(2 << 20), // 2 MB
 		LocationName:           "Europe/Berlin",
 		SessionTimeout:         2,
@@ -448,3 +461,13 @@ func Example() *ConfigT {
 	}
 	return ex
 }
+
+// LoadExample loads the Example() configuration;
+// for tests which require cfg.Get(), i.e. cfg.Get().Loc or cfg.Pref()
+func LoadExample() {
+	bts, err := json.Marshal(Example())
+	if err != nil {
+		log.Fatal(err)
+	}
+	Load(bytes.NewReader(bts))
+}
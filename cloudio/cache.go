@@ -0,0 +1,52 @@
+package cloudio
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type cachedFileT struct {
+	modTime time.Time
+	bts     []byte
+}
+
+// cache for ReadFileCached()
+var cache = struct {
+	sync.Mutex
+	files map[string]cachedFileT
+}{files: map[string]cachedFileT{}}
+
+// ReadFileCached is ReadFile with a read-through cache;
+// a cached file is re-read, if its modification time has changed;
+// thus one attributes request replaces the download.
+// Meant for small and frequently read files, such as questionnaire templates.
+//
+// The returned bytes are shared and must not be changed.
+func ReadFileCached(fileName string) ([]byte, error) {
+
+	buck, err := bucket()
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := buck.Attributes(context.Background(), fileName)
+	if err != nil {
+		return ReadFile(fileName) // not exist error as from ReadFile
+	}
+
+	cache.Lock()
+	cf, ok := cache.files[fileName]
+	cache.Unlock()
+	if ok && cf.modTime.Equal(attrs.ModTime) {
+		return cf.bts, nil
+	}
+
+	bts, err := ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	cache.Lock()
+	cache.files[fileName] = cachedFileT{modTime: attrs.ModTime, bts: bts}
+	cache.Unlock()
+	return bts, nil
+}
@@ -3,16 +3,24 @@
 //
 // It is zero config; either saving to local ./app-bucket/
 // or to appenginge bucket <appID>, depending on environment variables.
+// Environment variable STORAGE_DRIVER_URL overrides both with any gocloud blob URL:
+//    s3://my-bucket?endpoint=minio.local:9000&region=us-east-1&disableSSL=true&s3ForcePathStyle=true
+//    azblob://my-container
+//    gs://my-bucket
+//    file:///var/lib/go-questionnaire/bucket
+//    mem://
 //
 // The zero configuration is important, cause we load the *actual* configuration file
 // with this package, and want to avoid circular trouble or bootstrap hell.
+// The config may still switch the storage for everything else - see SetStorageURL().
 //
 // MarshalWriteFile and ReadFileUnmarshal incorporate
 // JSON serialization and deserialization.
 //
 // Open() is similar to file.Open
 //    r, err := file.Open("name")
-// but deviates in that is also returns a bucket closer func.
+// but deviates in that is also returns a bucket closer func;
+// it does nothing, since the bucket is opened once and kept until Shutdown().
 //
 // OpenAny() is just a wrapper arond Open() searching in various subdirectories.
 //
@@ -27,21 +35,35 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"cloud.google.com/go/storage"
 	"github.com/pkg/errors"
 	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/azureblob"
 	_ "gocloud.dev/blob/fileblob" // local file system
 	_ "gocloud.dev/blob/gcsblob"
+	"gocloud.dev/blob/memblob"
+	_ "gocloud.dev/blob/s3blob"
 )
 
 var appsID string // Google app engine ID
 
+// storageCfg holds a gocloud blob URL overriding appsID and ./app-bucket
+// and the long lived bucket; *blob.Bucket is safe for concurrent use
+var storageCfg = struct {
+	sync.Mutex
+	url  string
+	buck *blob.Bucket
+}{}
+
 func init() {
+	SetStorageURL(os.Getenv("STORAGE_DRIVER_URL"))
 	appsID = os.Getenv("GAE_APPLICATION")
 	if len(appsID) > 2 {
 		// chopping of g~ or h~ ...
@@ -86,7 +108,6 @@ func bucketLocal() (*blob.Bucket, error) {
 		return nil, err
 	}
 	storageDriverURL := fmt.Sprintf("file:///%s", filepath.Join(wd, "app-bucket")+"/") // relative directory not working on travis - but on appengine and windows
-	// storageDriverURL := cfg.Get().StorageDriverURL
 	bucket, err := blob.OpenBucket(ctx, storageDriverURL)
 	if err != nil {
 		return nil, fmt.Errorf("could not open local bucket for %v: %v", storageDriverURL, err)
@@ -102,7 +123,6 @@ func bucketGoogle() (*blob.Bucket, error) {
 			log.Print("SET GOOGLE_APPLICATION_CREDENTIALS=~/.ssh/google-cloud-[appname]-creds.json")
 		}
 	}
-	// storageDriverURL := cfg.Get().StorageDriverURL
 	storageDriverURL := fmt.Sprintf("gs://%s.appspot.com", appsID)
 	bucket, err := blob.OpenBucket(ctx, storageDriverURL)
 	if err != nil {
@@ -111,11 +131,87 @@ func bucketGoogle() (*blob.Bucket, error) {
 	return bucket, nil
 }
 
+// SetStorageURL switches all subsequent operations to the bucket at storageURL;
+// empty storageURL restores the zero config.
+// Each call with mem:// starts an empty in-memory bucket - i.e. for tests.
+//
+// The previous bucket is closed; operations still in flight on it fail.
+func SetStorageURL(storageURL string) {
+	storageCfg.Lock()
+	defer storageCfg.Unlock()
+	closeBucket()
+	storageCfg.url = storageURL
+	if storageURL != "" {
+		log.Printf("cloudio: storage is %v", storageURL)
+	}
+}
+
+// Shutdown closes the bucket;
+// to be called on server stop - after all requests are finished.
+func Shutdown() error {
+	storageCfg.Lock()
+	defer storageCfg.Unlock()
+	return closeBucket()
+}
+
+// closeBucket requires storageCfg to be locked
+func closeBucket() error {
+	cache.Lock()
+	cache.files = map[string]cachedFileT{}
+	cache.Unlock()
+	if storageCfg.buck == nil {
+		return nil
+	}
+	err := storageCfg.buck.Close()
+	storageCfg.buck = nil
+	if err != nil {
+		log.Printf("Error closing bucket: %v", err)
+	}
+	return err
+}
+
+func bucketURL(storageURL string) (*blob.Bucket, error) {
+	if strings.HasPrefix(storageURL, "file://") {
+		u, err := url.Parse(storageURL)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse storage URL %v: %v", storageURL, err)
+		}
+		if err := os.MkdirAll(filepath.FromSlash(u.Path), 0750); err != nil {
+			return nil, err
+		}
+	}
+	bucket, err := blob.OpenBucket(context.Background(), storageURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not open bucket for %v: %v", storageURL, err)
+	}
+	return bucket, nil
+}
+
+// bucket returns the long lived bucket - opening it on first use;
+// callers must not close it
 func bucket() (*blob.Bucket, error) {
-	if appsID != "" {
-		return bucketGoogle()
+	storageCfg.Lock()
+	defer storageCfg.Unlock()
+	if storageCfg.buck != nil {
+		return storageCfg.buck, nil
+	}
+	var buck *blob.Bucket
+	var err error
+	switch {
+	case strings.HasPrefix(storageCfg.url, "mem://"):
+		buck = memblob.OpenBucket(nil)
+	case storageCfg.url != "":
+		buck, err = bucketURL(storageCfg.url)
+	case appsID != "":
+		buck, err = bucketGoogle()
+	default:
+		buck, err = bucketLocal()
 	}
-	return bucketLocal()
+	if err != nil {
+		return nil, err
+	}
+	storageCfg.buck = buck
+	return buck, nil
 }
 
 // Attrs retrieves the attributes from a path;
@@ -123,7 +219,6 @@ func bucket() (*blob.Bucket, error) {
 func Attrs(fpth string) (attrs *blob.Attributes, err error) {
 
 	ctx := context.Background()
-	var errSec error
 	attrs = &blob.Attributes{}
 
 	// Bucket / directory
@@ -132,13 +227,6 @@ func Attrs(fpth string) (attrs *blob.Attributes, err error) {
 		log.Printf("cloudio.Stream(): Error opening bucket: %v", err)
 		return
 	}
-	defer func() {
-		errSec = buck.Close()
-		if errSec != nil {
-			err = errors.Wrap(err, errSec.Error())
-			log.Printf("cloudio.Stream(): Error closing bucket: %v", errSec)
-		}
-	}()
 
 	attrs, err = buck.Attributes(ctx, fpth)
 	if err != nil {
@@ -166,13 +254,6 @@ func WriteFile(fileName string, r io.Reader, perm os.FileMode) (err error) {
 		log.Printf("Error opening bucket: %v", err)
 		return
 	}
-	defer func() {
-		errSec = buck.Close()
-		if errSec != nil {
-			err = errors.Wrap(err, errSec.Error())
-			log.Printf("Error closing bucket: %v", errSec)
-		}
-	}()
 
 	// Writer to "filename" in bucket
 	w, err := buck.NewWriter(ctx, fileName, nil)
@@ -214,13 +295,6 @@ func ReadFile(fileName string) (bts []byte, err error) {
 		log.Printf("Error opening bucket: %v", err)
 		return
 	}
-	defer func() {
-		errSec = buck.Close()
-		if errSec != nil {
-			err = errors.Wrap(err, errSec.Error())
-			log.Printf("Error closing bucket: %v", errSec)
-		}
-	}()
 
 	// Reader of "filename" in bucket
 	r, err := buck.NewReader(ctx, fileName, nil)
@@ -289,12 +363,9 @@ func Open(fileName string) (r io.ReadCloser, bucketClose func() error, err error
 		log.Printf("Error opening bucket: %v", err)
 		return
 	}
+
 	bucketClose = func() error {
-		err := buck.Close()
-		if err != nil {
-			log.Printf("Error closing bucket: %v", err)
-		}
-		return err
+		return nil // the bucket is long lived - see Shutdown()
 	}
 
 	// Reader of "fileName" in bucket
@@ -387,7 +458,6 @@ func ReadFileUnmarshal(fileName string, intf interface{}) (err error) {
 func Delete(fileName string) error {
 	ctx := context.Background()
 	var buck *blob.Bucket
-	var errSec error
 
 	// Bucket / directory
 	buck, err := bucket()
@@ -395,13 +465,7 @@ func Delete(fileName string) error {
 		log.Printf("Error opening bucket for file deletion: %v", err)
 		return err
 	}
-	defer func() {
-		errSec = buck.Close()
-		if errSec != nil {
-			err = errors.Wrap(err, errSec.Error())
-			log.Printf("Error closing bucket for file deletion: %v", errSec)
-		}
-	}()
+
 	err = buck.Delete(ctx, fileName)
 	if err != nil {
 		if IsNotExist(err) {
@@ -478,7 +542,6 @@ func init() {
 func ReadDir(prefix string) (*[]*blob.ListObject, error) {
 	ctx := context.Background()
 	var buck *blob.Bucket
-	var errSec error
 
 	// ret := []os.FileInfo{}
 	ret := &[]*blob.ListObject{}
@@ -494,13 +557,6 @@ func ReadDir(prefix string) (*[]*blob.ListObject, error) {
 		log.Printf("Error opening bucket for file deletion: %v", err)
 		return ret, err
 	}
-	defer func() {
-		errSec = buck.Close()
-		if errSec != nil {
-			err = errors.Wrap(err, errSec.Error())
-			log.Printf("Error closing bucket for file deletion: %v", errSec)
-		}
-	}()
 
 	list(ctx, buck, prefix, 0, 0, ret)
 	return ret, nil
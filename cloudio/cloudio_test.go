@@ -0,0 +1,75 @@
+package cloudio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMemBucket(t *testing.T) {
+
+	SetStorageURL("mem://")
+	defer SetStorageURL("")
+
+	err := WriteFile("responses/fmt/2020-05/10001.json", bytes.NewBufferString(`{"user_id": "10001"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// survives between calls
+	bts, err := ReadFile("responses/fmt/2020-05/10001.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bts) != `{"user_id": "10001"}` {
+		t.Errorf("got %s", bts)
+	}
+
+	infos, err := ReadDir("responses/fmt/2020-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*infos) != 1 {
+		t.Errorf("want one file - got %v", len(*infos))
+	}
+
+	if err := Delete("responses/fmt/2020-05/10001.json"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadFile("responses/fmt/2020-05/10001.json")
+	if err == nil || !IsNotExist(err) {
+		t.Errorf("want not exist error - got %v", err)
+	}
+
+	// a new in-memory bucket is empty
+	SetStorageURL("mem://")
+	infos, _ = ReadDir("responses/fmt/2020-05")
+	if len(*infos) != 0 {
+		t.Errorf("want empty bucket - got %v files", len(*infos))
+	}
+}
+
+func TestReadFileCached(t *testing.T) {
+
+	SetStorageURL("mem://")
+	defer SetStorageURL("")
+
+	fn := "responses/fmt.json"
+	if _, err := ReadFileCached(fn); !IsNotExist(err) {
+		t.Errorf("want not exist error - got %v", err)
+	}
+
+	for _, content := range []string{`{"v": 1}`, `{"v": 2}`} {
+		if err := WriteFile(fn, bytes.NewBufferString(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			bts, err := ReadFileCached(fn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(bts) != content {
+				t.Errorf("read %v: got %s - want %s", i, bts, content)
+			}
+		}
+	}
+}
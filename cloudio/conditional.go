@@ -0,0 +1,139 @@
+package cloudio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/pkg/errors"
+	"gocloud.dev/blob"
+	"gocloud.dev/gcerrors"
+)
+
+// ErrConflict is returned by WriteFileIf(),
+// if the file was changed by someone else
+var ErrConflict = errors.New("file was changed meanwhile")
+
+// fileLocks serialize WriteFileIf() per file within this process
+var fileLocks = struct {
+	sync.Mutex
+	m map[string]*sync.Mutex
+}{m: map[string]*sync.Mutex{}}
+
+func lockFile(fileName string) (unlock func()) {
+	fileLocks.Lock()
+	mtx, ok := fileLocks.m[fileName]
+	if !ok {
+		mtx = &sync.Mutex{}
+		fileLocks.m[fileName] = mtx
+	}
+	fileLocks.Unlock()
+	mtx.Lock()
+	return mtx.Unlock
+}
+
+// WriteFileIf writes r to fileName - if check() accepts the current contents;
+// current is nil for a non-existing file; check() returns ErrConflict to reject.
+//
+// Checking and writing are atomic within this process by a lock per file.
+// Across processes - on google cloud storage - a generation precondition
+// rejects the write, if the file was changed since reading; also with ErrConflict.
+// For other buckets, several instances writing the same file are not detected.
+func WriteFileIf(fileName string, r io.Reader, check func(current []byte) error) error {
+	return writeFileIf(fileName, func(current []byte) (io.Reader, error) {
+		if err := check(current); err != nil {
+			return nil, err
+		}
+		return r, nil
+	})
+}
+
+// AppendFile appends bts to fileName - creating the file if necessary.
+// Buckets cannot append; thus the file is read and written anew -
+// guarded as in WriteFileIf(); ErrConflict is retried.
+func AppendFile(fileName string, bts []byte) (err error) {
+	for i := 0; i < 3; i++ {
+		err = writeFileIf(fileName, func(current []byte) (io.Reader, error) {
+			return io.MultiReader(bytes.NewReader(current), bytes.NewReader(bts)), nil
+		})
+		if err != ErrConflict {
+			return err
+		}
+	}
+	return err
+}
+
+// writeFileIf writes the contents from fn;
+// fn receives the current contents
+func writeFileIf(fileName string, fn func(current []byte) (io.Reader, error)) error {
+
+	unlock := lockFile(fileName)
+	defer unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	buck, err := bucket()
+	if err != nil {
+		return err
+	}
+
+	// current contents and generation
+	var current []byte
+	var generation int64
+	rdr, err := buck.NewReader(ctx, fileName, nil)
+	if err != nil && !IsNotExist(err) {
+		return err
+	}
+	exists := err == nil
+	if exists {
+		var sr *storage.Reader
+		if rdr.As(&sr) {
+			generation = sr.Attrs.Generation
+		}
+		buf := &bytes.Buffer{}
+		_, err = io.Copy(buf, rdr)
+		rdr.Close()
+		if err != nil {
+			return err
+		}
+		current = buf.Bytes()
+	}
+
+	r, err := fn(current)
+	if err != nil {
+		return err
+	}
+
+	opts := &blob.WriterOptions{
+		BeforeWrite: func(as func(interface{}) bool) error {
+			var obj **storage.ObjectHandle
+			if as(&obj) {
+				if !exists {
+					*obj = (*obj).If(storage.Conditions{DoesNotExist: true})
+				} else if generation != 0 {
+					*obj = (*obj).If(storage.Conditions{GenerationMatch: generation})
+				}
+			}
+			return nil
+		},
+	}
+	w, err := buck.NewWriter(ctx, fileName, opts)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	if err != nil {
+		cancel() // discards the partial write
+		w.Close()
+		return err
+	}
+	err = w.Close()
+	if gcerrors.Code(err) == gcerrors.FailedPrecondition {
+		log.Printf("%v was changed by another instance", fileName)
+		return ErrConflict
+	}
+	return err
+}
@@ -51,13 +51,6 @@ func ServeFileBulk(w http.ResponseWriter, req *http.Request) {
 		logAndShow("cloudio.ServeFileBulk(): Error opening bucket: %v", err)
 		return
 	}
-	defer func() {
-		errSec = buck.Close()
-		if errSec != nil {
-			err = errors.Wrap(err, errSec.Error())
-			logAndShow("cloudio.ServeFileBulk(): Error closing bucket: %v", errSec)
-		}
-	}()
 
 	// Reader of "filename" in bucket
 	r, err := buck.NewReader(ctx, fpth, nil)
@@ -86,13 +86,6 @@ func ServeFileStream(w http.ResponseWriter, req *http.Request) {
 		logAndShow("cloudio.ServeFileStream(): Error opening bucket: %v", err)
 		return
 	}
-	defer func() {
-		errSec = buck.Close()
-		if errSec != nil {
-			err = errors.Wrap(err, errSec.Error())
-			logAndShow("cloudio.ServeFileStream(): Error closing bucket: %v", errSec)
-		}
-	}()
 
 	// Reader of "filename" in bucket
 	r, err := buck.NewReader(ctx, fpth, nil)
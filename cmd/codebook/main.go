@@ -0,0 +1,91 @@
+// Package codebook writes the codebook of a questionnaire template
+// as markdown, HTML or JSON;
+// listing name, type, position, labels, values and validation of every input.
+//
+//	codebook.exe -file ../../app-bucket/responses/fmt.json -format md > fmt-codebook.md
+//	codebook.exe -file ../../app-bucket/responses/fmt.json -format json -lc de
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/export"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/util"
+)
+
+func main() {
+
+	log.SetFlags(log.Lshortfile | log.Ldate | log.Ltime)
+
+	fl := util.NewFlags()
+	fl.Add(
+		util.FlagT{
+			Long:       "file",
+			Short:      "f",
+			DefaultVal: "../../app-bucket/responses/fmt.json",
+			Desc:       "questionnaire template",
+		},
+	)
+	fl.Add(
+		util.FlagT{
+			Long:       "format",
+			Short:      "fmt",
+			DefaultVal: "md",
+			Desc:       "md, html or json",
+		},
+	)
+	fl.Add(
+		util.FlagT{
+			Long:       "lang_code",
+			Short:      "lc",
+			DefaultVal: "",
+			Desc:       "language of labels; empty for all languages",
+		},
+	)
+	fl.Gen()
+
+	// wave ID computation requires a config with location
+	{
+		bts, err := json.Marshal(cfg.Example())
+		if err != nil {
+			log.Fatalf("Error marshalling example config: %v", err)
+		}
+		cfg.Load(bytes.NewReader(bts))
+	}
+
+	pth := fl.ByKey("f").Val
+	bts, err := ioutil.ReadFile(pth)
+	if err != nil {
+		log.Fatalf("Error reading file %v: %v", pth, err)
+	}
+	q := &qst.QuestionnaireT{}
+	err = json.Unmarshal(bts, q)
+	if err != nil {
+		log.Fatalf("Error unmarshalling file %v: %v", pth, err)
+	}
+
+	cb := export.Codebook(q)
+	lc := fl.ByKey("lc").Val
+
+	switch format := fl.ByKey("fmt").Val; format {
+	case "md":
+		err = cb.WriteMarkdown(os.Stdout, lc)
+	case "html":
+		err = cb.WriteHTML(os.Stdout, lc)
+	case "json":
+		err = cb.WriteJSON(os.Stdout)
+	default:
+		log.Fatalf("Unknown format %q - use md, html or json", format)
+	}
+	if err != nil {
+		log.Fatalf("Error writing codebook: %v", err)
+	}
+	log.Printf("Codebook for %v - %v variables", pth, len(cb.Entries))
+
+}
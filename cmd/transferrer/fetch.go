@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/export"
+	"github.com/zew/go-questionnaire/qst"
+)
+
+// WaveT is a survey wave to fetch;
+// an empty WaveID means all waves of the survey
+type WaveT struct {
+	SurveyType string
+	WaveID     string
+}
+
+func (wv WaveT) String() string {
+	return fmt.Sprintf("%v-%v", wv.SurveyType, wv.WaveID)
+}
+
+// cursorT is persisted per survey and wave;
+// Since is the most recent modification time of the questionnaires
+// received in the last complete run.
+type cursorT struct {
+	SurveyType string
+	WaveID     string
+	Since      time.Time
+	LastRun    time.Time
+}
+
+// sessionT contains everything to request the remote host
+// after login
+type sessionT struct {
+	urlMain  string
+	urlWaves string
+	sessCook *http.Cookie
+	full     bool // ignore cursor
+}
+
+func (s *sessionT) post(urlReq string, vals url.Values) (*http.Response, error) {
+
+	method := "POST"
+	log.Printf("%v requesting %v?%v", method, urlReq, vals.Encode())
+	req, err := http.NewRequest(method, urlReq, bytes.NewBufferString(vals.Encode())) // <-- URL-encoded payload
+	if err != nil {
+		return nil, fmt.Errorf("error creating request %v: %v", urlReq, err)
+	}
+	// strangely, the json *response* is empty, if we omit this:
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	req.AddCookie(s.sessCook)
+
+	resp, err := getClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting %v: %v", urlReq, err)
+	}
+
+	// Check response status
+	rsc := resp.StatusCode
+	if rsc != http.StatusOK && rsc != http.StatusTemporaryRedirect && rsc != http.StatusSeeOther {
+		resp.Body.Close()
+		return nil, fmt.Errorf("bad response %q ", resp.Status)
+	}
+	return resp, nil
+}
+
+// waveIDs requests all wave IDs of a survey from the remote host
+func (s *sessionT) waveIDs(surveyType string) ([]string, error) {
+
+	vals := url.Values{}
+	vals.Set("survey_id", surveyType)
+	resp, err := s.post(s.urlWaves, vals)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	waveIDs := []string{}
+	err = json.NewDecoder(resp.Body).Decode(&waveIDs)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode wave IDs of %v: %v", surveyType, err)
+	}
+	return waveIDs, nil
+}
+
+// expand replaces waves without wave ID by all waves of their survey
+func (s *sessionT) expand(waves []WaveT) []WaveT {
+	ret := []WaveT{}
+	for _, wv := range waves {
+		if wv.WaveID != "" {
+			ret = append(ret, wv)
+			continue
+		}
+		waveIDs, err := s.waveIDs(wv.SurveyType)
+		if err != nil {
+			log.Printf("Could not obtain waves of %v: %v", wv.SurveyType, err)
+			continue
+		}
+		log.Printf("Survey %v has waves %v", wv.SurveyType, waveIDs)
+		for _, waveID := range waveIDs {
+			ret = append(ret, WaveT{SurveyType: wv.SurveyType, WaveID: waveID})
+		}
+	}
+	return ret
+}
+
+// fetchAll fetches waves with at most concurrency parallel requests
+func (s *sessionT) fetchAll(waves []WaveT, downloadDir string, concurrency int) (errs []error) {
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+
+	wg := sync.WaitGroup{}
+	mtx := sync.Mutex{}
+	for _, wv := range waves {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(wv WaveT) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			err := s.fetch(wv, downloadDir)
+			if err != nil {
+				log.Printf("%v: %v", wv, err)
+				mtx.Lock()
+				errs = append(errs, fmt.Errorf("%v: %v", wv, err))
+				mtx.Unlock()
+			}
+		}(wv)
+	}
+	wg.Wait()
+	return
+}
+
+// fetch streams the questionnaires of a wave modified since the last run
+// into the download directory;
+// then writes the CSV file of the wave
+func (s *sessionT) fetch(wv WaveT, downloadDir string) error {
+
+	//
+	// Cursor of previous runs
+	pthCursor := path.Join(downloadDir, wv.SurveyType, wv.WaveID+"-cursor.json")
+	cur := cursorT{SurveyType: wv.SurveyType, WaveID: wv.WaveID}
+	if !s.full {
+		err := cloudio.ReadFileUnmarshal(pthCursor, &cur)
+		if err != nil && !cloudio.IsNotExist(err) {
+			log.Printf("%v: Could not read cursor %v: %v", wv, pthCursor, err)
+		}
+		log.Printf("%v: Cursor is %v - last run %v", wv, cur.Since, cur.LastRun)
+	}
+
+	vals := url.Values{}
+	vals.Set("survey_id", wv.SurveyType)
+	vals.Set("wave_id", wv.WaveID)
+	vals.Set("fetch_all", "1")
+	vals.Set("format", "ndjson")
+	if !cur.Since.IsZero() {
+		vals.Set("since", cur.Since.Format(time.RFC3339Nano))
+	}
+	resp, err := s.post(s.urlMain, vals)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var rdr1 io.ReadCloser
+	switch resp.Header.Get("Content-Encoding") {
+	case "gzip":
+		rdr1, err = gzip.NewReader(resp.Body)
+		if err != nil {
+			return fmt.Errorf("could not read the response as gzip: %v", err)
+		}
+		defer rdr1.Close()
+	default:
+		rdr1 = resp.Body
+	}
+
+	dirFull := path.Join(downloadDir, wv.SurveyType, wv.WaveID)
+	dirEmpty := path.Join(dirFull, "empty")
+
+	// questionnaires are streamed and saved one by one;
+	// if the connection drops, those received so far are kept
+	cntr, failed := 0, 0
+	sum, err := export.ReadNDJSON(
+		rdr1,
+		func(q *qst.QuestionnaireT) error {
+			if err := processQ(cntr, q, dirFull, dirEmpty); err != nil {
+				log.Printf("%v: %v", wv, err)
+				failed++
+			}
+			cntr++
+			return nil
+		},
+		func(rec export.RecordErrorT) {
+			log.Printf("%v: remote error for %v: %v", wv, rec.Key, rec.Error)
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("reading response stream after %v questionnaires: %v - cursor remains at %v", cntr, err, cur.Since)
+	}
+	log.Printf("%v: Received %v questionnaires; %v errors, %v skipped unfinished, %v unchanged",
+		wv, sum.Count, sum.Errors, sum.SkippedUnfinished, sum.Unchanged)
+
+	// only after complete reception - and if all questionnaires were saved;
+	// otherwise the next run would not fetch the unsaved ones again
+	if failed == 0 {
+		cur.Since = sum.Cursor
+		cur.LastRun = time.Now()
+		err = cloudio.MarshalWriteFile(&cur, pthCursor)
+		if err != nil {
+			log.Printf("%v: Could not save cursor %v: %v", wv, pthCursor, err)
+		}
+		log.Printf("%v: Cursor set to %v", wv, cur.Since)
+	}
+
+	// Data into CSV matrix...
+	// from all questionnaires downloaded so far
+	mtrx := &export.WideMatrixT{}
+	cntrCSV := 0
+	err = export.EachInDir(dirFull, true, func(q *qst.QuestionnaireT) error {
+		mtrx.Add(q)
+		cntrCSV++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not load downloaded questionnaires: %v", err)
+	}
+	fn := fmt.Sprintf("/dl/online-responses-%v-%v.csv", wv.SurveyType, wv.WaveID)
+	err = writeCSV(fn, mtrx)
+	if err != nil {
+		return err
+	}
+	log.Printf("%v: %v questionnaire(s) received, %v in CSV. %v", wv, cntr, cntrCSV, fn)
+	if failed > 0 {
+		return fmt.Errorf("%v of %v questionnaires could not be saved - cursor remains at %v", failed, cntr, cur.Since)
+	}
+	return nil
+}
+
+// writeCombined writes the questionnaires of all waves
+// into one file in long format - one row per input;
+// for several waves, also into one file stacked - one row per participant and wave
+func writeCombined(waves []WaveT, downloadDir string) error {
+
+	mtrx := &export.WideMatrixT{Waves: true}
+	wtrLong := &bytes.Buffer{}
+	lw, err := export.NewLongWriter(wtrLong)
+	if err != nil {
+		return err
+	}
+
+	for _, wv := range waves {
+		dirFull := path.Join(downloadDir, wv.SurveyType, wv.WaveID)
+		err := export.EachInDir(dirFull, true, func(q *qst.QuestionnaireT) error {
+			if len(waves) > 1 {
+				mtrx.Add(q)
+			}
+			return lw.Add(q)
+		})
+		if err != nil {
+			return fmt.Errorf("%v: could not load downloaded questionnaires: %v", wv, err)
+		}
+	}
+
+	if err := lw.Flush(); err != nil {
+		return err
+	}
+	fn := "/dl/online-responses-long.csv"
+	err = cloudio.WriteFile(fn, wtrLong, 0644)
+	if err != nil {
+		return fmt.Errorf("could not write file %v: %v", fn, err)
+	}
+	log.Printf("Long file of %v waves: %v", len(waves), fn)
+
+	if len(waves) > 1 {
+		fn := "/dl/online-responses-combined.csv"
+		err := writeCSV(fn, mtrx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Combined file of %v waves: %v", len(waves), fn)
+	}
+	return nil
+}
+
+func writeCSV(fn string, mtrx *export.WideMatrixT) error {
+	header, rows := mtrx.Matrix()
+	var wtr = new(bytes.Buffer)
+	err := export.WriteCSV(wtr, header, rows)
+	if err != nil {
+		return err
+	}
+	err = cloudio.WriteFile(fn, wtr, 0644)
+	if err != nil {
+		return fmt.Errorf("could not write file %v: %v", fn, err)
+	}
+	return nil
+}
+
+// processQ saves a received questionnaire into dirFull;
+// questionnaires without answers are moved to dirEmpty
+func processQ(i int, q *qst.QuestionnaireT, dirFull, dirEmpty string) error {
+
+	serverSideMD5 := q.MD5
+
+	pthFull := path.Join(dirFull, q.UserID)
+	err := q.Save1Unconditionally(pthFull)
+	if err != nil {
+		return fmt.Errorf("%3v: Error saving %v: %v", i, pthFull, err)
+	}
+
+	//
+	if q.MD5 != serverSideMD5 {
+		// log.Printf("%3v: MD5 does not match: %v\nwnt %v\ngot %v", i, pth2, md5BeforeSave, q.MD5)
+		log.Printf("%3v: Server side and new client side MD5 hashes do not match %v - %v", i, q.Survey.String(), pthFull)
+	}
+
+	//
+	//
+	// Delete empty questionnaires and save them elsewhere
+	pthEmpty := path.Join(dirEmpty, q.UserID+".json")
+	err = cloudio.Delete(pthEmpty)
+	if err != nil && !cloudio.IsNotExist(err) {
+		log.Printf("%3v: Error removing previously empty %v - %v", i, pthEmpty, err)
+	}
+	realEntries, _, _ := q.Statistics()
+	if realEntries == 0 {
+		log.Printf("%3v: %v. No answers given, skipping, deleting, moving to %v.", i, pthFull, pthEmpty)
+		err := q.Save1Unconditionally(pthEmpty)
+		if err != nil {
+			return fmt.Errorf("%3v: Error saving  to empty %v: %v", i, pthEmpty, err)
+		}
+		err = cloudio.Delete(pthFull)
+		if err != nil && !cloudio.IsNotExist(err) {
+			log.Printf("%3v: Error removing empty %v - %v", i, pthFull, err)
+		}
+	}
+	return nil
+}
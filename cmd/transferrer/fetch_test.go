@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/export"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/store"
+)
+
+func TestFetchAll(t *testing.T) {
+
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+
+	cursor := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	mtx := sync.Mutex{}
+	inFlight, maxInFlight, requests := 0, 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		inFlight++
+		requests++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mtx.Unlock()
+		defer func() {
+			mtx.Lock()
+			inFlight--
+			mtx.Unlock()
+		}()
+
+		time.Sleep(20 * time.Millisecond) // let requests overlap
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if strings.HasPrefix(r.Form.Get("wave_id"), "bad") {
+			http.Error(w, "wave not found", http.StatusInternalServerError)
+			return
+		}
+		nw := export.NewNDJSONWriter(w)
+		nw.Summary.Cursor = cursor
+		if err := nw.Close(); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	s := &sessionT{urlMain: srv.URL, sessCook: &http.Cookie{Name: "session", Value: "x"}}
+	waves := []WaveT{
+		{"fmt", "2020-01"},
+		{"fmt", "bad-1"},
+		{"fmt", "2020-02"},
+		{"fmt", "2020-03"},
+		{"fmt", "bad-2"},
+		{"fmt", "2020-04"},
+	}
+	downloadDir := "responses/downloaded"
+	errs := s.fetchAll(waves, downloadDir, 2)
+
+	if requests != len(waves) {
+		t.Errorf("want %v requests - got %v", len(waves), requests)
+	}
+	if maxInFlight != 2 {
+		t.Errorf("want at most 2 parallel requests - and some overlap; got %v", maxInFlight)
+	}
+
+	got := []string{}
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	sort.Strings(got)
+	if len(got) != 2 || !strings.HasPrefix(got[0], "fmt-bad-1: ") || !strings.HasPrefix(got[1], "fmt-bad-2: ") {
+		t.Errorf("want errors of waves bad-1 and bad-2 - got %v", got)
+	}
+
+	// cursors are saved for successful waves only
+	for _, wv := range waves {
+		cur := cursorT{}
+		err := cloudio.ReadFileUnmarshal(path.Join(downloadDir, wv.SurveyType, wv.WaveID+"-cursor.json"), &cur)
+		if strings.HasPrefix(wv.WaveID, "bad") {
+			if !cloudio.IsNotExist(err) {
+				t.Errorf("%v: want no cursor - got %v", wv, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", wv, err)
+			continue
+		}
+		if !cur.Since.Equal(cursor) {
+			t.Errorf("%v: want cursor %v - got %v", wv, cursor, cur.Since)
+		}
+	}
+
+	// concurrency below one is treated as one
+	maxInFlight, requests = 0, 0
+	errs = s.fetchAll(waves[:3], downloadDir, 0)
+	if maxInFlight != 1 || requests != 3 || len(errs) != 1 {
+		t.Errorf("concurrency 0: want 1 parallel of 3 requests and 1 error - got %v of %v and %v", maxInFlight, requests, errs)
+	}
+}
+
+// failingStoreT fails writing the questionnaire of user 10002
+type failingStoreT struct {
+	store.FilesT
+}
+
+func (failingStoreT) Write(key string, bts []byte) error {
+	if path.Base(key) == "10002.json" {
+		return errors.New("disk full")
+	}
+	return store.FilesT{}.Write(key, bts)
+}
+
+func TestFetchSaveFailure(t *testing.T) {
+
+	cfg.LoadExample()
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+	store.Set(failingStoreT{})
+	defer store.Set(store.FilesT{})
+
+	cursor := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nw := export.NewNDJSONWriter(w)
+		for _, userID := range []string{"10001", "10002"} {
+			q := &qst.QuestionnaireT{UserID: userID, LangCode: "en"}
+			q.Survey.Type = "fmt"
+			q.Survey.Year = 2020
+			q.Survey.Month = 5
+			inp := q.AddPage().AddGroup().AddInput()
+			inp.Type = "text"
+			inp.Name = "q1"
+			inp.Response = "answer"
+			if err := nw.Questionnaire(userID, q); err != nil {
+				t.Error(err)
+			}
+		}
+		nw.Summary.Cursor = cursor
+		if err := nw.Close(); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	s := &sessionT{urlMain: srv.URL, sessCook: &http.Cookie{Name: "session", Value: "x"}}
+	wv := WaveT{"fmt", "2020-05"}
+	downloadDir := "responses/downloaded"
+	err := s.fetch(wv, downloadDir)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 questionnaires could not be saved") {
+		t.Errorf("want error for the unsaved questionnaire - got %v", err)
+	}
+
+	// the next run must fetch 10002 again
+	cur := cursorT{}
+	err = cloudio.ReadFileUnmarshal(path.Join(downloadDir, wv.SurveyType, wv.WaveID+"-cursor.json"), &cur)
+	if !cloudio.IsNotExist(err) {
+		t.Errorf("want no cursor - got %v - %v", cur.Since, err)
+	}
+
+	// the saved questionnaire is kept
+	if _, err := cloudio.ReadFile(path.Join(downloadDir, wv.SurveyType, wv.WaveID, "10001.json")); err != nil {
+		t.Errorf("want 10001 saved: %v", err)
+	}
+}
+
+func TestWriteCombined(t *testing.T) {
+
+	cfg.LoadExample()
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+
+	downloadDir := "responses/downloaded"
+	waves := []WaveT{{"fmt", "2020-05"}, {"fmt", "2020-06"}}
+	for i, wv := range waves {
+		for _, userID := range []string{"10001", "10002"} {
+			q := &qst.QuestionnaireT{UserID: userID, LangCode: "en"}
+			q.Survey.Type = wv.SurveyType
+			q.Survey.Year = 2020
+			q.Survey.Month = time.Month(5 + i)
+			gr := q.AddPage().AddGroup()
+			inp := gr.AddInput()
+			inp.Type = "textblock"
+			for _, val := range []string{"1", "2"} {
+				inp := gr.AddInput()
+				inp.Type = "radio"
+				inp.Name = "q1"
+				inp.ValueRadio = val
+			}
+			if userID == "10001" {
+				for _, inp := range gr.Inputs {
+					if inp.Name == "q1" {
+						inp.Response = "2" // radios share their response
+					}
+				}
+			}
+			dirFull := path.Join(downloadDir, wv.SurveyType, wv.WaveID)
+			if err := processQ(0, q, dirFull, path.Join(dirFull, "empty")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := writeCombined(waves, downloadDir); err != nil {
+		t.Fatal(err)
+	}
+
+	bts, err := cloudio.ReadFile("/dl/online-responses-long.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10002 gave no answers - moved to empty
+	want := `user_id;survey_id;wave_id;page;group;input;value;page_finished;lang_code
+10001;fmt;2020-05;1;1;q1;2;;en
+10001;fmt;2020-06;1;1;q1;2;;en
+`
+	if got := string(bts); got != want {
+		t.Errorf("\nwant\n%v\ngot\n%v", want, got)
+	}
+}
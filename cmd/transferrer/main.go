@@ -3,9 +3,6 @@
 package main
 
 import (
-	"bytes"
-	"compress/gzip"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,7 +10,6 @@ import (
 	"log"
 	"math/rand"
 	"net/http"
-	"net/url"
 	"os"
 	"path"
 	"strings"
@@ -21,7 +17,6 @@ import (
 
 	"github.com/zew/go-questionnaire/cfg"
 	"github.com/zew/go-questionnaire/cloudio"
-	"github.com/zew/go-questionnaire/lgn"
 	"github.com/zew/go-questionnaire/qst"
 	"github.com/zew/util"
 )
@@ -46,6 +41,12 @@ type RemoteConnConfigT struct {
 	SurveyType string
 	WaveID     string
 
+	// Waves to fetch in one session;
+	// an empty WaveID means all waves of the survey;
+	// if empty, SurveyType and WaveID are fetched
+	Waves       []WaveT
+	Concurrency int // max parallel requests; default 2
+
 	DownloadDir string
 }
 
@@ -70,6 +71,12 @@ func Example() RemoteConnConfigT {
 	r.WaveID = qst.NewSurvey(r.SurveyType).WaveID()
 	r.WaveID = "2020-05"
 
+	r.Waves = []WaveT{
+		{SurveyType: "fmt", WaveID: "2020-05"},
+		{SurveyType: "pat"}, // all waves
+	}
+	r.Concurrency = 2
+
 	r.DownloadDir = "../../app-bucket/dl"
 
 	return r
@@ -102,7 +109,7 @@ func main() {
 		log.Fatalf("Error - cannot 'cd' to main app dir: %v", err)
 	}
 
-	// We need config and logins
+	// We need config
 	// for main app at least initialized
 	{
 		//
@@ -141,35 +148,6 @@ func main() {
 		cfg.Load(r)
 	}
 
-	// logins data is directly read from file
-	// It only contains the remote salt
-	// required to create form request tokens
-	lgn.LgnsPath = path.Join("/transferrer", "logins-remote-salt.json")
-	{
-		fileName := lgn.LgnsPath
-		r, bucketClose, err := cloudio.Open(fileName)
-		if err != nil {
-			log.Fatalf("Error opening writer to %v: %v", fileName, err)
-		}
-		defer func() {
-			err := r.Close()
-			if err != nil {
-				log.Printf("Error closing writer to bucket to %v: %v", fileName, err)
-			}
-		}()
-		defer func() {
-			err := bucketClose()
-			if err != nil {
-				log.Printf("Error closing bucket of writer to %v: %v", fileName, err)
-			}
-		}()
-		log.Printf("Opened reader to cloud config %v", fileName)
-		lgn.Load(r)
-
-		cloudio.MarshalWriteFile(lgn.Example(), path.Join("/transferrer", "logins-example.json"))
-
-	}
-
 	//
 	// The actual config for *this* app:
 	fl := util.NewFlags()
@@ -181,7 +159,16 @@ func main() {
 			Desc:       "JSON file containing connection to remote host",
 		},
 	)
+	fl.Add(
+		util.FlagT{
+			Long:       "full_download",
+			Short:      "full",
+			DefaultVal: "false",
+			Desc:       "ignore the stored cursor; fetch all questionnaires of the wave",
+		},
+	)
 	fl.Gen()
+	full := fl.ByKey("full").Val == "true"
 
 	var c2 RemoteConnConfigT
 	c2 = Example()
@@ -223,11 +210,11 @@ func main() {
 		log.Printf("  ")
 		log.Printf("  ")
 		log.Printf("================")
-		log.Printf("Login         via   %v%v%v", host, cfg.Pref(), "login-primitive")
+		log.Printf("Login         via   %v%v%v", host, cfg.Pref(), "api-login")
 		log.Printf("Check results via   %v%v%v", host, cfg.Pref(), "transferrer-endpoint?...")
 	}()
 
-	urlLogin := host + cfg.Pref("/login-primitive")
+	urlLogin := host + cfg.Pref("/api-login")
 	log.Printf("url import %v", urlLogin)
 
 	urlMain := host + cfg.Pref("/transferrer-endpoint")
@@ -242,20 +229,13 @@ func main() {
 		log.Printf("==================")
 		urlReq := urlLogin
 
-		vals := url.Values{}
-		vals.Set("username", c2.AdminLogin)
-		vals.Set("password", c2.Pass)
-		vals.Set("token", lgn.FormToken())
-		req, err := http.NewRequest(
-			"POST",
-			urlReq,
-			strings.NewReader(vals.Encode()), // <-- URL-encoded payload
-		)
+		// credentials via basic auth - no form token required; see lgn.APILoginH()
+		req, err := http.NewRequest("POST", urlReq, nil)
 		if err != nil {
 			log.Printf("error creating request for %v: %v", urlReq, err)
 			return
 		}
-		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(c2.AdminLogin, c2.Pass)
 		resp, err := getClient().Do(req)
 		if err != nil {
 			log.Printf("error requesting cookie from %v: %v; %v", urlReq, err, resp)
@@ -270,8 +250,8 @@ func main() {
 		mustHave := fmt.Sprintf("Logged in as %v", c2.AdminLogin)
 		if !strings.Contains(string(respBytes), mustHave) {
 			log.Fatalf(
-				"Login response must contain '%v'\n%v\n%v\n\n%v",
-				mustHave, urlReq, vals, string(respBytes),
+				"Login response must contain '%v'\n%v\n\n%v",
+				mustHave, urlReq, string(respBytes),
 			)
 		}
 
@@ -288,232 +268,33 @@ func main() {
 
 	}
 
-	//
-	//
-	// Post values and check the response
-	{
-		log.Printf(" ")
-		log.Printf("Transferrer endpoint")
-		log.Printf("==================")
-		urlReq := urlMain
-
-		vals := url.Values{}
-		vals.Set("survey_id", c2.SurveyType)
-		vals.Set("wave_id", c2.WaveID)
-		vals.Set("fetch_all", "1")
-		method := "POST"
-		log.Printf("%v requesting %v?%v", method, urlReq, vals.Encode())
-		req, err := http.NewRequest(method, urlReq, bytes.NewBufferString(vals.Encode())) // <-- URL-encoded payload
-		if err != nil {
-			log.Printf("error creating request %v: %v", urlReq, err)
-			return
-		}
-		// strangely, the json *response* is empty, if we omit this:
-		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-		req.Header.Set("Accept-Encoding", "gzip, deflate, br")
-		for _, v := range []*http.Cookie{sessCook} {
-			req.AddCookie(v)
-		}
-
-		resp, err := getClient().Do(req)
-		if err != nil {
-			log.Printf("error requesting %v: %v", urlReq, err)
-			return
-		}
-
-		defer resp.Body.Close()
-		var rdr1 io.ReadCloser
-
-		log.Printf("Content encoding is -%v-", resp.Header.Get("Content-Encoding"))
-
-		switch resp.Header.Get("Content-Encoding") {
-		case "gzip":
-
-			if false {
-				// a hack - to spy into the response
-				// if the http download does not work...
-				bts, err := ioutil.ReadAll(resp.Body)
-				if err != nil {
-					log.Printf("could not read all response %v", err)
-					return
-				}
-				log.Printf("response is %v bytes", len(bts))
-				if len(bts) < 15000 {
-					log.Printf("response is %s", bts)
-				}
-				btsRdr := bytes.NewReader(bts)
-				rdr1, err = gzip.NewReader(btsRdr)
-			}
-
-			rdr1, err = gzip.NewReader(resp.Body)
-			if err != nil {
-				log.Printf("could not read the response as gzip: %v", err)
-				return
-			}
-			defer rdr1.Close()
-		default:
-			rdr1 = resp.Body
-		}
-
-		if false {
-			// a hack - to load from file
-			// if the http download would not work...
-			fr, err := os.Open("./app-bucket/dl/transferrer-endpoint.json")
-			if err != nil {
-				log.Printf("shortcut file not present; %v", err)
-			} else {
-				rdr1 = fr
-			}
-		}
-
-		// Check response status
-		rsc := resp.StatusCode
-		if rsc != http.StatusOK && rsc != http.StatusTemporaryRedirect && rsc != http.StatusSeeOther {
-			log.Printf("bad response %q ", resp.Status)
-			return
-		}
-
-		dirFull := path.Join(c2.DownloadDir, c2.SurveyType, c2.WaveID)
-		dirEmpty := path.Join(dirFull, "empty")
-
-		qs := []*qst.QuestionnaireT{}
-		dec := json.NewDecoder(rdr1)
-		err = dec.Decode(&qs)
-		if err != nil {
-			log.Printf("Unmarshal %v", err)
-			return
-		}
-		log.Printf("Unmarshalled %v questionnaires from responese stream", len(qs))
-
-		//
-		//
-		//
-		maxPages := 0
-		for _, q := range qs {
-			if maxPages < len(q.Pages) {
-				maxPages = len(q.Pages)
-			}
-		}
-
-		allKeys := [][]string{}
-		allVals := [][]string{}
-		staticCols := []string{"user_id", "lang_code"}
-		for iPg := 0; iPg < maxPages; iPg++ {
-			staticCols = append(staticCols, fmt.Sprintf("page_%v", iPg+1))
-		}
-
-		//
-		//
-		// Process questionnaires
-		for i, q := range qs {
-
-			serverSideMD5 := q.MD5
-
-			pthFull := path.Join(dirFull, q.UserID)
-			err := q.Save1(pthFull)
-			if err != nil {
-				log.Printf("%3v: Error saving %v: %v", i, pthFull, err)
-				continue
-			}
-
-			//
-			if q.MD5 != serverSideMD5 {
-				// log.Printf("%3v: MD5 does not match: %v\nwnt %v\ngot %v", i, pth2, md5BeforeSave, q.MD5)
-				log.Printf("%3v: Server side and new client side MD5 hashes do not match %v - %v", i, q.Survey.String(), pthFull)
-			}
-
-			//
-			//
-			// Delete empty questionnaires and save them elsewhere
-			pthEmpty := path.Join(dirEmpty, q.UserID+".json")
-			err = cloudio.Delete(pthEmpty)
-			if err != nil && !cloudio.IsNotExist(err) {
-				log.Printf("%3v: Error removing previously empty %v - %v", i, pthEmpty, err)
-			}
-			realEntries, _, _ := q.Statistics()
-			if realEntries == 0 {
-				log.Printf("%3v: %v. No answers given, skipping, deleting, moving to %v.", i, pthFull, pthEmpty)
-				err = cloudio.Delete(pthFull)
-				if err != nil && !cloudio.IsNotExist(err) {
-					log.Printf("%3v: Error removing empty %v - %v", i, pthFull, err)
-				}
-
-				err := q.Save1(pthEmpty)
-				if err != nil {
-					log.Printf("%3v: Error saving  to empty %v: %v", i, pthEmpty, err)
-				}
-				continue
-			}
-
-			// Prepare columns...
-			finishes, ks, vs := q.KeysValues()
-
-			ks = append(staticCols, ks...)
-			allKeys = append(allKeys, ks)
-
-			//
-			prepend := []string{qs[i].UserID, qs[i].LangCode}
-			for iPg := 0; iPg < maxPages; iPg++ {
-				if iPg < len(finishes) {
-					prepend = append(prepend, finishes[iPg])
-				} else {
-					prepend = append(prepend, "n.a.") // response had less than max pages - not finishing time
-				}
-			}
-			vs = append(prepend, vs...)
-			allVals = append(allVals, vs)
-		}
-
-		allKeysSuperset := Superset(allKeys)
-
-		allKeysSSMap := map[string]int{}
-		for idx, v := range allKeysSuperset {
-			allKeysSSMap[v] = idx
-		}
-		valsBySuperset := [][]string{}
-
-		// log.Printf("%v keys superset; %v", len(allKeysSuperset), util.IndentedDump(allKeysSuperset))
-		log.Printf("%v map  keys    ; %v", len(allKeysSSMap), util.IndentedDump(allKeysSSMap))
-		// log.Printf("%v", util.IndentedDump(allVals))
-
-		// Collect values...
-		for i1 := 0; i1 < len(allVals); i1++ {
-			keys := allKeys[i1]
-			vals := allVals[i1]
-			valsBySuperset = append(valsBySuperset, make([]string, len(allKeysSuperset)))
-			for i2 := 0; i2 < len(keys); i2++ {
-				v := vals[i2]
-				k := keys[i2]
-				pos := allKeysSSMap[k]
-				valsBySuperset[i1][pos] = v
-			}
-		}
-
-		// Data into CSV matrix...
-		var wtr = new(bytes.Buffer)
-		csvWtr := csv.NewWriter(wtr)
-		csvWtr.Comma = ';'
-		if err := csvWtr.Write(allKeysSuperset); err != nil {
-			log.Printf("error writing header line to csv: %v", err)
-		}
-		for _, record := range valsBySuperset {
-			if err := csvWtr.Write(record); err != nil {
-				log.Printf("error writing record to csv: %v", err)
-			}
-		}
+	waves := c2.Waves
+	if len(waves) == 0 {
+		waves = []WaveT{{SurveyType: c2.SurveyType, WaveID: c2.WaveID}}
+	}
+	if c2.Concurrency == 0 {
+		c2.Concurrency = 2
+	}
 
-		// Write any buffered data to the underlying writer (standard output).
-		csvWtr.Flush()
-		if err := csvWtr.Error(); err != nil {
-			log.Printf("error flushing csv to response writer: %v", err)
-		}
-		fn := fmt.Sprintf("/dl/online-responses-%v-%v.csv", c2.SurveyType, c2.WaveID)
-		err = cloudio.WriteFile(fn, wtr, 0644)
-		if err != nil {
-			log.Printf("Could not write file %v: %v", fn, err)
-		}
-		log.Printf("Regular finish. %v questionnaire(s) processed. %v", len(qs), fn)
+	sess := &sessionT{
+		urlMain:  urlMain,
+		urlWaves: host + cfg.Pref("/transferrer-waves"),
+		sessCook: sessCook,
+		full:     full,
+	}
 
+	log.Printf(" ")
+	log.Printf("Transferrer endpoint")
+	log.Printf("==================")
+	waves = sess.expand(waves)
+	errs := sess.fetchAll(waves, c2.DownloadDir, c2.Concurrency)
+	err = writeCombined(waves, c2.DownloadDir)
+	if err != nil {
+		log.Printf("%v", err)
+	}
+	for _, err := range errs {
+		log.Printf("Error: %v", err)
 	}
+	log.Printf("Regular finish. %v wave(s) fetched, %v failed.", len(waves), len(errs))
 
 }
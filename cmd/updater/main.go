@@ -1,20 +1,31 @@
-// Package updater makes a change to all questionaires in a given directory;
+// Package updater makes changes to all questionaires in a given directory;
 // can be applied to single origin json - as well as to filled out json files.
-//     updater.exe -dir ../app-bucket/responses/mul.json
-//     updater.exe -dir ../app-bucket/responses/mul/2019-02
+// The changes are read from a patch file; see patchT.
+// Without -apply true, the changes are only printed - a dry run.
+//
+//	updater.exe -dir responses/mul.json         -patch ../../app-bucket/patches/mul-typos.json
+//	updater.exe -dir responses/mul/2019-02      -patch ../../app-bucket/patches/mul-typos.json -apply true
+//
+// Templates - responses/[survey].json - are published as new template version; see qst.PublishTemplate().
+// Paths for -dir are relative to the app bucket; they are read and written
+// via the store of the app config -cfg - JSON files or database; see store.Open().
+// Directories of a database store must be survey waves - responses/[survey]/[wave].
 package main
 
 import (
-	"encoding/json"
-	"io/ioutil"
+	"bytes"
 	"log"
 	"math/rand"
 	"os"
-	"path/filepath"
+	"path"
 	"strings"
 	"time"
 
+	"github.com/zew/go-questionnaire/bootstrap"
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/cloudio"
 	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/store"
 	"github.com/zew/util"
 )
 
@@ -29,92 +40,139 @@ func main() {
 		util.FlagT{
 			Long:       "directory",
 			Short:      "dir",
-			DefaultVal: "../app-bucket/responses/mul/2019-02/",
+			DefaultVal: "responses/mul/2019-02/",
 			Desc:       "filename - or directory or to iterate",
 		},
 	)
+	fl.Add(
+		util.FlagT{
+			Long:       "config_file",
+			Short:      "cfg",
+			DefaultVal: "config.json",
+			Desc:       "JSON file containing config data - for the store",
+		},
+	)
+	fl.Add(
+		util.FlagT{
+			Long:       "patch_file",
+			Short:      "patch",
+			DefaultVal: "patch.json",
+			Desc:       "JSON file containing the operations",
+		},
+	)
+	fl.Add(
+		util.FlagT{
+			Long:       "apply_changes",
+			Short:      "apply",
+			DefaultVal: "false",
+			Desc:       "save changes; otherwise only print them",
+		},
+	)
 	fl.Gen()
 	dir := fl.ByKey("dir").Val
+	apply := fl.ByKey("apply").Val == "true"
 
 	//
-	isFile := false
-	files, err := ioutil.ReadDir(dir)
+	pf, err := os.Open(fl.ByKey("patch").Val)
 	if err != nil {
-		log.Printf("Opening as directory failed: %v", err)
-		f, err := os.OpenFile(dir, 0, 0777)
+		log.Fatalf("Error opening patch file: %v", err)
+	}
+	patch, err := loadPatch(pf)
+	pf.Close()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	// we must change to main app dir,
+	// so that cloudio finds ./app-bucket
+	err = os.Chdir("../..")
+	if err != nil {
+		log.Fatalf("Error - cannot 'cd' to main app dir: %v", err)
+	}
+
+	// the store of the app - as in bootstrap.Config()
+	cfg.CfgPath = fl.ByKey("cfg").Val
+	bts, err := cloudio.ReadFile(cfg.CfgPath)
+	if err != nil {
+		log.Fatalf("Error reading config %v: %v", cfg.CfgPath, err)
+	}
+	cfg.Load(bytes.NewReader(bts))
+	if err := bootstrap.OpenStore(); err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	//
+	files := []string{}
+	if strings.HasSuffix(dir, ".json") {
+		files = append(files, dir)
+	} else {
+		entries, err := store.Get().List(dir)
 		if err != nil {
-			log.Printf("Opening as file      failed: %v", err)
-			return
+			log.Fatalf("Error reading directory %v: %v", dir, err)
 		}
-		fi, err := f.Stat()
-		if err != nil {
-			log.Printf("Error obtaining file info: %v", err)
-			return
+		for _, e := range entries {
+			files = append(files, e.Key)
 		}
-		log.Printf("Opening as file succeeded: %v", dir)
-		isFile = true
-		files = append(files, fi)
 	}
 
 	//
 	cntrChanged := 0
-	for i, f := range files {
-
-		pth := filepath.Join(dir, f.Name())
-		if isFile {
-			pth = filepath.Join(dir)
-		}
-		log.Printf("%3v: opening file  %v", i, pth)
+	for i, pth := range files {
 
-		bts, err := ioutil.ReadFile(pth)
+		q, err := qst.Load1(pth)
 		if err != nil {
-			log.Printf("%3v: Error reading file %v: %v", i, pth, err)
-			return
+			log.Printf("%3v: Error loading %v: %v", i, pth, err)
+			continue
 		}
 
-		q := qst.QuestionnaireT{}
-		err = json.Unmarshal(bts, &q)
+		diff, err := patch.apply(q)
 		if err != nil {
-			log.Printf("%3v: Error unmarshalling file %v: %v", i, pth, err)
-			return
+			log.Printf("%3v: %v: %v - not saved", i, pth, err)
+			continue
 		}
-		log.Printf("%3v: questionnaire %v - unmarshalled - %10.3f MB", i, pth, float64(len(bts)/(1<<10))/(1<<10))
-
-		// changing and saving the questionnaire; checksum via q.Save()
-		//
-		// loc := time.FixedZone("UTC", 1*3600)
-		// tToBeCorrected := time.Date(2018, 10, 31, 24, 59, 0, 0, loc)  // "deadline": "2018-10-31T23:59:00Z"
-		// if q.Survey.Deadline.Equal(tToBeCorrected) {
-		if false && q.Variations > 0 {
-			log.Printf("%3v: questionnaire %v - correction needed %v", i, pth, q.Survey.Deadline)
-			// q.Survey.Deadline = tInstead
-			q.Variations = 0
-			err := q.Save1(pth)
-			if err != nil {
-				log.Printf("%3v: Error saving %v: %v", i, pth, err)
-			}
-			cntrChanged++
-			log.Printf("%3v: questionnaire %v saved", i, pth)
+		if len(diff) == 0 {
+			log.Printf("%3v: %v - no changes", i, pth)
+			continue
 		}
+		for _, d := range diff {
+			log.Printf("%3v: %v - %v", i, pth, d)
+		}
+		cntrChanged++
 
-		search := q.Pages[1].Groups[8].Inputs[0].Label["fr"]
-		old := "con-trainte"
-		new := "contrainte"
-		if strings.Contains(search, old) {
-			replaced := strings.Replace(search, old, new, -1)
-			q.Pages[1].Groups[8].Inputs[0].Label["fr"] = replaced
+		if apply {
+			if name, ok := templateName(pth); ok {
+				// templates are published - keeping versions; see qst.PublishTemplate()
+				hsh, err := q.PublishTemplate(name, "patch "+path.Base(fl.ByKey("patch").Val))
+				if err != nil {
+					log.Printf("%3v: Error publishing %v: %v", i, pth, err)
+					continue
+				}
+				log.Printf("%3v: %v published as version %v", i, pth, hsh)
+				continue
+			}
 			err := q.Save1(pth)
 			if err != nil {
 				log.Printf("%3v: Error saving %v: %v", i, pth, err)
 			}
-			cntrChanged++
-			log.Printf("%3v: questionnaire %v - %v corrected to %v", i, pth, old, new)
-		} else {
-			log.Printf("%3v: questionnaire %v - correction not needed %v", i, pth, search)
 		}
 
 	}
 	log.Printf("================")
-	log.Printf("Finish - %v changes", cntrChanged)
+	if apply {
+		log.Printf("Finish - %v of %v files changed", cntrChanged, len(files))
+	} else {
+		log.Printf("Dry run - %v of %v files would change; use -apply true to save", cntrChanged, len(files))
+	}
+
+}
 
+// templateName returns the survey name for template files -
+// responses/[name].json - as opposed to response files in subdirectories
+func templateName(pth string) (string, bool) {
+	pth = path.Clean(pth)
+	if path.Dir(pth) != qst.BasePath() || path.Ext(pth) != ".json" {
+		return "", false
+	}
+	return strings.TrimSuffix(path.Base(pth), ".json"), true
 }
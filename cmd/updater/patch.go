@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/trl"
+)
+
+// patchT is the content of a patch file
+//
+//	{
+//	  "operations": [
+//	    {"op": "set_label",     "path": "1/8/0", "lang": "fr", "value": "contrainte"},
+//	    {"op": "replace_text",  "field": "label", "lang": "fr", "old": "con-trainte", "new": "contrainte"},
+//	    {"op": "set_validator", "name": "y_ez", "value": "must"},
+//	    {"op": "set_deadline",  "value": "2018-10-31T23:59:00Z"},
+//	    {"op": "add_param",     "name": "main_refinance_rate_ecb", "value": "01.02.2018: 3.2%"}
+//	  ]
+//	}
+type patchT struct {
+	Operations []opT `json:"operations"`
+}
+
+// opT is one operation of a patch.
+//
+// Inputs are addressed by name or by path page/group/input - zero based - not by both.
+// For set_label and set_validator, name addresses the first input of that name;
+// for replace_text, all inputs of that name; no name and no path addresses all inputs.
+type opT struct {
+	Op    string `json:"op"`              // set_label, replace_text, set_validator, set_deadline, add_param
+	Name  string `json:"name,omitempty"`  // input name - or param name for add_param
+	Path  string `json:"path,omitempty"`  // i.e. "1/8/0"
+	Field string `json:"field,omitempty"` // for replace_text: label, description, suffix; default label
+	Lang  string `json:"lang,omitempty"`  // language code; empty for all languages with replace_text
+	Value string `json:"value,omitempty"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+func loadPatch(r io.Reader) (*patchT, error) {
+	p := &patchT{}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("error decoding patch: %v", err)
+	}
+	for i, op := range p.Operations {
+		if err := op.check(); err != nil {
+			return nil, fmt.Errorf("operation %v %q: %v", i, op.Op, err)
+		}
+	}
+	return p, nil
+}
+
+func (op opT) check() error {
+	if op.Name != "" && op.Path != "" {
+		return fmt.Errorf("name and path are mutually exclusive")
+	}
+	switch op.Op {
+	case "set_label":
+		if op.Name == "" && op.Path == "" {
+			return fmt.Errorf("requires name or path")
+		}
+		if op.Lang == "" {
+			return fmt.Errorf("requires lang")
+		}
+	case "replace_text":
+		if op.Old == "" {
+			return fmt.Errorf("requires old")
+		}
+		if op.Field != "" && op.Field != "label" && op.Field != "description" && op.Field != "suffix" {
+			return fmt.Errorf("field must be label, description or suffix")
+		}
+	case "set_validator":
+		if op.Name == "" && op.Path == "" {
+			return fmt.Errorf("requires name or path")
+		}
+	case "set_deadline":
+		if _, err := time.Parse(time.RFC3339, op.Value); err != nil {
+			return fmt.Errorf("value must be RFC3339 - i.e. 2018-10-31T23:59:00Z: %v", err)
+		}
+	case "add_param":
+		if op.Name == "" {
+			return fmt.Errorf("requires name")
+		}
+	default:
+		return fmt.Errorf("unknown operation")
+	}
+	return nil
+}
+
+// inputRef is an input with its position
+type inputRef struct {
+	pos string // Page %v - Group %v - Input %v
+	lbl *trl.S
+	dsc *trl.S
+	suf *trl.S
+	val *string
+}
+
+// targets returns the inputs addressed by op;
+// all matches for all == true
+func (op opT) targets(q *qst.QuestionnaireT, all bool) ([]inputRef, error) {
+
+	ret := []inputRef{}
+
+	if op.Path != "" {
+		parts := strings.Split(op.Path, "/")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("path %q must be page/group/input", op.Path)
+		}
+		idx := [3]int{}
+		for i, part := range parts {
+			v, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return nil, fmt.Errorf("path %q: %v", op.Path, err)
+			}
+			idx[i] = v
+		}
+		if idx[0] < 0 || idx[0] >= len(q.Pages) ||
+			idx[1] < 0 || idx[1] >= len(q.Pages[idx[0]].Groups) ||
+			idx[2] < 0 || idx[2] >= len(q.Pages[idx[0]].Groups[idx[1]].Inputs) {
+			return nil, fmt.Errorf("path %q does not exist", op.Path)
+		}
+		inp := q.Pages[idx[0]].Groups[idx[1]].Inputs[idx[2]]
+		ret = append(ret, inputRef{
+			pos: fmt.Sprintf("Page %v - Group %v - Input %v", idx[0], idx[1], idx[2]),
+			lbl: &inp.Label, dsc: &inp.Desc, suf: &inp.Suffix, val: &inp.Validator,
+		})
+		return ret, nil
+	}
+
+	for i1, p := range q.Pages {
+		for i2, gr := range p.Groups {
+			for i3, inp := range gr.Inputs {
+				if op.Name != "" && (inp.IsLayout() || inp.Name != op.Name) {
+					continue
+				}
+				ret = append(ret, inputRef{
+					pos: fmt.Sprintf("Page %v - Group %v - Input %v", i1, i2, i3),
+					lbl: &inp.Label, dsc: &inp.Desc, suf: &inp.Suffix, val: &inp.Validator,
+				})
+				if !all {
+					return ret, nil
+				}
+			}
+		}
+	}
+	if op.Name != "" && len(ret) == 0 {
+		return nil, fmt.Errorf("no input %q", op.Name)
+	}
+	return ret, nil
+}
+
+// apply changes q; changes are returned as diff lines
+func (op opT) apply(q *qst.QuestionnaireT) (diff []string, err error) {
+
+	switch op.Op {
+
+	case "set_label":
+		refs, err := op.targets(q, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			if *ref.lbl == nil {
+				*ref.lbl = trl.S{}
+			}
+			if (*ref.lbl)[op.Lang] == op.Value {
+				continue
+			}
+			diff = append(diff, fmt.Sprintf("%v - label[%v]: %q => %q", ref.pos, op.Lang, (*ref.lbl)[op.Lang], op.Value))
+			(*ref.lbl)[op.Lang] = op.Value
+		}
+
+	case "replace_text":
+		refs, err := op.targets(q, true)
+		if err != nil {
+			return nil, err
+		}
+		field := op.Field
+		if field == "" {
+			field = "label"
+		}
+		for _, ref := range refs {
+			s := ref.lbl
+			if field == "description" {
+				s = ref.dsc
+			}
+			if field == "suffix" {
+				s = ref.suf
+			}
+			lcs := []string{}
+			for lc := range *s {
+				lcs = append(lcs, lc)
+			}
+			sort.Strings(lcs)
+			for _, lc := range lcs {
+				if op.Lang != "" && lc != op.Lang {
+					continue
+				}
+				old := (*s)[lc]
+				if !strings.Contains(old, op.Old) {
+					continue
+				}
+				replaced := strings.Replace(old, op.Old, op.New, -1)
+				diff = append(diff, fmt.Sprintf("%v - %v[%v]: %q => %q", ref.pos, field, lc, old, replaced))
+				(*s)[lc] = replaced
+			}
+		}
+
+	case "set_validator":
+		refs, err := op.targets(q, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			if *ref.val == op.Value {
+				continue
+			}
+			diff = append(diff, fmt.Sprintf("%v - validator: %q => %q", ref.pos, *ref.val, op.Value))
+			*ref.val = op.Value
+		}
+
+	case "set_deadline":
+		dl, err := time.Parse(time.RFC3339, op.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !q.Survey.Deadline.Equal(dl) {
+			diff = append(diff, fmt.Sprintf("deadline: %v => %v", q.Survey.Deadline.Format(time.RFC3339), op.Value))
+			q.Survey.Deadline = dl
+		}
+
+	case "add_param":
+		for i, p := range q.Survey.Params {
+			if p.Name == op.Name {
+				if p.Val != op.Value {
+					diff = append(diff, fmt.Sprintf("param %v: %q => %q", op.Name, p.Val, op.Value))
+					q.Survey.Params[i].Val = op.Value
+				}
+				return diff, nil
+			}
+		}
+		diff = append(diff, fmt.Sprintf("param %v: added %q", op.Name, op.Value))
+		q.Survey.Params = append(q.Survey.Params, qst.ParamT{Name: op.Name, Val: op.Value})
+
+	default:
+		return nil, fmt.Errorf("unknown operation %q", op.Op)
+	}
+
+	return diff, nil
+}
+
+// apply applies all operations in order
+func (p *patchT) apply(q *qst.QuestionnaireT) (diff []string, err error) {
+	for i, op := range p.Operations {
+		d, err := op.apply(q)
+		if err != nil {
+			return diff, fmt.Errorf("operation %v %q: %v", i, op.Op, err)
+		}
+		diff = append(diff, d...)
+	}
+	return diff, nil
+}
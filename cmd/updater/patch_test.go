@@ -0,0 +1,195 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/trl"
+)
+
+// patchQuestionnaire has a textblock and two inputs named y_ez
+func patchQuestionnaire() *qst.QuestionnaireT {
+	q := &qst.QuestionnaireT{}
+	gr := q.AddPage().AddGroup()
+	inp := gr.AddInput()
+	inp.Type = "textblock"
+	inp.Label = trl.S{"de": "Ein-leitung", "en": "Intro"}
+	inp = gr.AddInput()
+	inp.Type = "text"
+	inp.Name = "y_ez"
+	inp.Label = trl.S{"de": "con-trainte", "fr": "con-trainte"}
+	gr = q.AddPage().AddGroup()
+	inp = gr.AddInput()
+	inp.Type = "number"
+	inp.Name = "y_ez"
+	inp.Label = trl.S{"fr": "con-trainte 2"}
+	inp.Suffix = trl.S{"fr": "con-trainte"}
+	q.Survey.Params = []qst.ParamT{{Name: "rate", Val: "3.2%"}}
+	return q
+}
+
+func TestPatch(t *testing.T) {
+
+	tests := []struct {
+		desc string
+		op   opT
+		diff []string
+		err  string
+	}{
+		{
+			"set label by path",
+			opT{Op: "set_label", Path: "1/0/0", Lang: "en", Value: "Constraint"},
+			[]string{`Page 1 - Group 0 - Input 0 - label[en]: "" => "Constraint"`},
+			"",
+		},
+		{
+			"set label by name - first input only",
+			opT{Op: "set_label", Name: "y_ez", Lang: "fr", Value: "contrainte"},
+			[]string{`Page 0 - Group 0 - Input 1 - label[fr]: "con-trainte" => "contrainte"`},
+			"",
+		},
+		{
+			"set label - unchanged",
+			opT{Op: "set_label", Path: "0/0/1", Lang: "fr", Value: "con-trainte"},
+			nil,
+			"",
+		},
+		{
+			"set label - path out of range",
+			opT{Op: "set_label", Path: "2/0/0", Lang: "fr", Value: "x"},
+			nil,
+			`path "2/0/0" does not exist`,
+		},
+		{
+			"set validator - unknown name",
+			opT{Op: "set_validator", Name: "y_xx", Value: "must"},
+			nil,
+			`no input "y_xx"`,
+		},
+		{
+			"replace text by name - one language",
+			opT{Op: "replace_text", Name: "y_ez", Lang: "fr", Old: "con-trainte", New: "contrainte"},
+			[]string{
+				`Page 0 - Group 0 - Input 1 - label[fr]: "con-trainte" => "contrainte"`,
+				`Page 1 - Group 0 - Input 0 - label[fr]: "con-trainte 2" => "contrainte 2"`,
+			},
+			"",
+		},
+		{
+			"replace text - all inputs, all languages",
+			opT{Op: "replace_text", Old: "-", New: ""},
+			[]string{
+				`Page 0 - Group 0 - Input 0 - label[de]: "Ein-leitung" => "Einleitung"`,
+				`Page 0 - Group 0 - Input 1 - label[de]: "con-trainte" => "contrainte"`,
+				`Page 0 - Group 0 - Input 1 - label[fr]: "con-trainte" => "contrainte"`,
+				`Page 1 - Group 0 - Input 0 - label[fr]: "con-trainte 2" => "contrainte 2"`,
+			},
+			"",
+		},
+		{
+			"replace text - suffix",
+			opT{Op: "replace_text", Field: "suffix", Old: "con-trainte", New: "contrainte"},
+			[]string{`Page 1 - Group 0 - Input 0 - suffix[fr]: "con-trainte" => "contrainte"`},
+			"",
+		},
+		{
+			"add param - update",
+			opT{Op: "add_param", Name: "rate", Value: "3.5%"},
+			[]string{`param rate: "3.2%" => "3.5%"`},
+			"",
+		},
+		{
+			"add param - unchanged",
+			opT{Op: "add_param", Name: "rate", Value: "3.2%"},
+			nil,
+			"",
+		},
+		{
+			"add param - insert",
+			opT{Op: "add_param", Name: "date", Value: "01.02.2018"},
+			[]string{`param date: added "01.02.2018"`},
+			"",
+		},
+	}
+
+	for _, tc := range tests {
+		if err := tc.op.check(); err != nil {
+			t.Errorf("%v: check: %v", tc.desc, err)
+			continue
+		}
+		q := patchQuestionnaire()
+		diff, err := tc.op.apply(q)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%v: want error %q - got %v", tc.desc, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tc.desc, err)
+			continue
+		}
+		if !reflect.DeepEqual(diff, tc.diff) {
+			t.Errorf("%v:\nwant %q\ngot  %q", tc.desc, tc.diff, diff)
+		}
+	}
+
+	// upsert result
+	q := patchQuestionnaire()
+	p := &patchT{Operations: []opT{
+		{Op: "add_param", Name: "rate", Value: "3.5%"},
+		{Op: "add_param", Name: "date", Value: "01.02.2018"},
+	}}
+	if _, err := p.apply(q); err != nil {
+		t.Fatal(err)
+	}
+	if want := []qst.ParamT{{Name: "rate", Val: "3.5%"}, {Name: "date", Val: "01.02.2018"}}; !reflect.DeepEqual(q.Survey.Params, want) {
+		t.Errorf("params: want %v - got %v", want, q.Survey.Params)
+	}
+}
+
+func TestLoadPatch(t *testing.T) {
+	tests := []struct {
+		json string
+		err  string
+	}{
+		{`{"operations": [{"op": "set_label", "path": "0/0/1", "lang": "fr", "value": "x"}]}`, ""},
+		{`{"operations": [{"op": "set_label", "name": "y_ez", "path": "0/0/1", "lang": "fr", "value": "x"}]}`, "mutually exclusive"},
+		{`{"operations": [{"op": "replace_text", "name": "y_ez", "path": "0/0/1", "old": "x"}]}`, "mutually exclusive"},
+		{`{"operations": [{"op": "set_label", "lang": "fr", "value": "x"}]}`, "requires name or path"},
+		{`{"operations": [{"op": "set_deadline", "value": "31.10.2018"}]}`, "RFC3339"},
+		{`{"operations": [{"op": "rename"}]}`, "unknown operation"},
+		{`{"operations": [{"op": "add_param", "nam": "x"}]}`, "unknown field"},
+	}
+	for _, tc := range tests {
+		_, err := loadPatch(strings.NewReader(tc.json))
+		if tc.err == "" && err != nil {
+			t.Errorf("%v: %v", tc.json, err)
+		}
+		if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("%v: want error %q - got %v", tc.json, tc.err, err)
+		}
+	}
+}
+
+func TestTemplateName(t *testing.T) {
+	tests := []struct {
+		pth  string
+		name string
+		ok   bool
+	}{
+		{"responses/mul.json", "mul", true},
+		{"./responses/fmt-var.json", "fmt-var", true},
+		{"responses/mul/2019-02/10001.json", "", false},
+		{"responses/mul/2019-02", "", false},
+		{"patches/mul.json", "", false},
+	}
+	for _, tc := range tests {
+		name, ok := templateName(tc.pth)
+		if name != tc.name || ok != tc.ok {
+			t.Errorf("%v: want %q %v - got %q %v", tc.pth, tc.name, tc.ok, name, ok)
+		}
+	}
+}
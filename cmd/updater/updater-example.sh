@@ -1 +1,4 @@
-./updater.exe -dir ../app-bucket/responses/lt2020/2020-05
+# dry run - only printing the changes
+./updater.exe -dir responses/lt2020/2020-05 -patch patch-example.json
+# saving the changes
+./updater.exe -dir responses/lt2020/2020-05 -patch patch-example.json -apply true
@@ -0,0 +1,221 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/russross/blackfriday"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/trl"
+)
+
+// CodebookValueT is a possible value of a radio or dropdown input
+type CodebookValueT struct {
+	Val   string `json:"val"`
+	Label trl.S  `json:"label,omitempty"`
+}
+
+// CodebookEntryT describes one variable of the response data
+type CodebookEntryT struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Page  int    `json:"page"`  // zero based; position of first occurrence
+	Group int    `json:"group"` //    ~
+	Input int    `json:"input"` //    ~
+
+	Label trl.S `json:"label,omitempty"`
+	Desc  trl.S `json:"description,omitempty"`
+
+	Values []CodebookValueT `json:"values,omitempty"`
+
+	Validator string  `json:"validator,omitempty"`
+	MaxChars  int     `json:"max_chars,omitempty"`
+	Min       float64 `json:"min,omitempty"`
+	Max       float64 `json:"max,omitempty"`
+	Step      float64 `json:"step,omitempty"`
+
+	DynamicFunc string `json:"dynamic_func,omitempty"`
+	ShowIf      string `json:"show_if,omitempty"`
+}
+
+// CodebookT lists every non-layout input of a questionnaire template
+type CodebookT struct {
+	SurveyID  string           `json:"survey_id"`
+	WaveID    string           `json:"wave_id"`
+	Name      trl.S            `json:"name,omitempty"`
+	LangCodes []string         `json:"lang_codes,omitempty"`
+	Entries   []CodebookEntryT `json:"entries"`
+}
+
+// Codebook extracts the codebook from a questionnaire template.
+// Inputs sharing a name - i.e. the radios of a grid row -
+// yield one entry at the position of their first occurrence.
+func Codebook(q *qst.QuestionnaireT) *CodebookT {
+
+	cb := &CodebookT{
+		SurveyID:  q.Survey.Type,
+		WaveID:    q.Survey.WaveID(),
+		Name:      q.Survey.Name,
+		LangCodes: q.LangCodes,
+	}
+
+	idx := map[string]int{}
+	for i1, p := range q.Pages {
+		for i2, gr := range p.Groups {
+			for i3, inp := range gr.Inputs {
+				if inp.IsLayout() || inp.Name == "" {
+					continue
+				}
+				if i, ok := idx[inp.Name]; ok {
+					e := &cb.Entries[i]
+					if e.Desc.Empty() && !inp.Desc.Empty() {
+						e.Desc = inp.Desc
+					}
+					continue
+				}
+				e := CodebookEntryT{
+					Name:        inp.Name,
+					Type:        inp.Type,
+					Page:        i1,
+					Group:       i2,
+					Input:       i3,
+					Label:       q.InputLabel(inp.Name),
+					Validator:   inp.Validator,
+					MaxChars:    inp.MaxChars,
+					Min:         inp.Min,
+					Max:         inp.Max,
+					Step:        inp.Step,
+					DynamicFunc: inp.DynamicFunc,
+					ShowIf:      inp.ShowIf,
+				}
+				if !inp.Desc.Empty() && !reflect.DeepEqual(inp.Desc, e.Label) {
+					e.Desc = inp.Desc
+				}
+				if inp.Type == "radio" || len(inp.Radios) > 0 {
+					vals, lbls := q.ValueLabels(inp.Name)
+					for i, val := range vals {
+						e.Values = append(e.Values, CodebookValueT{Val: val, Label: lbls[i]})
+					}
+				}
+				if inp.Type == "dropdown" && inp.DD != nil {
+					for _, opt := range inp.DD.Options {
+						e.Values = append(e.Values, CodebookValueT{Val: opt.Key, Label: opt.Val})
+					}
+				}
+				idx[inp.Name] = len(cb.Entries)
+				cb.Entries = append(cb.Entries, e)
+			}
+		}
+	}
+	return cb
+}
+
+// WriteJSON writes the codebook as indented JSON
+func (cb *CodebookT) WriteJSON(w io.Writer) error {
+	bts, err := json.MarshalIndent(cb, "", "\t")
+	if err != nil {
+		return fmt.Errorf("marshalling codebook: %v", err)
+	}
+	_, err = w.Write(bts)
+	return err
+}
+
+// mdCell makes s fit into a markdown table cell
+func mdCell(s string) string {
+	s = qst.PlainText(s)
+	s = strings.Replace(s, "|", "\\|", -1)
+	return s
+}
+
+// trCell renders all languages of s - or only language langCode
+func (cb *CodebookT) trCell(s trl.S, langCode string) string {
+	if s.Empty() {
+		return ""
+	}
+	if langCode != "" {
+		return mdCell(s.TrSilent(langCode))
+	}
+	lcs := cb.LangCodes
+	if len(lcs) == 0 {
+		for lc := range s {
+			lcs = append(lcs, lc)
+		}
+		sort.Strings(lcs)
+	}
+	parts := []string{}
+	for _, lc := range lcs {
+		if s[lc] == "" {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("*%v:* %v", lc, mdCell(s[lc])))
+	}
+	return strings.Join(parts, "<br>")
+}
+
+// WriteMarkdown writes the codebook as markdown table;
+// labels in language langCode, or all languages if langCode is empty
+func (cb *CodebookT) WriteMarkdown(w io.Writer, langCode string) error {
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "# Codebook %v %v\n\n", cb.SurveyID, cb.WaveID)
+	if nm := cb.trCell(cb.Name, langCode); nm != "" {
+		fmt.Fprintf(b, "%v\n\n", nm)
+	}
+	fmt.Fprintf(b, "%v variables\n\n", len(cb.Entries))
+
+	fmt.Fprintf(b, "| Name | Type | Position | Label | Description | Values | Validation | Dynamic func | Show if |\n")
+	fmt.Fprintf(b, "|---|---|---|---|---|---|---|---|---|\n")
+	for _, e := range cb.Entries {
+
+		vals := []string{}
+		for _, v := range e.Values {
+			lbl := cb.trCell(v.Label, langCode)
+			if lbl == "" {
+				vals = append(vals, mdCell(v.Val))
+				continue
+			}
+			vals = append(vals, fmt.Sprintf("%v = %v", mdCell(v.Val), lbl))
+		}
+
+		validation := []string{}
+		if e.Validator != "" {
+			validation = append(validation, mdCell(e.Validator))
+		}
+		if e.Min != 0 || e.Max != 0 {
+			validation = append(validation, fmt.Sprintf("min %v max %v", e.Min, e.Max))
+		}
+		if e.Step != 0 {
+			validation = append(validation, fmt.Sprintf("step %v", e.Step))
+		}
+		if e.MaxChars != 0 {
+			validation = append(validation, fmt.Sprintf("max chars %v", e.MaxChars))
+		}
+
+		fmt.Fprintf(b, "| %v | %v | %v-%v-%v | %v | %v | %v | %v | %v | %v |\n",
+			mdCell(e.Name), e.Type, e.Page, e.Group, e.Input,
+			cb.trCell(e.Label, langCode),
+			cb.trCell(e.Desc, langCode),
+			strings.Join(vals, "<br>"),
+			strings.Join(validation, "<br>"),
+			mdCell(e.DynamicFunc),
+			mdCell(e.ShowIf),
+		)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteHTML writes the markdown codebook rendered to HTML
+func (cb *CodebookT) WriteHTML(w io.Writer, langCode string) error {
+	b := &strings.Builder{}
+	if err := cb.WriteMarkdown(b, langCode); err != nil {
+		return err
+	}
+	_, err := w.Write(blackfriday.Run([]byte(b.String())))
+	return err
+}
@@ -0,0 +1,117 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/trl"
+)
+
+// codebookTemplate has a row of radios sharing a name,
+// a text input with validation and a layout input
+func codebookTemplate() *qst.QuestionnaireT {
+	q := &qst.QuestionnaireT{LangCodes: []string{"de", "en"}}
+	q.Survey.Type = "fmt"
+	q.Survey.Year = 2020
+	q.Survey.Month = time.May
+	q.Survey.Name = trl.S{"de": "Finanzmarkttest", "en": "Financial market test"}
+	gr := q.AddPage().AddGroup()
+	{
+		inp := gr.AddInput()
+		inp.Type = "textblock"
+		inp.Label = trl.S{"de": "Einleitung", "en": "Intro"}
+	}
+	for i, lbl := range []trl.S{{"de": "ja", "en": "yes"}, {"de": "nein", "en": "no"}} {
+		inp := gr.AddInput()
+		inp.Type = "radio"
+		inp.Name = "q1"
+		inp.ValueRadio = []string{"1", "2"}[i]
+		inp.Label = lbl
+	}
+	{
+		inp := gr.AddInput()
+		inp.Type = "number"
+		inp.Name = "q2"
+		inp.Label = trl.S{"de": "Anteil | Prozent", "en": "Share | percent"}
+		inp.Validator = "inRange100"
+		inp.Min, inp.Max = 0, 100
+		inp.MaxChars = 4
+	}
+	return q
+}
+
+func TestCodebook(t *testing.T) {
+
+	cfg.LoadExample()
+	cb := Codebook(codebookTemplate())
+
+	if cb.SurveyID != "fmt" || cb.WaveID != "2020-05" {
+		t.Errorf("got %v %v", cb.SurveyID, cb.WaveID)
+	}
+	if len(cb.Entries) != 2 {
+		t.Fatalf("radios sharing a name must yield one entry; got %+v", cb.Entries)
+	}
+	q1 := cb.Entries[0]
+	if q1.Name != "q1" || q1.Page != 0 || q1.Group != 0 || q1.Input != 1 || len(q1.Values) != 2 || q1.Values[1].Label["en"] != "no" {
+		t.Errorf("unexpected entry %+v", q1)
+	}
+
+	// JSON
+	b := &bytes.Buffer{}
+	if err := cb.WriteJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	cb2 := &CodebookT{}
+	if err := json.Unmarshal(b.Bytes(), cb2); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cb, cb2) {
+		t.Errorf("JSON round trip differs\n%+v\n%+v", cb, cb2)
+	}
+
+	// markdown - one language
+	b.Reset()
+	if err := cb.WriteMarkdown(b, "en"); err != nil {
+		t.Fatal(err)
+	}
+	wantMD := `# Codebook fmt 2020-05
+
+Financial market test
+
+2 variables
+
+| Name | Type | Position | Label | Description | Values | Validation | Dynamic func | Show if |
+|---|---|---|---|---|---|---|---|---|
+| q1 | radio | 0-0-1 | yes |  | 1 = yes<br>2 = no |  |  |  |
+| q2 | number | 0-0-3 | Share \| percent |  |  | inRange100<br>min 0 max 100<br>max chars 4 |  |  |
+`
+	if b.String() != wantMD {
+		t.Errorf("markdown\ngot\n%v\nwant\n%v", b.String(), wantMD)
+	}
+
+	// markdown - all languages
+	b.Reset()
+	if err := cb.WriteMarkdown(b, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "| *de:* ja<br>*en:* yes |") {
+		t.Errorf("all languages expected\n%v", b.String())
+	}
+
+	// HTML
+	b.Reset()
+	if err := cb.WriteHTML(b, "en"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<h1>Codebook fmt 2020-05</h1>", "<table>", "<td>q2</td>", "<td>Share | percent</td>"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("HTML lacks %v\n%v", want, b.String())
+		}
+	}
+}
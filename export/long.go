@@ -0,0 +1,99 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/zew/go-questionnaire/qst"
+)
+
+// LongHeader are the columns of the long format;
+// page and group count from 1 - page 1 being q.Pages[0];
+// page_finished is empty until the participant leaves the page
+var LongHeader = []string{
+	"user_id", "survey_id", "wave_id",
+	"page", "group", "input", "value",
+	"page_finished", "lang_code",
+}
+
+// LongRows returns one row per input of questionnaire q - empty values included.
+// Inputs sharing a name - i.e. the radios of a grid row -
+// yield one row at the position of their first occurrence.
+// Unlike the wide matrix, questionnaires with different inputs
+// need no column alignment.
+func LongRows(q *qst.QuestionnaireT) (rows [][]string) {
+
+	waveID := q.Survey.WaveID()
+	seen := map[string]bool{}
+
+	for i1, p := range q.Pages {
+		finished := ""
+		if !p.Finished.IsZero() {
+			finished = p.Finished.Format("2006-01-02 15:04:05")
+		}
+		for i2, gr := range p.Groups {
+			for _, inp := range gr.Inputs {
+				if inp.IsLayout() || seen[inp.Name] {
+					continue
+				}
+				seen[inp.Name] = true
+				rows = append(rows, []string{
+					q.UserID, q.Survey.Type, waveID,
+					fmt.Sprint(i1 + 1), fmt.Sprint(i2 + 1), inp.Name, inp.Response,
+					finished, q.LangCode,
+				})
+			}
+		}
+	}
+	return
+}
+
+// LongWriterT writes questionnaires in long format one by one;
+// semicolon separated - as WriteCSV()
+type LongWriterT struct {
+	csvWtr *csv.Writer
+}
+
+// NewLongWriter writes the header to w
+func NewLongWriter(w io.Writer) (*LongWriterT, error) {
+	lw := &LongWriterT{csvWtr: csv.NewWriter(w)}
+	lw.csvWtr.Comma = ';'
+	if err := lw.csvWtr.Write(LongHeader); err != nil {
+		return nil, fmt.Errorf("error writing header line to csv: %v", err)
+	}
+	return lw, nil
+}
+
+// Add writes the rows of questionnaire q
+func (lw *LongWriterT) Add(q *qst.QuestionnaireT) error {
+	for _, record := range LongRows(q) {
+		if err := lw.csvWtr.Write(record); err != nil {
+			return fmt.Errorf("error writing record to csv: %v", err)
+		}
+	}
+	return nil
+}
+
+// Flush completes writing
+func (lw *LongWriterT) Flush() error {
+	lw.csvWtr.Flush()
+	if err := lw.csvWtr.Error(); err != nil {
+		return fmt.Errorf("error flushing csv: %v", err)
+	}
+	return nil
+}
+
+// WriteLong writes questionnaires qs in long format
+func WriteLong(w io.Writer, qs []*qst.QuestionnaireT) error {
+	lw, err := NewLongWriter(w)
+	if err != nil {
+		return err
+	}
+	for _, q := range qs {
+		if err := lw.Add(q); err != nil {
+			return err
+		}
+	}
+	return lw.Flush()
+}
@@ -0,0 +1,91 @@
+package export
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/qst"
+)
+
+// longQuestionnaire has a textblock, a radio row split across two groups
+// and a second page; q1 is answered with 2
+func longQuestionnaire(userID string) *qst.QuestionnaireT {
+	q := &qst.QuestionnaireT{UserID: userID, LangCode: "en"}
+	q.Survey.Type = "fmt"
+	q.Survey.Year = 2020
+	q.Survey.Month = time.May
+	p := q.AddPage()
+	p.Finished = time.Date(2020, 5, 3, 10, 11, 12, 0, time.UTC)
+	gr := p.AddGroup()
+	inp := gr.AddInput()
+	inp.Type = "textblock"
+	inp = gr.AddInput()
+	inp.Type = "radio"
+	inp.Name = "q1"
+	inp.ValueRadio = "1"
+	inp.Response = "2"
+	inp = gr.AddInput()
+	inp.Type = "text"
+	inp.Name = "comment"
+	inp.Response = "fine"
+	gr = p.AddGroup()
+	inp = gr.AddInput()
+	inp.Type = "radio"
+	inp.Name = "q1"
+	inp.ValueRadio = "2"
+	inp.Response = "2"
+	inp = gr.AddInput()
+	inp.Type = "number"
+	inp.Name = "q2"
+	gr = q.AddPage().AddGroup()
+	inp = gr.AddInput()
+	inp.Type = "button"
+	inp = gr.AddInput()
+	inp.Type = "number"
+	inp.Name = "q3"
+	inp.Response = "7"
+	return q
+}
+
+func TestLongRows(t *testing.T) {
+
+	cfg.LoadExample()
+
+	got := LongRows(longQuestionnaire("10001"))
+	want := [][]string{
+		{"10001", "fmt", "2020-05", "1", "1", "q1", "2", "2020-05-03 10:11:12", "en"},
+		{"10001", "fmt", "2020-05", "1", "1", "comment", "fine", "2020-05-03 10:11:12", "en"},
+		{"10001", "fmt", "2020-05", "1", "2", "q2", "", "2020-05-03 10:11:12", "en"},
+		{"10001", "fmt", "2020-05", "2", "1", "q3", "7", "", "en"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nwant %v\ngot  %v", want, got)
+	}
+}
+
+func TestWriteLong(t *testing.T) {
+
+	cfg.LoadExample()
+
+	b := &bytes.Buffer{}
+	qs := []*qst.QuestionnaireT{longQuestionnaire("10001"), longQuestionnaire("10002")}
+	if err := WriteLong(b, qs); err != nil {
+		t.Fatal(err)
+	}
+	want := `user_id;survey_id;wave_id;page;group;input;value;page_finished;lang_code
+10001;fmt;2020-05;1;1;q1;2;2020-05-03 10:11:12;en
+10001;fmt;2020-05;1;1;comment;fine;2020-05-03 10:11:12;en
+10001;fmt;2020-05;1;2;q2;;2020-05-03 10:11:12;en
+10001;fmt;2020-05;2;1;q3;7;;en
+10002;fmt;2020-05;1;1;q1;2;2020-05-03 10:11:12;en
+10002;fmt;2020-05;1;1;comment;fine;2020-05-03 10:11:12;en
+10002;fmt;2020-05;1;2;q2;;2020-05-03 10:11:12;en
+10002;fmt;2020-05;2;1;q3;7;;en
+`
+	if got := b.String(); got != want {
+		t.Errorf("\nwant\n%v\ngot\n%v", want, got)
+	}
+}
@@ -0,0 +1,185 @@
+// Package export converts questionnaire responses
+// into tabular formats for statistical software;
+// a wide CSV matrix - one row per participant,
+// XLSX and SPSS syntax with variable and value labels;
+// also the fieldwork status of a survey wave - see WaveStatus().
+//
+// The wide matrix columns are the superset of all input names;
+// see Superset().
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"time"
+
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/store"
+)
+
+// LoadWave loads all response files of a survey wave;
+// see LoadDir()
+func LoadWave(surveyID, waveID string, fetchAll bool) ([]*qst.QuestionnaireT, error) {
+	return LoadDir(path.Join(qst.BasePath(), surveyID, waveID), fetchAll)
+}
+
+// LoadDir loads all questionnaire files in directory pth;
+// see EachInDir()
+func LoadDir(pth string, fetchAll bool) ([]*qst.QuestionnaireT, error) {
+	qs := []*qst.QuestionnaireT{}
+	err := EachInDir(pth, fetchAll, func(q *qst.QuestionnaireT) error {
+		qs = append(qs, q)
+		return nil
+	})
+	return qs, err
+}
+
+// EachInDir loads the questionnaire files in directory pth one by one
+// and calls fn for each; thus not all questionnaires are held in memory.
+// Unfinished questionnaires are skipped - unless fetchAll is set
+// or the survey deadline has passed.
+// Questionnaires without any answers are skipped.
+func EachInDir(pth string, fetchAll bool, fn func(q *qst.QuestionnaireT) error) error {
+
+	entries, err := store.Get().List(pth)
+	if err != nil {
+		return fmt.Errorf("could not read directory %v: %v", pth, err)
+	}
+
+	for i, info := range entries {
+		q, err := qst.Load1(info.Key)
+		if err != nil {
+			return fmt.Errorf("iter %3v: loading %v: %v", i, info.Key, err)
+		}
+		if q.ClosingTime.IsZero() && !fetchAll {
+			if time.Now().Before(q.Survey.Deadline) {
+				log.Printf("%v unfinished and not yet past global deadline => skipping", info.Key)
+				continue
+			}
+		}
+		realEntries, _, _ := q.Statistics()
+		if realEntries == 0 {
+			log.Printf("%v no answers given => skipping", info.Key)
+			continue
+		}
+		if err := fn(q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WideMatrixT collects questionnaires one by one
+// retaining only their keys and values;
+// see WideMatrix()
+type WideMatrixT struct {
+	Waves bool // questionnaires of several waves; prepend columns survey_id and wave_id
+
+	maxPages int
+	prepends [][]string // [survey_id, wave_id,] user_id, lang_code
+	finishes [][]string
+	keys     [][]string
+	vals     [][]string
+}
+
+// Add appends the keys and values of questionnaire q
+func (m *WideMatrixT) Add(q *qst.QuestionnaireT) {
+	if m.maxPages < len(q.Pages) {
+		m.maxPages = len(q.Pages)
+	}
+	finishes, ks, vs := q.KeysValues()
+	prepend := []string{q.UserID, q.LangCode}
+	if m.Waves {
+		prepend = append([]string{q.Survey.Type, q.Survey.WaveID()}, prepend...)
+	}
+	m.prepends = append(m.prepends, prepend)
+	m.finishes = append(m.finishes, finishes)
+	m.keys = append(m.keys, ks)
+	m.vals = append(m.vals, vs)
+}
+
+// Matrix returns the CSV header and one row per questionnaire.
+// Columns are user_id, lang_code, the finishing times of all pages
+// and the superset of all input names.
+// With Waves set, survey_id and wave_id come first.
+func (m *WideMatrixT) Matrix() (header []string, rows [][]string) {
+
+	staticCols := []string{"user_id", "lang_code"}
+	if m.Waves {
+		staticCols = append([]string{"survey_id", "wave_id"}, staticCols...)
+	}
+	for iPg := 0; iPg < m.maxPages; iPg++ {
+		staticCols = append(staticCols, fmt.Sprintf("page_%v", iPg+1))
+	}
+
+	allKeys := make([][]string, len(m.keys))
+	allVals := make([][]string, len(m.vals))
+	for i := range m.keys {
+
+		allKeys[i] = append(append([]string{}, staticCols...), m.keys[i]...)
+
+		prepend := append([]string{}, m.prepends[i]...)
+		for iPg := 0; iPg < m.maxPages; iPg++ {
+			if iPg < len(m.finishes[i]) {
+				prepend = append(prepend, m.finishes[i][iPg])
+			} else {
+				prepend = append(prepend, "n.a.") // response had less than max pages - not finishing time
+			}
+		}
+		allVals[i] = append(prepend, m.vals[i]...)
+	}
+
+	header = Superset(allKeys)
+	if len(allKeys) == 0 {
+		header = staticCols
+	}
+
+	headerMap := map[string]int{}
+	for idx, v := range header {
+		headerMap[v] = idx
+	}
+
+	for i1 := 0; i1 < len(allVals); i1++ {
+		keys := allKeys[i1]
+		vals := allVals[i1]
+		row := make([]string, len(header))
+		for i2 := 0; i2 < len(keys); i2++ {
+			row[headerMap[keys[i2]]] = vals[i2]
+		}
+		rows = append(rows, row)
+	}
+
+	return
+}
+
+// WideMatrix returns the CSV header and one row per questionnaire;
+// see WideMatrixT.Matrix()
+func WideMatrix(qs []*qst.QuestionnaireT) (header []string, rows [][]string) {
+	m := &WideMatrixT{}
+	for _, q := range qs {
+		m.Add(q)
+	}
+	return m.Matrix()
+}
+
+// WriteCSV writes header and rows semicolon separated
+func WriteCSV(w io.Writer, header []string, rows [][]string) error {
+	csvWtr := csv.NewWriter(w)
+	csvWtr.Comma = ';'
+	if err := csvWtr.Write(header); err != nil {
+		return fmt.Errorf("error writing header line to csv: %v", err)
+	}
+	for _, record := range rows {
+		if err := csvWtr.Write(record); err != nil {
+			return fmt.Errorf("error writing record to csv: %v", err)
+		}
+	}
+	csvWtr.Flush()
+	if err := csvWtr.Error(); err != nil {
+		return fmt.Errorf("error flushing csv: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/zew/go-questionnaire/qst"
+)
+
+// Record types of the NDJSON transfer format;
+// lines without record_type are questionnaires
+const (
+	RecordError   = "error"
+	RecordSummary = "summary"
+)
+
+// RecordErrorT reports a questionnaire, which could not be transferred
+type RecordErrorT struct {
+	RecordType string `json:"record_type"`
+	Key        string `json:"key"`
+	Error      string `json:"error"`
+}
+
+// RecordSummaryT is the last line of a complete NDJSON transfer
+type RecordSummaryT struct {
+	RecordType        string    `json:"record_type"`
+	Count             int       `json:"count"`              // questionnaires written
+	Errors            int       `json:"errors"`             // error records written
+	SkippedUnfinished int       `json:"skipped_unfinished"` // unfinished and before deadline
+	Unchanged         int       `json:"unchanged"`          // not modified since cursor
+	Cursor            time.Time `json:"cursor"`             // most recent modification time of all questionnaires
+}
+
+// NDJSONWriterT writes questionnaires as newline delimited JSON;
+// one compact questionnaire per line;
+// Close() concludes the stream with a summary record.
+type NDJSONWriterT struct {
+	w       io.Writer
+	Summary RecordSummaryT
+}
+
+// NewNDJSONWriter returns a writer to w
+func NewNDJSONWriter(w io.Writer) *NDJSONWriterT {
+	return &NDJSONWriterT{
+		w:       w,
+		Summary: RecordSummaryT{RecordType: RecordSummary},
+	}
+}
+
+func (nw *NDJSONWriterT) line(intf interface{}) error {
+	bts, err := json.Marshal(intf)
+	if err != nil {
+		return err
+	}
+	bts = append(bts, '\n')
+	_, err = nw.w.Write(bts)
+	return err
+}
+
+// Questionnaire writes q as one line;
+// a marshalling error is written as error record
+func (nw *NDJSONWriterT) Questionnaire(key string, q *qst.QuestionnaireT) error {
+	bts, err := json.Marshal(q)
+	if err != nil {
+		return nw.Error(key, fmt.Errorf("marshalling questionnaire failed: %v", err))
+	}
+	bts = append(bts, '\n')
+	if _, err := nw.w.Write(bts); err != nil {
+		return err
+	}
+	nw.Summary.Count++
+	return nil
+}
+
+// Error writes an error record for questionnaire key
+func (nw *NDJSONWriterT) Error(key string, err error) error {
+	nw.Summary.Errors++
+	return nw.line(RecordErrorT{RecordType: RecordError, Key: key, Error: err.Error()})
+}
+
+// Close writes the summary record
+func (nw *NDJSONWriterT) Close() error {
+	return nw.line(nw.Summary)
+}
+
+// ReadNDJSON reads questionnaires from an NDJSON stream one by one
+// and calls fn for each; error records are passed to fnErr.
+// A stream without summary record is incomplete and yields an error;
+// questionnaires passed to fn up to this point remain valid.
+func ReadNDJSON(r io.Reader, fn func(q *qst.QuestionnaireT) error, fnErr func(RecordErrorT)) (*RecordSummaryT, error) {
+
+	dec := json.NewDecoder(r)
+	for i := 0; ; i++ {
+
+		raw := json.RawMessage{}
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return nil, fmt.Errorf("stream ended after %v records without summary", i)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record %v: %v", i, err)
+		}
+
+		probe := struct {
+			RecordType string `json:"record_type"`
+		}{}
+		if err := json.Unmarshal(raw, &probe); err != nil {
+			return nil, fmt.Errorf("record %v: %v", i, err)
+		}
+
+		switch probe.RecordType {
+		case "":
+			q := &qst.QuestionnaireT{}
+			if err := json.Unmarshal(raw, q); err != nil {
+				return nil, fmt.Errorf("record %v: unmarshalling questionnaire: %v", i, err)
+			}
+			if err := fn(q); err != nil {
+				return nil, err
+			}
+		case RecordError:
+			rec := RecordErrorT{}
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				return nil, fmt.Errorf("record %v: %v", i, err)
+			}
+			if fnErr != nil {
+				fnErr(rec)
+			}
+		case RecordSummary:
+			sum := &RecordSummaryT{}
+			if err := json.Unmarshal(raw, sum); err != nil {
+				return nil, fmt.Errorf("record %v: %v", i, err)
+			}
+			return sum, nil
+		default:
+			return nil, fmt.Errorf("record %v: unknown record type %q", i, probe.RecordType)
+		}
+	}
+}
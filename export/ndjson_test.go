@@ -0,0 +1,67 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/zew/go-questionnaire/qst"
+)
+
+func TestNDJSON(t *testing.T) {
+
+	b := &bytes.Buffer{}
+	nw := NewNDJSONWriter(b)
+	for _, id := range []string{"u1", "u2"} {
+		if err := nw.Questionnaire(id, &qst.QuestionnaireT{UserID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := nw.Error("u3", fmt.Errorf("broken")); err != nil {
+		t.Fatal(err)
+	}
+	complete := b.String()
+	if err := nw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []string{}
+	errs := []string{}
+	sum, err := ReadNDJSON(
+		bytes.NewReader(b.Bytes()),
+		func(q *qst.QuestionnaireT) error {
+			ids = append(ids, q.UserID)
+			return nil
+		},
+		func(rec RecordErrorT) {
+			errs = append(errs, rec.Key)
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(ids) != "[u1 u2]" || fmt.Sprint(errs) != "[u3]" {
+		t.Errorf("got questionnaires %v and errors %v", ids, errs)
+	}
+	if sum.Count != 2 || sum.Errors != 1 {
+		t.Errorf("got summary %+v", sum)
+	}
+
+	// truncated stream
+	ids = []string{}
+	_, err = ReadNDJSON(
+		bytes.NewBufferString(complete),
+		func(q *qst.QuestionnaireT) error {
+			ids = append(ids, q.UserID)
+			return nil
+		},
+		nil,
+	)
+	if err == nil {
+		t.Errorf("stream without summary must yield an error")
+	}
+	if len(ids) != 2 {
+		t.Errorf("questionnaires before truncation must be passed; got %v", ids)
+	}
+
+}
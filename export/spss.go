@@ -0,0 +1,225 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zew/go-questionnaire/qst"
+)
+
+var notSPSSName = regexp.MustCompile(`[^a-zA-Z0-9_.]+`)
+
+// SPSS reserved keywords - not allowed as variable names
+var spssReserved = map[string]bool{
+	"ALL": true, "AND": true, "BY": true, "EQ": true, "GE": true, "GT": true, "LE": true,
+	"LT": true, "NE": true, "NOT": true, "OR": true, "TO": true, "WITH": true,
+}
+
+// spssName converts an input name into a valid SPSS variable name;
+// letters, digits, underscore and dot; starting with a letter; max 64 chars;
+// no reserved keyword
+func spssName(s string) string {
+	s = notSPSSName.ReplaceAllString(s, "_")
+	if s == "" || !(s[0] >= 'a' && s[0] <= 'z' || s[0] >= 'A' && s[0] <= 'Z') {
+		s = "v" + s
+	}
+	if spssReserved[strings.ToUpper(s)] {
+		s = "v" + s
+	}
+	s = strings.TrimRight(s, ".")
+	if len(s) > 64 {
+		s = s[:64]
+	}
+	return s
+}
+
+// spssQuote puts s into single quotes - doubling contained single quotes;
+// SPSS limits labels to maxBytes - of the escaped text;
+// truncation neither splits a rune nor a doubled quote
+func spssQuote(s string, maxBytes int) string {
+	s = strings.Replace(qst.PlainText(s), "'", "''", -1)
+	if len(s) > maxBytes {
+		s = s[:maxBytes]
+		for !utf8.ValidString(s) {
+			s = s[:len(s)-1]
+		}
+		if trailing := len(s) - len(strings.TrimRight(s, "'")); trailing%2 == 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	return "'" + s + "'"
+}
+
+// spssVarT describes one column of the CSV matrix
+type spssVarT struct {
+	col     string // original column name
+	name    string // SPSS name
+	format  string // i.e. A255 or F8.0
+	numeric bool
+	label   string
+	vals    []string
+	lbls    []string
+}
+
+// spssVars derives SPSS variable definitions from the CSV header;
+// radios and checkboxes with integer values become numeric;
+// everything else string
+func spssVars(tpl *qst.QuestionnaireT, header []string, langCode string) []spssVarT {
+
+	vars := []spssVarT{}
+	used := map[string]bool{} // SPSS names are case insensitive
+
+	for _, col := range header {
+		v := spssVarT{col: col, format: "A255"}
+
+		v.name = spssName(col)
+		// suffixing until unique - the suffixed name itself might already be taken
+		for i := 2; used[strings.ToLower(v.name)]; i++ {
+			base, sfx := spssName(col), fmt.Sprintf("_%v", i)
+			if len(base)+len(sfx) > 64 {
+				base = base[:64-len(sfx)]
+			}
+			v.name = base + sfx
+		}
+		used[strings.ToLower(v.name)] = true
+
+		switch {
+		case col == "user_id":
+			v.format = "A40"
+			v.label = "User ID"
+		case col == "lang_code":
+			v.format = "A8"
+			v.label = "Language"
+		case strings.HasPrefix(col, "page_") && (tpl == nil || tpl.ByName(col) == nil):
+			v.format = "A20"
+			v.label = "Finished " + strings.Replace(col, "_", " ", -1)
+		}
+
+		if tpl != nil {
+			if inp := tpl.ByName(col); inp != nil {
+				v.label = tpl.InputLabel(col).TrSilent(langCode)
+				if inp.Type == "textarea" && inp.MaxChars > 255 {
+					v.format = fmt.Sprintf("A%v", inp.MaxChars)
+				}
+				if inp.Type == "radio" || inp.Type == "checkbox" || len(inp.Radios) > 0 {
+					vals, lbls := tpl.ValueLabels(col)
+					if inp.Type == "checkbox" {
+						vals = []string{"0", "1"}
+						lbls = nil
+					}
+					v.numeric = true
+					for i, val := range vals {
+						if _, err := strconv.Atoi(val); err != nil {
+							v.numeric = false
+						}
+						v.vals = append(v.vals, val)
+						lbl := ""
+						if i < len(lbls) {
+							lbl = lbls[i].TrSilent(langCode)
+						}
+						v.lbls = append(v.lbls, lbl)
+					}
+					if v.numeric {
+						v.format = "F8.0"
+					}
+				}
+			}
+		}
+		vars = append(vars, v)
+	}
+	return vars
+}
+
+// WriteSPSS writes an SPSS syntax file, which reads csvName
+// - as written by WriteCSV() - and applies variable labels and value labels.
+// Labels are taken from the questionnaire template tpl in language langCode;
+// tpl may be nil.
+func WriteSPSS(w io.Writer, tpl *qst.QuestionnaireT, header []string, csvName, langCode string) error {
+
+	vars := spssVars(tpl, header, langCode)
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "* Encoding: UTF-8.\n")
+	if tpl != nil {
+		fmt.Fprintf(b, "* %v - %v.\n", tpl.Survey.String(), qst.PlainText(tpl.Survey.Name.TrSilent(langCode)))
+	}
+	fmt.Fprintf(b, "\nGET DATA\n")
+	fmt.Fprintf(b, "  /TYPE=TXT\n")
+	fmt.Fprintf(b, "  /FILE=%v\n", spssQuote(csvName, 1024))
+	fmt.Fprintf(b, "  /ENCODING='UTF8'\n")
+	fmt.Fprintf(b, "  /DELCASE=LINE\n")
+	fmt.Fprintf(b, "  /DELIMITERS=\";\"\n")
+	fmt.Fprintf(b, "  /QUALIFIER='\"'\n")
+	fmt.Fprintf(b, "  /ARRANGEMENT=DELIMITED\n")
+	fmt.Fprintf(b, "  /FIRSTCASE=2\n")
+	fmt.Fprintf(b, "  /VARIABLES=\n")
+	for _, v := range vars {
+		fmt.Fprintf(b, "    %v %v\n", v.name, v.format)
+	}
+	fmt.Fprintf(b, ".\n\n")
+
+	hasVarLabels, hasValueLabels := false, false
+	for _, v := range vars {
+		if v.label != "" {
+			hasVarLabels = true
+		}
+		if len(v.vals) > 0 {
+			hasValueLabels = true
+		}
+	}
+
+	if hasVarLabels {
+		fmt.Fprintf(b, "VARIABLE LABELS\n")
+		first := true
+		for _, v := range vars {
+			if v.label == "" {
+				continue
+			}
+			sep := " "
+			if !first {
+				sep = "/"
+			}
+			first = false
+			fmt.Fprintf(b, "  %v%v %v\n", sep, v.name, spssQuote(v.label, 250))
+		}
+		fmt.Fprintf(b, ".\n\n")
+	}
+
+	if hasValueLabels {
+		fmt.Fprintf(b, "VALUE LABELS\n")
+		first := true
+		for _, v := range vars {
+			if len(v.vals) == 0 {
+				continue
+			}
+			sep := " "
+			if !first {
+				sep = "/"
+			}
+			first = false
+			fmt.Fprintf(b, "  %v%v", sep, v.name)
+			for i, val := range v.vals {
+				lbl := v.lbls[i]
+				if lbl == "" {
+					lbl = val
+				}
+				if v.numeric {
+					fmt.Fprintf(b, " %v %v", val, spssQuote(lbl, 120))
+				} else {
+					fmt.Fprintf(b, " %v %v", spssQuote(val, 120), spssQuote(lbl, 120))
+				}
+			}
+			fmt.Fprintf(b, "\n")
+		}
+		fmt.Fprintf(b, ".\n\n")
+	}
+
+	fmt.Fprintf(b, "EXECUTE.\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
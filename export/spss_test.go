@@ -0,0 +1,105 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/trl"
+)
+
+func TestSPSSQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"plain", 10, "'plain'"},
+		{"it's", 10, "'it''s'"},
+		{"<b>bold</b> &amp; more", 20, "'bold & more'"},
+		{"abc'def", 4, "'abc'"},   // doubled quote would be cut in half
+		{"abc'def", 5, "'abc'''"}, // doubled quote fits
+		{"abcü", 4, "'abc'"},      // ü has two bytes
+		{"abcdef", 3, "'abc'"},
+	}
+	for _, tc := range tests {
+		if got := spssQuote(tc.in, tc.max); got != tc.want {
+			t.Errorf("spssQuote(%q, %v) = %v - want %v", tc.in, tc.max, got, tc.want)
+		}
+	}
+}
+
+func TestSPSSName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"q1", "q1"},
+		{"7th", "v7th"},
+		{"a-b c", "a_b_c"},
+		{"to", "vto"},
+		{"With", "vWith"},
+		{"tox", "tox"},
+		{strings.Repeat("x", 70), strings.Repeat("x", 64)},
+	}
+	for _, tc := range tests {
+		if got := spssName(tc.in); got != tc.want {
+			t.Errorf("spssName(%q) = %v - want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestSPSSVarsUnique(t *testing.T) {
+	header := []string{"a_2", "a", "A", "a.", strings.Repeat("x", 64), strings.Repeat("x", 65)}
+	want := []string{"a_2", "a", "A_3", "a_4", strings.Repeat("x", 64), strings.Repeat("x", 62) + "_2"}
+	vars := spssVars(nil, header, "en")
+	for i, v := range vars {
+		if v.name != want[i] {
+			t.Errorf("column %q: SPSS name %v - want %v", header[i], v.name, want[i])
+		}
+	}
+}
+
+func TestWriteSPSS(t *testing.T) {
+
+	cfg.LoadExample()
+
+	tpl := &qst.QuestionnaireT{}
+	gr := tpl.AddPage().AddGroup()
+	for i, lbl := range []string{"yes", "no"} {
+		inp := gr.AddInput()
+		inp.Type = "radio"
+		inp.Name = "q1"
+		inp.ValueRadio = []string{"1", "2"}[i]
+		inp.Label = trl.S{"en": lbl}
+	}
+	inp := gr.AddInput()
+	inp.Type = "text"
+	inp.Name = "comment"
+	inp.Label = trl.S{"en": "Participant's comment"}
+
+	header := []string{"user_id", "lang_code", "q1", "comment", "page_1", "7th"}
+	b := &bytes.Buffer{}
+	if err := WriteSPSS(b, tpl, header, "fmt 2020-05.csv", "en"); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"  /FILE='fmt 2020-05.csv'\n",
+		"    user_id A40\n",
+		"    q1 F8.0\n",
+		"    comment A255\n",
+		"    page_1 A20\n",
+		"    v7th A255\n",
+		"  /comment 'Participant''s comment'\n",
+		"  /page_1 'Finished page 1'\n",
+		" q1 1 'yes' 2 'no'\n",
+		"EXECUTE.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("SPSS syntax lacks %q\n%v", want, got)
+		}
+	}
+}
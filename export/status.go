@@ -0,0 +1,148 @@
+package export
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/store"
+)
+
+// StatusRowT is the fieldwork status of one participant
+type StatusRowT struct {
+	Key           string // response file
+	UserID        string
+	CurrPage      int // zero based
+	PagesFinished int
+	ClosingTime   time.Time
+	Completion    float64 // percentage of inputs answered
+	LangCode      string
+	Mobile        int
+	ModTime       time.Time
+	Err           error // response file could not be loaded
+}
+
+// Finished means the participant has closed the questionnaire
+func (sr StatusRowT) Finished() bool {
+	return !sr.ClosingTime.IsZero()
+}
+
+// StatusT is the fieldwork status of a survey wave
+type StatusT struct {
+	SurveyID string
+	WaveID   string
+	Rows     []StatusRowT
+
+	Started  int
+	Finished int
+	// Abandoned counts unfinished participants
+	// by the page they are on - zero based
+	Abandoned map[int]int
+}
+
+// WaveStatus summarizes the response files of a survey wave - newest first;
+// a file which cannot be loaded becomes a row with Err set;
+// it counts neither as started nor as abandoned.
+func WaveStatus(surveyID, waveID string) (*StatusT, error) {
+
+	st := &StatusT{
+		SurveyID:  surveyID,
+		WaveID:    waveID,
+		Abandoned: map[int]int{},
+	}
+
+	pth := path.Join(qst.BasePath(), surveyID, waveID)
+	entries, err := store.Get().List(pth)
+	if err != nil {
+		return nil, fmt.Errorf("could not read directory %v: %v", pth, err)
+	}
+
+	for _, info := range entries {
+		row := StatusRowT{
+			Key:     info.Key,
+			UserID:  strings.TrimSuffix(path.Base(info.Key), ".json"),
+			ModTime: info.ModTime,
+		}
+		q, err := qst.Load1(info.Key)
+		if err != nil {
+			row.Err = err
+			st.Rows = append(st.Rows, row)
+			continue
+		}
+		if q.UserID != "" {
+			row.UserID = q.UserID
+		}
+		row.CurrPage = q.CurrPage
+		for _, p := range q.Pages {
+			if !p.Finished.IsZero() {
+				row.PagesFinished++
+			}
+		}
+		row.ClosingTime = q.ClosingTime
+		if _, inputs, pct := q.Statistics(); inputs > 0 {
+			row.Completion = pct
+		}
+		row.LangCode = q.LangCode
+		row.Mobile = q.Mobile
+		st.Rows = append(st.Rows, row)
+
+		st.Started++
+		if row.Finished() {
+			st.Finished++
+		} else {
+			st.Abandoned[row.CurrPage]++
+		}
+	}
+
+	sort.SliceStable(st.Rows, func(i, j int) bool {
+		return st.Rows[i].ModTime.After(st.Rows[j].ModTime)
+	})
+	return st, nil
+}
+
+// AbandonedPages returns the pages in Abandoned in ascending order
+func (st *StatusT) AbandonedPages() []int {
+	pages := []int{}
+	for pg := range st.Abandoned {
+		pages = append(pages, pg)
+	}
+	sort.Ints(pages)
+	return pages
+}
+
+// Matrix returns the status rows in tabular form - for WriteCSV();
+// curr_page counts from 1 for spreadsheet users - unlike StatusRowT.CurrPage
+func (st *StatusT) Matrix() (header []string, rows [][]string) {
+
+	header = []string{
+		"user_id", "curr_page", "pages_finished", "closing_time",
+		"completion_pct", "lang_code", "mobile", "modified", "error",
+	}
+
+	tf := "2006-01-02 15:04:05"
+	for _, sr := range st.Rows {
+		closing := ""
+		if sr.Finished() {
+			closing = sr.ClosingTime.Format(tf)
+		}
+		errStr := ""
+		if sr.Err != nil {
+			errStr = sr.Err.Error()
+		}
+		rows = append(rows, []string{
+			sr.UserID,
+			fmt.Sprint(sr.CurrPage + 1),
+			fmt.Sprint(sr.PagesFinished),
+			closing,
+			fmt.Sprintf("%.1f", sr.Completion),
+			sr.LangCode,
+			fmt.Sprint(sr.Mobile),
+			sr.ModTime.Format(tf),
+			errStr,
+		})
+	}
+	return
+}
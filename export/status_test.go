@@ -0,0 +1,100 @@
+package export
+
+import (
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/store"
+)
+
+func TestWaveStatus(t *testing.T) {
+
+	cfg.LoadExample()
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+
+	dir := path.Join(qst.BasePath(), "fmt", "2020-05")
+	closing := time.Date(2020, 5, 4, 8, 0, 0, 0, time.UTC)
+
+	// finished
+	q := longQuestionnaire("10001")
+	q.CurrPage = 1
+	q.Pages[1].Finished = closing
+	q.ClosingTime = closing
+	q.Mobile = 2
+	if err := q.Save1Unconditionally(path.Join(dir, q.UserID)); err != nil {
+		t.Fatal(err)
+	}
+	// unfinished on the second page
+	q = longQuestionnaire("10002")
+	q.CurrPage = 1
+	if err := q.Save1Unconditionally(path.Join(dir, q.UserID)); err != nil {
+		t.Fatal(err)
+	}
+	// unfinished on the first page - without answers
+	q = longQuestionnaire("10003")
+	q.Pages[0].Finished = time.Time{}
+	for _, p := range q.Pages {
+		for _, gr := range p.Groups {
+			for _, inp := range gr.Inputs {
+				inp.Response = ""
+			}
+		}
+	}
+	if err := q.Save1Unconditionally(path.Join(dir, q.UserID)); err != nil {
+		t.Fatal(err)
+	}
+	// unloadable
+	if err := store.Get().Write(path.Join(dir, "10004.json"), []byte(`{"user_id": `)); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := WaveStatus("fmt", "2020-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Started != 3 || st.Finished != 1 {
+		t.Errorf("want 3 started, 1 finished - got %v, %v", st.Started, st.Finished)
+	}
+	if want := map[int]int{0: 1, 1: 1}; !reflect.DeepEqual(st.Abandoned, want) {
+		t.Errorf("abandoned: want %v - got %v", want, st.Abandoned)
+	}
+	if want := []int{0, 1}; !reflect.DeepEqual(st.AbandonedPages(), want) {
+		t.Errorf("abandoned pages: want %v - got %v", want, st.AbandonedPages())
+	}
+	if len(st.Rows) != 4 {
+		t.Fatalf("want 4 rows - got %+v", st.Rows)
+	}
+
+	header, rows := st.Matrix()
+	if len(header) != 9 || len(rows) != 4 {
+		t.Fatalf("want 9 columns and 4 rows - got %v and %v", header, rows)
+	}
+	byUser := map[string][]string{}
+	for _, row := range rows {
+		if len(row) != len(header) {
+			t.Errorf("row %v does not match header %v", row, header)
+			continue
+		}
+		byUser[row[0]] = row[:7] // without modified and error
+	}
+	want := map[string][]string{
+		"10001": {"10001", "2", "2", "2020-05-04 08:00:00", "75.0", "en", "2"},
+		"10002": {"10002", "2", "1", "", "75.0", "en", "0"},
+		"10003": {"10003", "1", "0", "", "0.0", "en", "0"},
+		"10004": {"10004", "1", "0", "", "0.0", "", "0"},
+	}
+	if !reflect.DeepEqual(byUser, want) {
+		t.Errorf("\nwant %v\ngot  %v", want, byUser)
+	}
+	for _, row := range rows {
+		if (row[0] == "10004") != (row[8] != "") {
+			t.Errorf("only the unloadable file must have an error - got %v", row)
+		}
+	}
+}
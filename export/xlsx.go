@@ -0,0 +1,117 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Minimal Office Open XML spreadsheet;
+// one sheet, inline strings, no shared strings table, no styles.
+// Avoids a dependency to a spreadsheet library.
+var xlsxStatic = []struct {
+	name, content string
+}{
+	{
+		"[Content_Types].xml",
+		`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`,
+	},
+	{
+		"_rels/.rels",
+		`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`,
+	},
+	{
+		"xl/workbook.xml",
+		`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="responses" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`,
+	},
+	{
+		"xl/_rels/workbook.xml.rels",
+		`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`,
+	},
+}
+
+// xlsxColName converts a zero based column index to A, B, ... Z, AA, AB ...
+func xlsxColName(idx int) string {
+	name := ""
+	for idx++; idx > 0; idx = (idx - 1) / 26 {
+		name = string(rune('A'+(idx-1)%26)) + name
+	}
+	return name
+}
+
+func xlsxRow(w io.Writer, rowIdx int, vals []string) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "<row r=\"%v\">", rowIdx+1)
+	for colIdx, v := range vals {
+		ref := fmt.Sprintf("%v%v", xlsxColName(colIdx), rowIdx+1)
+		if v == "" {
+			continue
+		}
+		// numbers as numbers - but keep leading zeros and such as text
+		if f, err := strconv.ParseFloat(v, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) && strconv.FormatFloat(f, 'f', -1, 64) == v {
+			fmt.Fprintf(b, "<c r=\"%v\"><v>%v</v></c>", ref, v)
+			continue
+		}
+		fmt.Fprintf(b, "<c r=\"%v\" t=\"inlineStr\"><is><t xml:space=\"preserve\">", ref)
+		if err := xml.EscapeText(b, []byte(v)); err != nil {
+			return err
+		}
+		fmt.Fprint(b, "</t></is></c>")
+	}
+	fmt.Fprint(b, "</row>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteXLSX writes header and rows as a single sheet Excel workbook
+func WriteXLSX(w io.Writer, header []string, rows [][]string) error {
+
+	zw := zip.NewWriter(w)
+
+	for _, f := range xlsxStatic {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return fmt.Errorf("xlsx: creating %v: %v", f.name, err)
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return fmt.Errorf("xlsx: writing %v: %v", f.name, err)
+		}
+	}
+
+	fw, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return fmt.Errorf("xlsx: creating sheet: %v", err)
+	}
+	io.WriteString(fw, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n")
+	io.WriteString(fw, `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+"\n")
+	if err := xlsxRow(fw, 0, header); err != nil {
+		return fmt.Errorf("xlsx: writing header: %v", err)
+	}
+	for i, row := range rows {
+		if err := xlsxRow(fw, i+1, row); err != nil {
+			return fmt.Errorf("xlsx: writing row %v: %v", i, err)
+		}
+	}
+	io.WriteString(fw, "</sheetData></worksheet>")
+
+	return zw.Close()
+}
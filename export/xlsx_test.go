@@ -0,0 +1,72 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestXLSXColName(t *testing.T) {
+	for idx, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColName(idx); got != want {
+			t.Errorf("column %v: got %v - want %v", idx, got, want)
+		}
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+
+	b := &bytes.Buffer{}
+	header := []string{"user_id", "q1", "comment"}
+	rows := [][]string{
+		{"10001", "2", "a <b> & 'c'"},
+		{"007", "", "1.50"},
+	}
+	if err := WriteXLSX(b, header, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bts, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(bts)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("workbook lacks %v", name)
+		}
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">user_id</t></is></c>`,
+		`<c r="A2"><v>10001</v></c>`, // number
+		`<c r="C2" t="inlineStr"><is><t xml:space="preserve">a &lt;b&gt; &amp; &#39;c&#39;</t></is></c>`, // escaped
+		`<c r="A3" t="inlineStr"><is><t xml:space="preserve">007</t></is></c>`,                           // leading zero kept
+		`<c r="C3" t="inlineStr"><is><t xml:space="preserve">1.50</t></is></c>`,                          // trailing zero kept
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet lacks %v\n%v", want, sheet)
+		}
+	}
+	if strings.Contains(sheet, `r="B3"`) {
+		t.Errorf("empty cells must be skipped\n%v", sheet)
+	}
+	if n := strings.Count(sheet, "<row "); n != 3 {
+		t.Errorf("got %v rows - want 3", n)
+	}
+}
@@ -1,3 +1,5 @@
 // Package generators contains example questionnaires and helpers to create them.
 // All types of package qst would have to be made public.
+//
+// Package dsl creates questionnaires from definition files - without Go code.
 package generators
@@ -0,0 +1,359 @@
+// Package dsl creates questionnaire templates from declarative definitions -
+// YAML or JSON files in the bucket - instead of Go generators;
+// new surveys need no recompilation.
+//
+// A definition for survey type [type] is stored as definitions/[type].yaml
+// or definitions/[type].json; see app-bucket/definitions/demo.yaml for all elements.
+//
+// Pages, groups and inputs map onto the fields of qst.QuestionnaireT;
+// a group can be a grid - a radio matrix as created by qst.NewGridBuilderRadios();
+// column templates and header labels are named and reused across grids.
+package dsl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/css"
+	"github.com/zew/go-questionnaire/ctr"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/trl"
+)
+
+// Dir contains the definitions
+var Dir = "definitions"
+
+// Extensions of definition files - in order of precedence
+var Extensions = []string{".yaml", ".yml", ".json"}
+
+// DefinitionT is the root of a definition file
+type DefinitionT struct {
+	LangCodes  []string `json:"lang_codes"         yaml:"lang_codes"` // first one is default
+	Org        trl.S    `json:"org,omitempty"      yaml:"org,omitempty"`
+	Name       trl.S    `json:"name,omitempty"     yaml:"name,omitempty"`
+	Variations int      `json:"variations,omitempty" yaml:"variations,omitempty"`
+
+	// ColumnTemplates are pairs of label and control widths - one pair per grid column
+	ColumnTemplates map[string][]float32 `json:"column_templates,omitempty" yaml:"column_templates,omitempty"`
+	// LabelSets are reusable grid headers, i.e. good - normal - bad - no answer
+	LabelSets map[string][]trl.S `json:"label_sets,omitempty" yaml:"label_sets,omitempty"`
+
+	Pages []PageT `json:"pages" yaml:"pages"`
+}
+
+// PageT defines a page
+type PageT struct {
+	Section      trl.S  `json:"section,omitempty"       yaml:"section,omitempty"`
+	Label        trl.S  `json:"label,omitempty"         yaml:"label,omitempty"`
+	Desc         trl.S  `json:"description,omitempty"   yaml:"description,omitempty"`
+	Short        trl.S  `json:"short,omitempty"         yaml:"short,omitempty"`
+	NoNavigation bool   `json:"no_navigation,omitempty" yaml:"no_navigation,omitempty"`
+	ShowIf       string `json:"show_if,omitempty"       yaml:"show_if,omitempty"`
+	Width        string `json:"width,omitempty"         yaml:"width,omitempty"` // max width on desktop, i.e. 34rem
+
+	ValidationFuncName string `json:"validation_func_name,omitempty" yaml:"validation_func_name,omitempty"`
+	ValidationFuncMsg  trl.S  `json:"validation_func_msg,omitempty"  yaml:"validation_func_msg,omitempty"`
+
+	Groups []GroupT `json:"groups" yaml:"groups"`
+}
+
+// GroupT defines a group of inputs - or a grid
+type GroupT struct {
+	Cols               float32 `json:"columns,omitempty"             yaml:"columns,omitempty"`
+	BottomVSpacers     *int    `json:"bottom_vspacers,omitempty"     yaml:"bottom_vspacers,omitempty"` // default 3
+	OddRowsColoring    bool    `json:"odd_rows_coloring,omitempty"   yaml:"odd_rows_coloring,omitempty"`
+	RandomizationGroup int     `json:"randomization_group,omitempty" yaml:"randomization_group,omitempty"`
+	ShowIf             string  `json:"show_if,omitempty"             yaml:"show_if,omitempty"`
+
+	Inputs []InputT `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	Grid   *GridT   `json:"grid,omitempty"   yaml:"grid,omitempty"` // instead of columns and inputs
+}
+
+// InputT defines an input; see qst implementedTypes
+type InputT struct {
+	Name        string  `json:"name,omitempty"        yaml:"name,omitempty"`
+	Type        string  `json:"type"                  yaml:"type"`
+	MaxChars    int     `json:"max_chars,omitempty"   yaml:"max_chars,omitempty"`
+	Step        float64 `json:"step,omitempty"        yaml:"step,omitempty"`
+	Min         float64 `json:"min,omitempty"         yaml:"min,omitempty"`
+	Max         float64 `json:"max,omitempty"         yaml:"max,omitempty"`
+	Placeholder trl.S   `json:"placeholder,omitempty" yaml:"placeholder,omitempty"`
+
+	Label  trl.S `json:"label,omitempty"       yaml:"label,omitempty"`
+	Desc   trl.S `json:"description,omitempty" yaml:"description,omitempty"`
+	Suffix trl.S `json:"suffix,omitempty"      yaml:"suffix,omitempty"`
+
+	ColSpan        float32 `json:"col_span,omitempty"         yaml:"col_span,omitempty"`
+	ColSpanLabel   float32 `json:"col_span_label,omitempty"   yaml:"col_span_label,omitempty"`
+	ColSpanControl float32 `json:"col_span_control,omitempty" yaml:"col_span_control,omitempty"`
+
+	ValueRadio  string `json:"value_radio,omitempty"  yaml:"value_radio,omitempty"`
+	Validator   string `json:"validator,omitempty"    yaml:"validator,omitempty"`
+	ErrMsg      trl.S  `json:"err_msg,omitempty"      yaml:"err_msg,omitempty"`
+	DynamicFunc string `json:"dynamic_func,omitempty" yaml:"dynamic_func,omitempty"`
+	ShowIf      string `json:"show_if,omitempty"      yaml:"show_if,omitempty"`
+}
+
+// GridT defines a radio matrix;
+// one row per input name; one column per radio value;
+// columns from column template - or a named template from ColumnTemplates;
+// headers from header_labels - or a named set from LabelSets.
+type GridT struct {
+	MainLabel      trl.S     `json:"main_label,omitempty"      yaml:"main_label,omitempty"`
+	ColumnTemplate string    `json:"column_template,omitempty" yaml:"column_template,omitempty"`
+	Columns        []float32 `json:"columns,omitempty"         yaml:"columns,omitempty"`
+	HeaderSet      string    `json:"header_set,omitempty"      yaml:"header_set,omitempty"`
+	HeaderLabels   []trl.S   `json:"header_labels,omitempty"   yaml:"header_labels,omitempty"`
+	Values         []string  `json:"values"                    yaml:"values"`
+	Rows           []RowT    `json:"rows"                      yaml:"rows"`
+}
+
+// RowT is a row of a grid
+type RowT struct {
+	Name  string `json:"name"            yaml:"name"`
+	Label trl.S  `json:"label,omitempty" yaml:"label,omitempty"`
+}
+
+// Parse decodes a definition - YAML or JSON depending on the extension of fn;
+// unknown keys are rejected - catching typos.
+func Parse(fn string, bts []byte) (*DefinitionT, error) {
+	d := &DefinitionT{}
+	switch ext := strings.ToLower(path.Ext(fn)); ext {
+	case ".yaml", ".yml":
+		if err := yaml.UnmarshalStrict(bts, d); err != nil {
+			return nil, fmt.Errorf("parsing %v: %v", fn, err)
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(bts))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(d); err != nil {
+			return nil, fmt.Errorf("parsing %v: %v", fn, err)
+		}
+	default:
+		return nil, fmt.Errorf("%v: extension %q not in %v", fn, ext, Extensions)
+	}
+	return d, nil
+}
+
+// Load reads and parses the definition of surveyType from Dir
+func Load(surveyType string) (*DefinitionT, error) {
+	for _, ext := range Extensions {
+		fn := path.Join(Dir, surveyType+ext)
+		bts, err := cloudio.ReadFile(fn)
+		if err != nil {
+			if cloudio.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		return Parse(fn, bts)
+	}
+	return nil, fmt.Errorf("no definition for %v in %v", surveyType, Dir)
+}
+
+// List returns the survey types defined in Dir - sorted
+func List() ([]string, error) {
+	infos, err := cloudio.ReadDir(Dir)
+	if err != nil {
+		return nil, err
+	}
+	distinct := map[string]bool{}
+	for _, info := range *infos {
+		if info.IsDir {
+			continue
+		}
+		fn := path.Base(info.Key)
+		ext := strings.ToLower(path.Ext(fn))
+		for _, e := range Extensions {
+			if ext == e {
+				distinct[strings.TrimSuffix(fn, path.Ext(fn))] = true
+			}
+		}
+	}
+	types := make([]string, 0, len(distinct))
+	for tp := range distinct {
+		types = append(types, tp)
+	}
+	sort.Strings(types)
+	return types, nil
+}
+
+// Generator returns a generator function for surveyType -
+// as the Go generators of package generators;
+// the definition is read anew on each call.
+func Generator(surveyType string) func(params []qst.ParamT) (*qst.QuestionnaireT, error) {
+	return func(params []qst.ParamT) (*qst.QuestionnaireT, error) {
+		d, err := Load(surveyType)
+		if err != nil {
+			return nil, err
+		}
+		return d.Questionnaire(surveyType, params)
+	}
+}
+
+// Questionnaire creates the questionnaire template;
+// it is validated as those of the Go generators.
+func (d *DefinitionT) Questionnaire(surveyType string, params []qst.ParamT) (*qst.QuestionnaireT, error) {
+
+	ctr.Reset()
+
+	q := &qst.QuestionnaireT{}
+	q.Survey = qst.NewSurvey(surveyType)
+	q.Survey.Params = params
+	q.Survey.Org = d.Org
+	q.Survey.Name = d.Name
+
+	if err := d.build(q); err != nil {
+		return q, err
+	}
+
+	q.Hyphenize()
+	q.ComputeMaxGroups()
+	if err := q.TranslationCompleteness(); err != nil {
+		return q, err
+	}
+	if err := q.Validate(); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// build adds pages, groups and inputs to q
+func (d *DefinitionT) build(q *qst.QuestionnaireT) error {
+
+	if len(d.LangCodes) == 0 {
+		return fmt.Errorf("lang_codes must contain at least one language")
+	}
+	q.LangCodes = d.LangCodes
+	q.Variations = d.Variations
+
+	for i1, pd := range d.Pages {
+		page := q.AddPage()
+		page.Section = pd.Section
+		page.Label = pd.Label
+		page.Desc = pd.Desc
+		page.Short = pd.Short
+		page.NoNavigation = pd.NoNavigation
+		page.ShowIf = pd.ShowIf
+		page.ValidationFuncName = pd.ValidationFuncName
+		page.ValidationFuncMsg = pd.ValidationFuncMsg
+		if pd.Width != "" {
+			page.Style = css.DesktopWidthMax(page.Style, pd.Width)
+		}
+
+		for i2, gd := range pd.Groups {
+			pos := fmt.Sprintf("page %v - group %v", i1, i2)
+			if gd.Grid != nil && len(gd.Inputs) > 0 {
+				return fmt.Errorf("%v: either grid or inputs", pos)
+			}
+
+			if gd.Grid != nil {
+				gb, err := d.gridBuilder(gd.Grid)
+				if err != nil {
+					return fmt.Errorf("%v: %v", pos, err)
+				}
+				page.AddGrid(gb)
+			} else {
+				gr := page.AddGroup()
+				gr.Cols = gd.Cols
+				for _, id := range gd.Inputs {
+					inp := gr.AddInput()
+					inp.Name = id.Name
+					inp.Type = id.Type
+					inp.MaxChars = id.MaxChars
+					inp.Step = id.Step
+					inp.Min = id.Min
+					inp.Max = id.Max
+					inp.Placeholder = id.Placeholder
+					inp.Label = id.Label
+					inp.Desc = id.Desc
+					inp.Suffix = id.Suffix
+					inp.ColSpan = id.ColSpan
+					inp.ColSpanLabel = id.ColSpanLabel
+					inp.ColSpanControl = id.ColSpanControl
+					inp.ValueRadio = id.ValueRadio
+					inp.Validator = id.Validator
+					inp.ErrMsg = id.ErrMsg
+					inp.DynamicFunc = id.DynamicFunc
+					inp.ShowIf = id.ShowIf
+				}
+			}
+
+			// common to grids and plain groups
+			gr := page.Groups[len(page.Groups)-1]
+			gr.OddRowsColoring = gd.OddRowsColoring
+			gr.RandomizationGroup = gd.RandomizationGroup
+			gr.ShowIf = gd.ShowIf
+			if gd.BottomVSpacers != nil {
+				gr.BottomVSpacers = *gd.BottomVSpacers
+			}
+		}
+	}
+	return nil
+}
+
+// gridBuilder resolves named column templates and header sets;
+// the checks precede qst.NewGridBuilderRadios(), which panics
+func (d *DefinitionT) gridBuilder(g *GridT) (*qst.GridBuilder, error) {
+
+	cols := g.Columns
+	if g.ColumnTemplate != "" {
+		if len(cols) > 0 {
+			return nil, fmt.Errorf("grid: either column_template or columns")
+		}
+		ct, ok := d.ColumnTemplates[g.ColumnTemplate]
+		if !ok {
+			return nil, fmt.Errorf("grid: column template %q is not in column_templates", g.ColumnTemplate)
+		}
+		cols = ct
+	}
+	if len(cols) == 0 || len(cols)%2 != 0 {
+		return nil, fmt.Errorf("grid: columns need pairs of label and control width - got %v", cols)
+	}
+
+	hdrs := g.HeaderLabels
+	if g.HeaderSet != "" {
+		if len(hdrs) > 0 {
+			return nil, fmt.Errorf("grid: either header_set or header_labels")
+		}
+		ls, ok := d.LabelSets[g.HeaderSet]
+		if !ok {
+			return nil, fmt.Errorf("grid: header set %q is not in label_sets", g.HeaderSet)
+		}
+		hdrs = ls
+	}
+	if len(hdrs) > 0 && len(hdrs)*2 != len(cols) {
+		return nil, fmt.Errorf("grid: %v header labels for %v columns", len(hdrs), len(cols)/2)
+	}
+
+	if len(g.Values) < 2 {
+		return nil, fmt.Errorf("grid: at least two values required")
+	}
+	if len(g.Values)*2 > len(cols) {
+		return nil, fmt.Errorf("grid: %v values for %v columns", len(g.Values), len(cols)/2)
+	}
+	if len(g.Rows) == 0 {
+		return nil, fmt.Errorf("grid: rows are empty")
+	}
+
+	names := make([]string, 0, len(g.Rows))
+	lbls := make([]trl.S, 0, len(g.Rows))
+	for i, row := range g.Rows {
+		if row.Name == "" {
+			return nil, fmt.Errorf("grid: row %v has no name", i)
+		}
+		names = append(names, row.Name)
+		lbls = append(lbls, row.Label)
+	}
+
+	gb := qst.NewGridBuilderRadios(cols, hdrs, names, g.Values, lbls)
+	gb.MainLabel = g.MainLabel
+	return gb, nil
+}
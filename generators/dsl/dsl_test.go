@@ -0,0 +1,71 @@
+package dsl
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/zew/go-questionnaire/qst"
+)
+
+func TestBuild(t *testing.T) {
+
+	fn := "../../app-bucket/definitions/demo.yaml"
+	bts, err := ioutil.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Parse(fn, bts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &qst.QuestionnaireT{}
+	if err := d.build(q); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Pages) != 3 {
+		t.Fatalf("want 3 pages - got %v", len(q.Pages))
+	}
+	grid := q.Pages[0].Groups[0]
+	if grid.Cols != 4 || !grid.OddRowsColoring {
+		t.Errorf("grid: want 4 columns with odd rows coloring - got %v %v", grid.Cols, grid.OddRowsColoring)
+	}
+	if inp := q.ByName("y0_deu"); inp == nil || inp.Type != "radio" {
+		t.Errorf("grid row y0_deu missing")
+	}
+	if q.Pages[1].Groups[1].ShowIf != "age >= 18" {
+		t.Errorf("group condition missing")
+	}
+
+	// json equivalent
+	js := `{"lang_codes": ["en"], "pages": [{"groups": [{"columns": 1, "inputs": [{"name": "q1", "type": "text", "max_chars": 10}]}]}]}`
+	d, err = Parse("x.json", []byte(js))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q = &qst.QuestionnaireT{}
+	if err := d.build(q); err != nil || q.ByName("q1") == nil {
+		t.Errorf("json definition: %v", err)
+	}
+
+	// typos and inconsistencies
+	invalid := []struct {
+		fn, src, errPart string
+	}{
+		{"x.yaml", "lang_codes: [en]\npages:\n  - lable: {en: x}\n", "lable"},
+		{"x.json", `{"lang_codes": ["en"], "pagez": []}`, "pagez"},
+		{"x.yaml", "pages: []\n", "lang_codes"},
+		{"x.yaml", "lang_codes: [en]\npages:\n  - groups:\n    - grid: {column_template: nine, values: [a, b], rows: [{name: r}]}\n", "nine"},
+		{"x.yaml", "lang_codes: [en]\npages:\n  - groups:\n    - grid: {columns: [1, 1, 1, 1], values: [a, b, c], rows: [{name: r}]}\n", "3 values"},
+		{"x.txt", "", "extension"},
+	}
+	for i, tc := range invalid {
+		d, err := Parse(tc.fn, []byte(tc.src))
+		if err == nil {
+			err = d.build(&qst.QuestionnaireT{})
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.errPart) {
+			t.Errorf("invalid %v: want error containing %q - got %v", i, tc.errPart, err)
+		}
+	}
+}
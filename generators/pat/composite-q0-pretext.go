@@ -1,9 +1,10 @@
-package qst
+package pat
 
 import (
 	"fmt"
 
 	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/qst"
 )
 
 var q1Pretext = []string{
@@ -29,7 +30,7 @@ var q1Pretext = []string{
 
 // PoliticalFoundationsPretext returns one of 16
 // introductions to PoliticalFoundations question series
-func PoliticalFoundationsPretext(q *QuestionnaireT, seq0to5, paramSetIdx int) (string, []string, error) {
+func PoliticalFoundationsPretext(q *qst.QuestionnaireT, seq0to5, paramSetIdx int) (string, []string, error) {
 
 	userID := 0
 	if q != nil {
@@ -1,4 +1,4 @@
-package qst
+package pat
 
 import (
 	"fmt"
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/qst"
 )
 
 type preferences3x3T struct {
@@ -42,8 +43,8 @@ var reshuffle6basedOn16 = [][]int{
 // a HTML table with three option and three checkbox inputs;
 // seq0to5 is the numbering;
 // based on userIDInt() - 4 versions / 4 permutations - via fourPermutationsOf6x3x3 + reshuffle6basedOn16;
-// see composite.go for more.
-func PoliticalFoundations(q *QuestionnaireT, seq0to5, paramSetIdx int) (string, []string, error) {
+// see qst/composite.go for more.
+func PoliticalFoundations(q *qst.QuestionnaireT, seq0to5, paramSetIdx int) (string, []string, error) {
 
 	userID := 0
 	if q != nil {
@@ -77,7 +78,7 @@ func PoliticalFoundations(q *QuestionnaireT, seq0to5, paramSetIdx int) (string,
 	)
 }
 
-func politicalFoundations(q *QuestionnaireT, seq0to5 int, questionID string, ppls [][]int) (string, []string, error) {
+func politicalFoundations(q *qst.QuestionnaireT, seq0to5 int, questionID string, ppls [][]int) (string, []string, error) {
 
 	//
 	inputNames := []string{}
@@ -1,4 +1,4 @@
-package qst
+package pat
 
 import (
 	"fmt"
@@ -7,14 +7,15 @@ import (
 	"strings"
 
 	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/qst"
 )
 
 // TimePreferenceSelf creates
 // a HTML table with six option and three checkbox inputs;
 // based on userIDInt() - 8 versions - via paramSetIdx + dataQ2;
 // seq0to5 is the numbering;
-// see composite.go for more.
-func TimePreferenceSelf(q *QuestionnaireT, seq0to5, paramSetIdx int) (string, []string, error) {
+// see qst/composite.go for more.
+func TimePreferenceSelf(q *qst.QuestionnaireT, seq0to5, paramSetIdx int) (string, []string, error) {
 
 	userID := 0
 	if q != nil {
@@ -49,7 +50,7 @@ func TimePreferenceSelf(q *QuestionnaireT, seq0to5, paramSetIdx int) (string, []
 	)
 }
 
-func timePreferenceSelf(q *QuestionnaireT, seq0to0 int, questionID string, rowLabels []string) (string, []string, error) {
+func timePreferenceSelf(q *qst.QuestionnaireT, seq0to0 int, questionID string, rowLabels []string) (string, []string, error) {
 
 	//
 	inputNames := []string{}
@@ -1,17 +1,18 @@
-package qst
+package pat
 
 import (
 	"fmt"
 	"strings"
 
 	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/qst"
 )
 
 // GroupPreferences creates a HTML table with three columns
 // based on userIDInt() - 8 versions - via paramSetIdx + dataQ3;
 // seq0to5 is the numbering;
-// see composite.go for more.
-func GroupPreferences(q *QuestionnaireT, seq0to5, paramSetIdx int) (string, []string, error) {
+// see qst/composite.go for more.
+func GroupPreferences(q *qst.QuestionnaireT, seq0to5, paramSetIdx int) (string, []string, error) {
 
 	userID := 0
 	if q != nil {
@@ -38,7 +39,7 @@ func GroupPreferences(q *QuestionnaireT, seq0to5, paramSetIdx int) (string, []st
 	)
 }
 
-func groupPreferences(q *QuestionnaireT, seq0to0 int, questionID string, rowLabels []string) (string, []string, error) {
+func groupPreferences(q *qst.QuestionnaireT, seq0to0 int, questionID string, rowLabels []string) (string, []string, error) {
 
 	//
 	inputNames := []string{}
@@ -0,0 +1,33 @@
+package pat
+
+import (
+	"fmt"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/qst"
+)
+
+// PatLogos - only for the img src URLs
+func PatLogos(q *qst.QuestionnaireT) (string, error) {
+
+	return fmt.Sprintf(
+		`
+		<div class="uni-logos  logo-imgs-in-content">
+			<img src="%v"  style="width:61%%;"  alt=""  >
+			<img src="%v"  style="width:33%%;"  alt=""  >
+			<img src="%v"  style="width:50%%;"  alt=""  >
+			<img src="%v"  style="width:44%%;"  alt=""  >
+			<img src="%v"  style="width:28%%;"  alt=""  >
+		</div>
+		
+		<br>
+		
+		`,
+		cfg.Pref("/img/pat/uni-mannheim-wide.png"),
+		cfg.Pref("/img/pat/uni-koeln.png"),
+		cfg.Pref("/img/pat/uni-muenster.png"),
+		cfg.Pref("/img/pat/uni-zurich.png"),
+		cfg.Pref("/img/pat/zew.png"),
+	), nil
+
+}
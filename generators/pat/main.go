@@ -211,7 +211,7 @@ func Create(params []qst.ParamT) (*qst.QuestionnaireT, error) {
 				inp.ColSpanControl = 1
 				inp.DynamicFunc = "PoliticalFoundations__0__0"
 			}
-			_, inputNames, _ := qst.PoliticalFoundations(nil, 0, 0)
+			_, inputNames, _ := PoliticalFoundations(nil, 0, 0)
 			for _, inpName := range inputNames {
 				inp := gr.AddInput()
 				inp.Type = "dyn-composite-scalar"
@@ -309,7 +309,7 @@ func Create(params []qst.ParamT) (*qst.QuestionnaireT, error) {
 					inp.ColSpanControl = 1
 					inp.DynamicFunc = fmt.Sprintf("PoliticalFoundations__%v__%v", i, i)
 				}
-				_, inputNames, _ := qst.PoliticalFoundations(nil, i, i)
+				_, inputNames, _ := PoliticalFoundations(nil, i, i)
 				for _, inpName := range inputNames {
 					inp := gr.AddInput()
 					inp.Type = "dyn-composite-scalar"
@@ -349,7 +349,7 @@ func Create(params []qst.ParamT) (*qst.QuestionnaireT, error) {
 					inp.ColSpanControl = 1
 					inp.DynamicFunc = fmt.Sprintf("PoliticalFoundations__%v__%v", i, i)
 				}
-				_, inputNames, _ := qst.PoliticalFoundations(nil, i, i)
+				_, inputNames, _ := PoliticalFoundations(nil, i, i)
 				for _, inpName := range inputNames {
 					inp := gr.AddInput()
 					inp.Type = "dyn-composite-scalar"
@@ -533,7 +533,7 @@ func Create(params []qst.ParamT) (*qst.QuestionnaireT, error) {
 				inp.ColSpanControl = 1
 				inp.DynamicFunc = "TimePreferenceSelf__0__0"
 			}
-			_, inputNames, _ := qst.TimePreferenceSelf(nil, 0, 0)
+			_, inputNames, _ := TimePreferenceSelf(nil, 0, 0)
 			for _, inpName := range inputNames {
 				inp := gr.AddInput()
 				inp.Type = "dyn-composite-scalar"
@@ -578,7 +578,7 @@ func Create(params []qst.ParamT) (*qst.QuestionnaireT, error) {
 				inp.ColSpanControl = 1
 				inp.DynamicFunc = "TimePreferenceSelf__1__1"
 			}
-			_, inputNames, _ := qst.TimePreferenceSelf(nil, 1, 1)
+			_, inputNames, _ := TimePreferenceSelf(nil, 1, 1)
 			for _, inpName := range inputNames {
 				inp := gr.AddInput()
 				inp.Type = "dyn-composite-scalar"
@@ -635,7 +635,7 @@ func Create(params []qst.ParamT) (*qst.QuestionnaireT, error) {
 				inp.ColSpanControl = 12
 				inp.DynamicFunc = "GroupPreferences__0__0"
 			}
-			_, inputNames, _ := qst.GroupPreferences(nil, 0, 0)
+			_, inputNames, _ := GroupPreferences(nil, 0, 0)
 			for _, inpName := range inputNames {
 				inp := gr.AddInput()
 				inp.Name = inpName
@@ -745,7 +745,7 @@ func Create(params []qst.ParamT) (*qst.QuestionnaireT, error) {
 				inp.ColSpanControl = 12
 				inp.DynamicFunc = "GroupPreferences__1__1"
 			}
-			_, inputNames, _ := qst.GroupPreferences(nil, 1, 1)
+			_, inputNames, _ := GroupPreferences(nil, 1, 1)
 			for _, inpName := range inputNames {
 				inp := gr.AddInput()
 				inp.Type = "dyn-composite-scalar"
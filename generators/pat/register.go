@@ -0,0 +1,33 @@
+package pat
+
+import "github.com/zew/go-questionnaire/qst"
+
+// survey specific funcs - referenced by inputs of type dyn-composite and dyn-textblock
+func init() {
+
+	qst.RegisterComposite("PoliticalFoundationsPretext", PoliticalFoundationsPretext, qst.FuncMetaT{
+		SurveyType: "pat",
+		Params:     []string{"unused", "unused"},
+		Desc:       "one of 16 introductions to PoliticalFoundations - based on user ID",
+	})
+	qst.RegisterComposite("PoliticalFoundations", PoliticalFoundations, qst.FuncMetaT{
+		SurveyType: "pat",
+		Params:     []string{"question sequence 0...5", "unused"},
+		Desc:       "three foundations with radio and checkbox inputs - four permutations based on user ID",
+	})
+	qst.RegisterComposite("TimePreferenceSelf", TimePreferenceSelf, qst.FuncMetaT{
+		SurveyType: "pat",
+		Params:     []string{"question sequence", "label set a or b"},
+		Desc:       "table of six options and three checkboxes - versions based on user ID",
+	})
+	qst.RegisterComposite("GroupPreferences", GroupPreferences, qst.FuncMetaT{
+		SurveyType: "pat",
+		Params:     []string{"question sequence", "label set a or b"},
+		Desc:       "table of three options - 16 versions based on user ID",
+	})
+
+	qst.RegisterDynFunc("PatLogos", PatLogos, qst.FuncMetaT{
+		SurveyType: "pat",
+		Desc:       "logos of the participating universities",
+	})
+}
@@ -3,18 +3,21 @@ package generators
 import (
 	"bytes"
 	myfmt "fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/go-playground/form"
 	"github.com/zew/go-questionnaire/cfg"
 	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/generators/dsl"
 	"github.com/zew/go-questionnaire/generators/example"
 	"github.com/zew/go-questionnaire/generators/fmt"
 	"github.com/zew/go-questionnaire/generators/pat"
@@ -35,9 +38,26 @@ var gens = map[string]genT{
 	// "lt2020":  lt2020.Create,
 }
 
-// Get returns all questionnaire generators
+// Get returns all questionnaire generators -
+// the Go generators above and the definition files in dsl.Dir;
+// Go generators take precedence.
 func Get() map[string]genT {
-	return gens
+	ret := make(map[string]genT, len(gens))
+	for key, fnc := range gens {
+		ret[key] = fnc
+	}
+	types, err := dsl.List()
+	if err != nil {
+		log.Printf("Could not list questionnaire definitions in %v: %v", dsl.Dir, err)
+	}
+	for _, key := range types {
+		if _, ok := ret[key]; ok {
+			log.Printf("Questionnaire definition %v ignored - Go generator of same name", key)
+			continue
+		}
+		ret[key] = dsl.Generator(key)
+	}
+	return ret
 }
 
 func get() []string {
@@ -46,6 +66,7 @@ func get() []string {
 	for key := range gens {
 		ret = append(ret, key)
 	}
+	sort.Strings(ret)
 	return ret
 }
 
@@ -126,56 +147,59 @@ func GenerateQuestionnaireTemplates(w http.ResponseWriter, r *http.Request) {
 		q.Survey = s
 		q.Survey.Org, q.Survey.Name = tr1, tr2
 
-		fn := path.Join(qst.BasePath(), key+".json")
-		err = q.Save1(fn)
+		hash, err := q.PublishTemplate(key, "generated")
 		if err != nil {
-			myfmt.Fprintf(w, "Error saving %v: %v<br>\n", fn, err)
+			myfmt.Fprintf(w, "Error saving %v: %v<br>\n", qst.TemplatePath(key), err)
 			return
 		}
-		myfmt.Fprintf(w, "%v generated<br>\n", key)
-
-		//
-		// create empty styles-quest-[surveytype].css"
-		// if it does not yet exist
-		fcCreate := func(desktopOrMobile string) (bool, error) {
-			pth := path.Join(".", "templates", desktopOrMobile+q.Survey.Type+".css")
-			_, err := cloudio.ReadFile(pth)
-			if err != nil {
-				if cloudio.IsNotExist(err) {
-					rdr := &bytes.Buffer{}
-					err := cloudio.WriteFile(pth, rdr, 0755)
-					if err != nil {
-						return false, myfmt.Errorf("Could not create %v: %v <br>\n", pth, err)
-					}
-					myfmt.Fprintf(w, "Done creating template %v<br>\n", pth)
-					return true, nil
-				} else {
-					return false, myfmt.Errorf("Other error while checking for %v: %v <br>\n", pth, err)
+		myfmt.Fprintf(w, "%v generated - version %v<br>\n", key, qst.ShortHash(hash))
+
+		CreateSurveyCSS(w, q.Survey.Type)
+
+	}
+}
+
+// CreateSurveyCSS creates an empty styles-quest-[surveytype].css
+// if it does not yet exist - and re-parses all templates
+func CreateSurveyCSS(w io.Writer, surveyType string) {
+
+	fcCreate := func(desktopOrMobile string) (bool, error) {
+		pth := path.Join(".", "templates", desktopOrMobile+surveyType+".css")
+		_, err := cloudio.ReadFile(pth)
+		if err != nil {
+			if cloudio.IsNotExist(err) {
+				rdr := &bytes.Buffer{}
+				err := cloudio.WriteFile(pth, rdr, 0755)
+				if err != nil {
+					return false, myfmt.Errorf("Could not create %v: %v <br>\n", pth, err)
 				}
+				myfmt.Fprintf(w, "Done creating template %v<br>\n", pth)
+				return true, nil
+			} else {
+				return false, myfmt.Errorf("Other error while checking for %v: %v <br>\n", pth, err)
 			}
-			return false, nil
 		}
+		return false, nil
+	}
 
-		// add to parsed templates
-		for _, bt := range []string{"styles-quest-"} {
-			ok, err := fcCreate(bt)
+	// add to parsed templates
+	for _, bt := range []string{"styles-quest-"} {
+		ok, err := fcCreate(bt)
+		if err != nil {
+			myfmt.Fprintf(w, "Could not generate template %v for %v<br>\n", bt, err)
+			continue
+		}
+		if ok {
+			// parse new and previous templates
+			dummyReq, err := http.NewRequest("GET", "", nil)
 			if err != nil {
-				myfmt.Fprintf(w, "Could not generate template %v for %v<br>\n", bt, err)
-				continue
+				log.Fatalf("failed to create request for pre-loading assets %v", err)
 			}
-			if ok {
-				// parse new and previous templates
-				dummyReq, err := http.NewRequest("GET", "", nil)
-				if err != nil {
-					log.Fatalf("failed to create request for pre-loading assets %v", err)
-				}
-				respRec := httptest.NewRecorder()
-				tpl.TemplatesPreparse(respRec, dummyReq)
-				log.Printf("\n%v", respRec.Body.String())
+			respRec := httptest.NewRecorder()
+			tpl.TemplatesPreparse(respRec, dummyReq)
+			log.Printf("\n%v", respRec.Body.String())
 
-			}
 		}
-
 	}
 }
 
@@ -252,7 +276,7 @@ func GenerateLandtagsVariations(w http.ResponseWriter, r *http.Request) {
 		}
 
 		fnNew := strings.ReplaceAll(fn, ".json", myfmt.Sprintf("-%02v.json", i))
-		qst.Save1(fnNew)
+		qst.Save1Unconditionally(fnNew)
 
 		myfmt.Fprintf(w, "Iter %v - stop; resp status %v<br><br>\n", i, resp.Status)
 		myfmt.Fprintf(w, "<hr>\n")
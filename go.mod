@@ -20,11 +20,10 @@ require (
 	go.opencensus.io v0.22.4 // indirect
 	gocloud.dev v0.20.0
 	golang.org/x/crypto v0.0.0-20200709230013-948cd5f35899
-	golang.org/x/net v0.0.0-20200707034311-ab3426394381 // indirect
-	golang.org/x/sys v0.0.0-20200625212154-ddb9806d33ae // indirect
 	golang.org/x/text v0.3.3 // indirect
-	golang.org/x/tools v0.0.0-20200709181711-e327e1019dfe // indirect
 	google.golang.org/api v0.29.0
 	google.golang.org/genproto v0.0.0-20200709005830-7a2ca40e9dc3 // indirect
 	google.golang.org/grpc v1.30.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0
+	modernc.org/sqlite v1.21.2
 )
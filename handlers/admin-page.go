@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+)
+
+// adminPage writes a standalone HTML page for admin forms;
+// layout.html wraps its content into form frmMain - and forms cannot be nested;
+// compare lgn.OuterHTMLPost().
+func adminPage(w http.ResponseWriter, htmlTitle, content string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8" />
+	<title>%v</title>
+	<meta name="viewport" content="width=device-width, initial-scale=1.0"/>
+	<style>
+		* {font-family: monospace;}
+		td, th {padding: 0.1rem 0.6rem; text-align: left; vertical-align: top;}
+	</style>
+</head>
+<body style="margin: 50px;">
+%v
+</body>
+</html>
+`, html.EscapeString(htmlTitle), content)
+}
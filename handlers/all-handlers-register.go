@@ -40,6 +40,13 @@ func RegisterHandlers(mux *http.ServeMux) {
 			ShortCut: "l",
 			Allow:    map[handler.Privilege]bool{handler.LoggedOut: true},
 		},
+		{
+			Urls:    []string{"/api-login"},
+			Title:   "Login for API clients",
+			Handler: lgn.APILoginH,
+			Keys:    []string{"api-login"},
+			Allow:   map[handler.Privilege]bool{handler.LoggedOut: true},
+		},
 		{
 			Urls:    []string{"/change-password-primitive"},
 			Title:   "Change password",
@@ -242,6 +249,34 @@ func RegisterHandlers(mux *http.ServeMux) {
 			Keys:    []string{"generate-questionnaire-templates"},
 			Allow:   map[handler.Privilege]bool{handler.Admin: true},
 		},
+		{
+			Urls:    []string{"/templates-upload"},
+			Handler: TemplatesUploadH,
+			Title:   "Upload Questionnaire Template",
+			Keys:    []string{"templates-upload"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
+		{
+			Urls:    []string{"/templates/versions"},
+			Handler: TemplateVersionsH,
+			Title:   "Questionnaire Template Versions",
+			Keys:    []string{"templates-versions"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
+		{
+			Urls:    []string{"/templates/diff"},
+			Handler: TemplateDiffH,
+			Title:   "Questionnaire Template Diff",
+			Keys:    []string{"templates-diff"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
+		{
+			Urls:    []string{"/preview"},
+			Handler: PreviewH,
+			Title:   "Preview Questionnaire",
+			Keys:    []string{"preview"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
 		{
 			Urls:    []string{"/generate-landtag-variations"},
 			Handler: generators.GenerateLandtagsVariations,
@@ -284,6 +319,83 @@ func RegisterHandlers(mux *http.ServeMux) {
 			Keys:    []string{"transferrer-endpoint"},
 			Allow:   map[handler.Privilege]bool{handler.Admin: true},
 		},
+		{
+			Urls:    []string{"/transferrer-waves"},
+			Handler: TransferrerWavesH,
+			Title:   "Transferrer - waves of a survey",
+			Keys:    []string{"transferrer-waves"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
+		{
+			Urls:    []string{"/migrate"},
+			Title:   "Migrate responses to changed template",
+			Handler: MigrateH,
+			Keys:    []string{"migrate"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
+		{
+			Urls:    []string{"/status"},
+			Title:   "Fieldwork status",
+			Handler: StatusH,
+			Keys:    []string{"status"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
+		{
+			Urls:    []string{"/analytics"},
+			Title:   "Dropout funnel and time on page",
+			Handler: AnalyticsH,
+			Keys:    []string{"analytics"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
+		{
+			Urls:    []string{"/changelog"},
+			Title:   "Answer changes",
+			Handler: ChangeLogH,
+			Keys:    []string{"changelog"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
+		{
+			Urls:    []string{"/store-import"},
+			Title:   "Import responses into database",
+			Handler: StoreImportH,
+			Keys:    []string{"store-import"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
+		{
+			Urls:    []string{"/logins/list"},
+			Title:   "Logins",
+			Handler: LoginsAdminH,
+			Keys:    []string{"logins-list"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
+		{
+			Urls:    []string{"/logins/edit"},
+			Title:   "Edit login",
+			Handler: LoginEditH,
+			Keys:    []string{"logins-edit"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
+		{
+			Urls:    []string{"/logins/import"},
+			Title:   "Import participants",
+			Handler: LoginsImportH,
+			Keys:    []string{"logins-import"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
+		{
+			Urls:    []string{"/export"},
+			Handler: ExportH,
+			Title:   "Export responses as CSV, XLSX, SPSS",
+			Keys:    []string{"export"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
+		{
+			Urls:    []string{"/codebook"},
+			Handler: CodebookH,
+			Title:   "Codebook of a questionnaire template",
+			Keys:    []string{"codebook"},
+			Allow:   map[handler.Privilege]bool{handler.Admin: true},
+		},
 	}
 
 	infos.MakeKeys()
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/zew/go-questionnaire/analytics"
+	"github.com/zew/go-questionnaire/sessx"
+	"github.com/zew/go-questionnaire/tpl"
+)
+
+// AnalyticsH shows the dropout funnel and time on page of a survey wave;
+// parameters survey_id, wave_id, format=html|json,
+// attrs - comma separated login attributes to break down by; default all.
+func AnalyticsH(w http.ResponseWriter, r *http.Request) {
+
+	sess := sessx.New(w, r)
+
+	surveyID, ok := sess.ReqParam("survey_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a survey_id parameter.")
+		return
+	}
+	waveID, ok := sess.ReqParam("wave_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a wave_id parameter.")
+		return
+	}
+	format, _ := sess.ReqParam("format")
+	if format == "" {
+		format = "html"
+	}
+	var attrs []string
+	if a, _ := sess.ReqParam("attrs"); a != "" {
+		for _, k := range strings.Split(a, ",") {
+			attrs = append(attrs, strings.TrimSpace(k))
+		}
+	}
+
+	rep, err := analytics.Wave(surveyID, waveID, attrs)
+	if err != nil {
+		helper(w, r, err, "Could not compute analytics.")
+		return
+	}
+
+	switch format {
+	case "json":
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rep); err != nil {
+			log.Printf("analytics %v %v as json failed: %v", surveyID, waveID, err)
+		}
+		return
+	case "html":
+	default:
+		helper(w, r, nil, fmt.Sprintf("Unknown format %q - use html or json.", format))
+		return
+	}
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "<h3>Dropout funnel and time on page %v %v</h3>\n", html.EscapeString(surveyID), html.EscapeString(waveID))
+	fmt.Fprint(b, "<p>Times in seconds between leaving the previous page and leaving the page; none for the first page.</p>\n")
+	if len(rep.Unreadable) > 0 {
+		fmt.Fprintf(b, "<p style='color:red'>%v response files could not be loaded - and are not counted:<br>\n", len(rep.Unreadable))
+		for _, u := range rep.Unreadable {
+			fmt.Fprintf(b, "%v: %v<br>\n", html.EscapeString(u.Key), html.EscapeString(u.Error))
+		}
+		fmt.Fprint(b, "</p>\n")
+	}
+	segmentHTML(b, "All participants", rep.Total)
+	for _, sg := range rep.Breakdowns {
+		segmentHTML(b, fmt.Sprintf("%v = %v", sg.Dimension, sg.Value), sg)
+	}
+
+	tpl.ExecContent(w, r, b.String(), "layout.html")
+}
+
+func segmentHTML(b *bytes.Buffer, title string, sg *analytics.SegmentT) {
+	fmt.Fprintf(b, "<h4>%v - %v participants, %v completed</h4>\n", html.EscapeString(title), sg.Participants, sg.Completed)
+	fmt.Fprint(b, "<table>\n<tr><th>Page</th><th>Reached</th><th>Finished</th><th>Dropped</th>")
+	fmt.Fprint(b, "<th>Timings</th><th>Q1</th><th>Median</th><th>Q3</th></tr>\n")
+	for _, p := range sg.Pages {
+		fmt.Fprintf(b, "<tr><td>%v</td><td>%v</td><td>%v</td><td>%v</td><td>%v</td><td>%.0f</td><td>%.0f</td><td>%.0f</td></tr>\n",
+			p.Page+1, p.Reached, p.Finished, p.Dropped, p.Timings, p.Q1, p.Median, p.Q3)
+	}
+	fmt.Fprint(b, "</table>\n")
+}
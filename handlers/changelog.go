@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/export"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/sessx"
+)
+
+// ChangeLogH responds with the answer changes of all participants of a survey wave
+// as CSV - one row per change; parameters survey_id, wave_id;
+// see cfg change_log.
+func ChangeLogH(w http.ResponseWriter, r *http.Request) {
+
+	sess := sessx.New(w, r)
+
+	surveyID, ok := sess.ReqParam("survey_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a survey_id parameter.")
+		return
+	}
+	waveID, ok := sess.ReqParam("wave_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a wave_id parameter.")
+		return
+	}
+
+	dir := path.Join(qst.BasePath(), surveyID, waveID, "changelog")
+	infos, err := cloudio.ReadDir(dir)
+	if err != nil {
+		helper(w, r, err, fmt.Sprintf("Could not read directory %v.", dir))
+		return
+	}
+
+	header := []string{"user_id", "time", "page", "name", "old", "new", "remote_ip"}
+	rows := [][]string{}
+	for _, info := range *infos {
+		if info.IsDir || !strings.HasSuffix(info.Key, ".ndjson") {
+			continue
+		}
+		userID := strings.TrimSuffix(path.Base(info.Key), ".ndjson")
+		changes, err := qst.ReadChanges(info.Key)
+		if err != nil {
+			log.Printf("changelog %v: %v", info.Key, err) // keep what could be read
+		}
+		for _, chg := range changes {
+			rows = append(rows, []string{
+				userID,
+				chg.Time.Format("2006-01-02 15:04:05"),
+				fmt.Sprint(chg.Page + 1),
+				chg.Name,
+				chg.Old,
+				chg.New,
+				chg.RemoteIP,
+			})
+		}
+	}
+
+	baseName := fmt.Sprintf("changelog-%v-%v", surveyID, waveID)
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", baseName+".csv"))
+	err = export.WriteCSV(w, header, rows)
+	if err != nil {
+		log.Printf("changelog %v as csv failed: %v", baseName, err)
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/zew/go-questionnaire/sessx"
+)
+
+// sessionServer serves h with sessions - as main() does;
+// the returned client keeps the session cookie
+func sessionServer(t *testing.T, h http.HandlerFunc) (*httptest.Server, *http.Client) {
+	t.Helper()
+	srv := httptest.NewServer(sessx.Mgr().LoadAndSave(h))
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, &http.Client{Jar: jar}
+}
+
+var tokenRx = regexp.MustCompile(`name=.token. value=.([^'"]+)`)
+
+// formToken extracts the form token from an admin page
+func formToken(t *testing.T, page string) string {
+	t.Helper()
+	m := tokenRx.FindStringSubmatch(page)
+	if m == nil {
+		t.Fatalf("no form token in\n%v", page)
+	}
+	return m[1]
+}
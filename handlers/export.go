@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+
+	"github.com/zew/go-questionnaire/export"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/sessx"
+	"github.com/zew/go-questionnaire/tpl"
+)
+
+// ExportH responds with the responses of a survey wave
+// as CSV, XLSX or SPSS syntax file - or as CSV in long format;
+// parameters survey_id, wave_id, format=csv|xlsx|sps|long,
+// fetch_all - include unfinished questionnaires,
+// lang_code - language of SPSS labels.
+//
+// The SPSS syntax reads the CSV file of the same wave.
+func ExportH(w http.ResponseWriter, r *http.Request) {
+
+	sess := sessx.New(w, r)
+
+	surveyID, ok := sess.ReqParam("survey_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a survey_id parameter.")
+		return
+	}
+	waveID, ok := sess.ReqParam("wave_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a wave_id parameter.")
+		return
+	}
+	format, _ := sess.ReqParam("format")
+	if format == "" {
+		format = "csv"
+	}
+	fetchAll, _ := sess.ReqParam("fetch_all")
+
+	baseName := fmt.Sprintf("online-responses-%v-%v", surveyID, waveID)
+
+	// template for labels
+	var q *qst.QuestionnaireT
+	if format == "sps" {
+		var err error
+		q, err = qst.Load1(path.Join(qst.BasePath(), surveyID+".json"))
+		if err != nil {
+			log.Printf("export: no template for labels: %v", err)
+			q = nil
+		}
+	}
+
+	qs, err := export.LoadWave(surveyID, waveID, fetchAll != "")
+	if err != nil {
+		helper(w, r, err, "Could not load responses.")
+		return
+	}
+	header, rows := export.WideMatrix(qs)
+	log.Printf("export %v: %v questionnaires, %v columns", baseName, len(qs), len(header))
+
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", baseName+".csv"))
+		err = export.WriteCSV(w, header, rows)
+	case "long":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", baseName+"-long.csv"))
+		err = export.WriteLong(w, qs)
+	case "xlsx":
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", baseName+".xlsx"))
+		err = export.WriteXLSX(w, header, rows)
+	case "sps":
+		lc, _ := sess.ReqParam("lang_code")
+		if lc == "" && q != nil && len(q.LangCodes) > 0 {
+			lc = q.LangCodes[0]
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", baseName+".sps"))
+		err = export.WriteSPSS(w, q, header, baseName+".csv", lc)
+	default:
+		helper(w, r, nil, fmt.Sprintf("Unknown format %q - use csv, xlsx, sps or long.", format))
+		return
+	}
+	if err != nil {
+		// headers are already sent
+		log.Printf("export %v as %v failed: %v", baseName, format, err)
+	}
+
+}
+
+// CodebookH responds with the codebook of a questionnaire template;
+// parameters survey_id, format=html|md|json,
+// lang_code - language of labels; empty for all languages.
+func CodebookH(w http.ResponseWriter, r *http.Request) {
+
+	sess := sessx.New(w, r)
+
+	surveyID, ok := sess.ReqParam("survey_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a survey_id parameter.")
+		return
+	}
+	format, _ := sess.ReqParam("format")
+	if format == "" {
+		format = "html"
+	}
+	lc, _ := sess.ReqParam("lang_code")
+
+	q, err := qst.Load1(path.Join(qst.BasePath(), surveyID+".json"))
+	if err != nil {
+		helper(w, r, err, "Could not load questionnaire template.")
+		return
+	}
+	cb := export.Codebook(q)
+	baseName := fmt.Sprintf("codebook-%v", surveyID)
+
+	switch format {
+	case "html":
+		b := &bytes.Buffer{}
+		err = cb.WriteHTML(b, lc)
+		if err == nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			tpl.ExecContent(w, r, b.String(), "layout.html")
+		}
+	case "md":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", baseName+".md"))
+		err = cb.WriteMarkdown(w, lc)
+	case "json":
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		err = cb.WriteJSON(w)
+	default:
+		helper(w, r, nil, fmt.Sprintf("Unknown format %q - use html, md or json.", format))
+		return
+	}
+	if err != nil {
+		helper(w, r, err, "Could not write codebook.")
+	}
+
+}
@@ -40,7 +40,14 @@ func loadQuestionnaire(w http.ResponseWriter, r *http.Request, l *lgn.LoginT) (*
 		return q, err
 	}
 	if ok {
-		return q, nil
+		sess := sessx.New(w, r)
+		loaded := sess.GetTime(r.Context(), "questionnaire_loaded")
+		if !migratedSince(l.Attrs["survey_id"], loaded) {
+			return q, nil
+		}
+		// responses were migrated onto a changed template meanwhile
+		sess.Remove(r.Context(), "questionnaire")
+		log.Printf("template was migrated since %v - questionnaire will be reloaded from file", loaded)
 	}
 
 	// from file
@@ -63,13 +70,21 @@ func loadQuestionnaire(w http.ResponseWriter, r *http.Request, l *lgn.LoginT) (*
 			return q, err
 		}
 		log.Printf("No previous user questionnaire file %v found. Using base file.", pth)
+		qBase.RecordTemplateVersion(qBase.MD5)
 	} else {
-		err = qBase.Join(qSplit)
-		if err != nil {
-			log.Printf("\tJoining base questionnaire with user data yielded error:    %v", err)
+		// template might have changed since the previous session;
+		// joining by name is acceptable - as long as no response is lost;
+		// otherwise the next save would overwrite them - see MigrateH
+		byName, lost := qBase.Migrate(qSplit)
+		if len(lost) > 0 {
+			err = fmt.Errorf("template changed; %v responses cannot be carried over: %v - migrate the wave first", len(lost), lost)
 			return q, err
 		}
+		if byName {
+			log.Printf("\tTemplate changed; responses joined by name; none lost")
+		}
 	}
+	sessx.New(w, r).PutObject("questionnaire_loaded", time.Now())
 
 	q = qBase
 	err = q.Validate()
@@ -174,7 +189,7 @@ func MainH(w http.ResponseWriter, r *http.Request) {
 
 	token, ok := sess.ReqParam("token")
 	if ok {
-		err = lgn.ValidateFormToken(token)
+		err = lgn.ValidateFormToken(r, token)
 		if err != nil {
 			helper(w, r, err)
 			return
@@ -206,6 +221,14 @@ func MainH(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The form was rendered before answers were saved from another window;
+	// its values would overwrite them
+	if rev, ok := sess.ReqParam("revision"); ok && rev != fmt.Sprint(q.Revision) {
+		log.Printf("form revision %v - but questionnaire revision %v; reloading", rev, q.Revision)
+		revisionConflict(w, r)
+		return
+	}
+
 	//
 	// Meta parameters
 	// =============
@@ -275,6 +298,43 @@ func MainH(w http.ResponseWriter, r *http.Request) {
 		q.CurrPage = 0
 		prevPage = 0
 	}
+
+	//
+	// Put request values into questionnaire;
+	// before navigating - page conditions may depend on them
+	if q.Pages[prevPage].Finished.IsZero() {
+		q.Pages[prevPage].Finished = time.Now().Truncate(time.Second)
+	}
+	logChanges := cfg.Get().ChangeLog[q.Survey.Type]
+	changes := []qst.ChangeT{}
+	for i1 := 0; i1 < len(q.Pages[prevPage].Groups); i1++ {
+		for i2 := range q.Pages[prevPage].Groups[i1].Inputs {
+			inp := q.Pages[prevPage].Groups[i1].Inputs[i2]
+			if inp.IsLayout() {
+				continue
+			}
+			// log.Printf("checking for %v", inp.Name)
+			// amazingly, this works for scattered radio inputs as well
+			ok := sess.EffectiveIsSet(inp.Name)
+			if ok {
+				val := sess.EffectiveStr(inp.Name)
+				log.Printf("(Page#%2v) Setting %-24q to '%v'", prevPage, inp.Name, val)
+				val = html.EscapeString(val) // XSS prevention
+				if logChanges && val != inp.Response && !changedAlready(changes, inp.Name) {
+					changes = append(changes, qst.ChangeT{
+						Time:     time.Now().Truncate(time.Second),
+						Page:     prevPage,
+						Name:     inp.Name,
+						Old:      inp.Response,
+						New:      val,
+						RemoteIP: r.RemoteAddr,
+					})
+				}
+				q.Pages[prevPage].Groups[i1].Inputs[i2].Response = val
+			}
+		}
+	}
+
 	currPage := prevPage // Default assumption: we are still on prev page - unless there is some modification:
 	submit := sess.EffectiveStr("submitBtn")
 	if submit == "prev" {
@@ -287,7 +347,7 @@ func MainH(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			// invalid page value, just dont use it
 		}
-		if ok && err == nil && explicit > -1 {
+		if ok && err == nil && explicit > -1 && explicit < len(q.Pages) {
 			log.Printf("curPage set explicitly by 'submitBtn' to %v", explicit)
 			currPage = explicit
 		}
@@ -299,35 +359,17 @@ func MainH(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// invalid page value, just dont use it
 	}
-	if ok && err == nil && explicit > -1 {
+	if ok && err == nil && explicit > -1 && explicit < len(q.Pages) {
 		log.Printf("curPage set explicitly by param 'page' to %v", explicit)
 		currPage = explicit
 	}
 	q.CurrPage = currPage // Put current page into questionnaire
-	log.Printf("submitBtn was '%v' - new currPage is %v", submit, currPage)
-
-	//
-	// Put request values into questionnaire
-	if q.Pages[prevPage].Finished.IsZero() {
-		q.Pages[prevPage].Finished = time.Now().Truncate(time.Second)
-	}
-	for i1 := 0; i1 < len(q.Pages[prevPage].Groups); i1++ {
-		for i2 := range q.Pages[prevPage].Groups[i1].Inputs {
-			inp := q.Pages[prevPage].Groups[i1].Inputs[i2]
-			if inp.IsLayout() {
-				continue
-			}
-			// log.Printf("checking for %v", inp.Name)
-			// amazingly, this works for scattered radio inputs as well
-			ok := sess.EffectiveIsSet(inp.Name)
-			if ok {
-				val := sess.EffectiveStr(inp.Name)
-				log.Printf("(Page#%2v) Setting %-24q to '%v'", prevPage, inp.Name, val)
-				val = html.EscapeString(val) // XSS prevention
-				q.Pages[prevPage].Groups[i1].Inputs[i2].Response = val
-			}
-		}
+	if !q.IsPageVisible(q.CurrPage) {
+		// explicit destination is hidden by its condition - skip to next visible
+		q.CurrPage = q.Next()
+		log.Printf("page %v hidden by condition - moving to %v", currPage, q.CurrPage)
 	}
+	log.Printf("submitBtn was '%v' - new currPage is %v", submit, q.CurrPage)
 
 	if sess.EffectiveStr("skip_validation") == "" && r.Method == "POST" {
 		err = q.ValidateResponseData(prevPage, q.LangCode)
@@ -340,6 +382,7 @@ func MainH(w http.ResponseWriter, r *http.Request) {
 		q.RemoteIP = r.RemoteAddr
 	}
 	q.UserAgent = r.Header.Get("User-Agent")
+	recordMobile(sess, r, q)
 
 	if ok := sess.EffectiveIsSet("finished"); ok {
 		if sess.EffectiveStr("finished") == qst.ValSet {
@@ -352,17 +395,33 @@ func MainH(w http.ResponseWriter, r *http.Request) {
 		log.Printf("ComputeDynamicContent computation for page %v caused error %v", prevPage, err)
 	}
 
-	//
-	//
-	// Save questionnaire into session
-	sess.PutObject("questionnaire", q)
-
 	q2, _ := q.Split()
 	err = q2.Save1(l.QuestPath())
+	if err == qst.ErrConflict {
+		// saved meanwhile by another session - i.e. another device
+		sess.Remove(r.Context(), "questionnaire")
+		revisionConflict(w, r)
+		return
+	}
 	if err != nil {
 		helper(w, r, err, "Saving splitted repsonses to file caused error")
 		return
 	}
+	q.Revision = q2.Revision
+	err = qst.AppendChanges(l.QuestPath(), changes)
+	if err != nil {
+		log.Printf("Could not append to change log of %v: %v", l.QuestPath(), err)
+	}
+
+	//
+	//
+	// Save questionnaire into session
+	sess.PutObject("questionnaire", q)
+
+	conflict := sess.GetBool(r.Context(), "revision_conflict")
+	if conflict {
+		sess.Remove(r.Context(), "revision_conflict")
+	}
 
 	//
 	//
@@ -381,9 +440,9 @@ func MainH(w http.ResponseWriter, r *http.Request) {
 		"LogoTitle": q.Survey.TemplateLogoText(q.LangCode),
 		"Q":         q,
 		"Content":   "",
-	}
 
-	// mobile := computeMobile(w, r, q)
+		"RevisionConflict": conflict,
+	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
@@ -397,40 +456,49 @@ func MainH(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func computeMobile(w http.ResponseWriter, r *http.Request, q *qst.QuestionnaireT) bool {
-
-	sess := sessx.New(w, r)
-
-	// determination from the browser string
-	mobile := false
-	if detect.IsMobile(r) {
-		mobile = true
-	}
-
-	// override by explicit url parameter
-	if mP, ok := sess.ReqParam("mobile"); ok {
-		if mP == "0" || mP == "false" {
-			mobile = false
-			q.Mobile = 0 // no user preference
-		}
-		if mP == "1" || mP == "true" {
-			mobile = true
-			q.Mobile = 1 // explicit mobile
-		}
-		if mP == "2" || mP == "desktop" {
-			mobile = false
-			q.Mobile = 2 // explicit desktop
+// changedAlready - scattered radio inputs share their name
+func changedAlready(changes []qst.ChangeT, name string) bool {
+	for _, chg := range changes {
+		if chg.Name == name {
+			return true
 		}
 	}
+	return false
+}
 
-	// log.Printf("Mobile = %v", q.Mobile)
+// revisionConflict reloads the questionnaire without the request values
+// and shows a message
+func revisionConflict(w http.ResponseWriter, r *http.Request) {
+	sess := sessx.New(w, r)
+	sess.PutObject("revision_conflict", true)
+	http.Redirect(w, r, cfg.Pref("/"), http.StatusSeeOther)
+}
 
-	if q.Mobile == 1 {
-		return true
-	}
-	if q.Mobile == 2 {
-		return false
-	}
+// mobileOf returns the new value of q.Mobile - see there;
+// URL parameter mobile - 1, true, 2, desktop - sets an explicit preference;
+// 0 or false withdraw it; otherwise a preference is kept,
+// and an unknown device is taken from the user agent.
+func mobileOf(current int, param string, isMobileUA bool) int {
+	switch param {
+	case "1", "true":
+		return 2
+	case "2", "desktop":
+		return 1
+	case "0", "false":
+		current = 0
+	}
+	if current != 0 {
+		return current
+	}
+	if isMobileUA {
+		return 2
+	}
+	return 1
+}
 
-	return mobile
+// recordMobile stores device or preference of the participant
+// into q - for analytics; layout is adapted by CSS media queries.
+func recordMobile(sess *sessx.SessT, r *http.Request, q *qst.QuestionnaireT) {
+	mP, _ := sess.ReqParam("mobile")
+	q.Mobile = mobileOf(q.Mobile, mP, detect.IsMobile(r))
 }
@@ -0,0 +1,29 @@
+package handlers
+
+import "testing"
+
+func TestMobileOf(t *testing.T) {
+	tests := []struct {
+		current    int
+		param      string
+		isMobileUA bool
+		want       int
+	}{
+		{0, "", false, 1},        // detected desktop
+		{0, "", true, 2},         // detected mobile
+		{1, "", true, 1},         // kept
+		{2, "", false, 2},        // kept
+		{0, "1", false, 2},       // explicit mobile
+		{1, "true", false, 2},    // explicit mobile
+		{2, "2", true, 1},        // explicit desktop
+		{0, "desktop", true, 1},  // explicit desktop
+		{2, "0", false, 1},       // withdrawn - detected desktop
+		{1, "false", true, 2},    // withdrawn - detected mobile
+		{2, "garbage", false, 2}, // ignored
+	}
+	for _, tc := range tests {
+		if got := mobileOf(tc.current, tc.param, tc.isMobileUA); got != tc.want {
+			t.Errorf("mobileOf(%v, %q, %v) = %v - want %v", tc.current, tc.param, tc.isMobileUA, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/lgn"
+)
+
+// checkFormToken is required for all modifying requests
+func checkFormToken(r *http.Request) error {
+	if r.Method != "POST" {
+		return fmt.Errorf("modifications require method POST")
+	}
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	return lgn.ValidateFormToken(r, r.PostForm.Get("token"))
+}
+
+// keyVals renders a map as lines of key: value
+func keyVals(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(m))
+	for _, k := range keys {
+		lines = append(lines, k+": "+m[k])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseKeyVals is the inverse of keyVals()
+func parseKeyVals(s string) (map[string]string, error) {
+	m := map[string]string{}
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("line %v: %q must be key: value", i+1, line)
+		}
+		m[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return m, nil
+}
+
+// actionButton renders a POST form with a single button
+func actionButton(b io.Writer, token, action, user, label string) {
+	fmt.Fprintf(b, `<form method="post" action="%v" style="display:inline">`, cfg.Pref("/logins/list"))
+	fmt.Fprintf(b, `<input type="hidden" name="token" value="%v">`, token)
+	fmt.Fprintf(b, `<input type="hidden" name="action" value="%v">`, action)
+	fmt.Fprintf(b, `<input type="hidden" name="u" value="%v">`, html.EscapeString(user))
+	fmt.Fprintf(b, `<button type="submit">%v</button></form>`, label)
+}
+
+// LoginsAdminH lists the logins;
+// POST parameter action with reset, disable, enable or delete modifies login u;
+// see lgn.ResetInitPW() and others.
+// Every modification saves the logins file - keeping a backup of the previous one.
+func LoginsAdminH(w http.ResponseWriter, r *http.Request) {
+
+	b := &bytes.Buffer{}
+
+	if r.Method == "POST" {
+		if err := checkFormToken(r); err != nil {
+			helper(w, r, err, "Invalid request.")
+			return
+		}
+		u := r.PostForm.Get("u")
+		var err error
+		switch action := r.PostForm.Get("action"); action {
+		case "reset":
+			var pw string
+			pw, err = lgn.ResetInitPW(u)
+			if err == nil {
+				fmt.Fprintf(b, "<p>New init password for %v: <code>%v</code></p>\n", html.EscapeString(u), html.EscapeString(pw))
+			}
+		case "disable", "enable":
+			err = lgn.SetDisabled(u, action == "disable")
+			if err == nil {
+				fmt.Fprintf(b, "<p>%v %vd.</p>\n", html.EscapeString(u), action)
+			}
+		case "delete":
+			err = lgn.DeleteLogin(u)
+			if err == nil {
+				fmt.Fprintf(b, "<p>%v deleted.</p>\n", html.EscapeString(u))
+			}
+		default:
+			err = fmt.Errorf("unknown action %q", action)
+		}
+		if err != nil {
+			helper(w, r, err, fmt.Sprintf("Changing login %v failed.", u))
+			return
+		}
+	}
+
+	logins := lgn.Sorted()
+	token := lgn.FormToken(r)
+	fmt.Fprintf(b, "<h3>Logins (%v)</h3>\n", len(logins))
+	fmt.Fprintf(b, "<p><a href='%v'>Create login</a> &nbsp; <a href='%v'>Import participants from CSV</a></p>\n",
+		cfg.Pref("/logins/edit"), cfg.Pref("/logins/import"))
+	fmt.Fprint(b, "<table>\n<tr><th>User</th><th>Email</th><th>Roles</th><th>Attributes</th><th>Password</th><th>Status</th><th></th></tr>\n")
+	for _, l := range logins {
+		pw := "changed"
+		if l.IsInitPassword {
+			pw = "init"
+		}
+		status := "active"
+		toggle := "disable"
+		if l.Disabled {
+			status = "disabled"
+			toggle = "enable"
+		}
+		fmt.Fprintf(b, "<tr><td><a href='%v?u=%v'>%v</a></td><td>%v</td><td><pre>%v</pre></td><td><pre>%v</pre></td><td>%v</td><td>%v</td><td>",
+			cfg.Pref("/logins/edit"), url.QueryEscape(l.User), html.EscapeString(l.User),
+			html.EscapeString(l.Email),
+			html.EscapeString(keyVals(l.Roles)), html.EscapeString(keyVals(l.Attrs)),
+			pw, status,
+		)
+		actionButton(b, token, "reset", l.User, "reset password")
+		actionButton(b, token, toggle, l.User, toggle)
+		actionButton(b, token, "delete", l.User, "delete")
+		fmt.Fprint(b, "</td></tr>\n")
+	}
+	fmt.Fprint(b, "</table>\n")
+
+	adminPage(w, "Logins", b.String())
+}
+
+// LoginEditH creates a login - or edits email, roles, attributes of login u;
+// new logins get an init password, which is shown once.
+func LoginEditH(w http.ResponseWriter, r *http.Request) {
+
+	b := &bytes.Buffer{}
+
+	l := lgn.LoginT{}
+	isNew := true
+	if err := r.ParseForm(); err != nil {
+		helper(w, r, err)
+		return
+	}
+	if u := r.Form.Get("u"); u != "" {
+		found, ok := lgn.Find(u)
+		if !ok {
+			helper(w, r, nil, fmt.Sprintf("Login %v not found.", u))
+			return
+		}
+		l, isNew = found, false
+	}
+
+	if r.Method == "POST" {
+		if err := checkFormToken(r); err != nil {
+			helper(w, r, err, "Invalid request.")
+			return
+		}
+		roles, err := parseKeyVals(r.PostForm.Get("roles"))
+		if err != nil {
+			helper(w, r, err, "Invalid roles.")
+			return
+		}
+		attrs, err := parseKeyVals(r.PostForm.Get("attrs"))
+		if err != nil {
+			helper(w, r, err, "Invalid attributes.")
+			return
+		}
+		l.Email = strings.TrimSpace(r.PostForm.Get("email"))
+		l.Roles = roles
+		l.Attrs = attrs
+		l.Disabled = r.PostForm.Get("disabled") != ""
+		if isNew {
+			l.User = strings.TrimSpace(r.PostForm.Get("user"))
+			l, err = lgn.CreateLogin(l)
+			if err != nil {
+				helper(w, r, err, "Creating login failed.")
+				return
+			}
+			fmt.Fprintf(b, "<p>Login %v created; init password: <code>%v</code></p>\n",
+				html.EscapeString(l.User), html.EscapeString(l.PassInitial))
+			isNew = false
+		} else {
+			if err := lgn.UpdateLogin(l); err != nil {
+				helper(w, r, err, "Saving login failed.")
+				return
+			}
+			fmt.Fprintf(b, "<p>Login %v saved.</p>\n", html.EscapeString(l.User))
+		}
+	}
+
+	if isNew {
+		fmt.Fprint(b, "<h3>Create login</h3>\n")
+	} else {
+		fmt.Fprintf(b, "<h3>Login %v</h3>\n", html.EscapeString(l.User))
+	}
+	fmt.Fprintf(b, "<form method='post' action='%v'>\n", cfg.Pref("/logins/edit"))
+	fmt.Fprintf(b, "<input type='hidden' name='token' value='%v'>\n", lgn.FormToken(r))
+	if isNew {
+		fmt.Fprint(b, "<label>User <input name='user' size='30'></label><br>\n")
+	} else {
+		fmt.Fprintf(b, "<input type='hidden' name='u' value='%v'>\n", html.EscapeString(l.User))
+	}
+	checked := ""
+	if l.Disabled {
+		checked = "checked"
+	}
+	fmt.Fprintf(b, "<label>Email <input name='email' size='40' value='%v'></label><br>\n", html.EscapeString(l.Email))
+	fmt.Fprint(b, "Roles - one per line, i.e. <code>admin: yes</code><br>\n")
+	fmt.Fprintf(b, "<textarea name='roles' rows='3' cols='50'>%v</textarea><br>\n", html.EscapeString(keyVals(l.Roles)))
+	fmt.Fprint(b, "Attributes - one per line, i.e. <code>survey_id: fmt</code><br>\n")
+	fmt.Fprintf(b, "<textarea name='attrs' rows='6' cols='50'>%v</textarea><br>\n", html.EscapeString(keyVals(l.Attrs)))
+	fmt.Fprintf(b, "<label><input type='checkbox' name='disabled' value='1' %v> disabled</label><br>\n", checked)
+	fmt.Fprint(b, "<button type='submit'>save</button>\n</form>\n")
+	fmt.Fprintf(b, "<p><a href='%v'>All logins</a></p>\n", cfg.Pref("/logins/list"))
+
+	adminPage(w, "Edit login", b.String())
+}
+
+// LoginsImportH creates participant logins from CSV - see lgn.ImportCSV();
+// the init passwords are shown once.
+func LoginsImportH(w http.ResponseWriter, r *http.Request) {
+
+	b := &bytes.Buffer{}
+
+	if r.Method == "POST" {
+		if err := checkFormToken(r); err != nil {
+			helper(w, r, err, "Invalid request.")
+			return
+		}
+		created, skipped, err := lgn.ImportCSV(strings.NewReader(r.PostForm.Get("csv")))
+		if err != nil {
+			helper(w, r, err, "Import failed - no logins were created.")
+			return
+		}
+		fmt.Fprintf(b, "<h3>%v logins created</h3>\n", len(created))
+		if len(skipped) > 0 {
+			fmt.Fprintf(b, "<p>Existing users skipped: %v</p>\n", html.EscapeString(strings.Join(skipped, ", ")))
+		}
+		fmt.Fprint(b, "<pre>\nuser;init_password;attrs\n")
+		for _, l := range created {
+			fmt.Fprintf(b, "%v;%v;%v\n", html.EscapeString(l.User), html.EscapeString(l.PassInitial),
+				html.EscapeString(strings.Replace(keyVals(l.Attrs), "\n", ", ", -1)))
+		}
+		fmt.Fprint(b, "</pre>\n")
+	}
+
+	fmt.Fprint(b, "<h3>Import participants</h3>\n")
+	fmt.Fprint(b, "<p>CSV with header row: <code>user;email;survey_id;wave_id;profile</code> - further columns become attributes;<br>\n")
+	fmt.Fprint(b, "profile refers to the config profiles - prefixed by survey_id.</p>\n")
+	fmt.Fprintf(b, "<form method='post' action='%v'>\n", cfg.Pref("/logins/import"))
+	fmt.Fprintf(b, "<input type='hidden' name='token' value='%v'>\n", lgn.FormToken(r))
+	fmt.Fprint(b, "<textarea name='csv' rows='16' cols='80'></textarea><br>\n")
+	fmt.Fprint(b, "<button type='submit'>import</button>\n</form>\n")
+	fmt.Fprintf(b, "<p><a href='%v'>All logins</a></p>\n", cfg.Pref("/logins/list"))
+
+	adminPage(w, "Import logins", b.String())
+}
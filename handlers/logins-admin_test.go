@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/lgn"
+	"github.com/zew/go-questionnaire/store"
+)
+
+func TestLoginsImportH(t *testing.T) {
+
+	cfg.LoadExample()
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+	if err := store.Get().Write(lgn.LgnsPath, []byte(`{"salt": "salt-handlers-test", "logins": []}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := lgn.LoadFromStore(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, client := sessionServer(t, LoginsImportH)
+	defer srv.Close()
+
+	get := func(resp *http.Response, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bts, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(bts)
+	}
+
+	page := get(client.Get(srv.URL))
+	vals := url.Values{}
+	vals.Set("token", formToken(t, page))
+	vals.Set("csv", "user;email;survey_id;wave_id\np1;p1@x.de;fmt;2020-05\np2;;fmt;2020-05\n")
+	page = get(client.PostForm(srv.URL, vals))
+	if !strings.Contains(page, "<h3>2 logins created</h3>") || !strings.Contains(page, "p1;") {
+		t.Errorf("import failed\n%v", page)
+	}
+	if l, ok := lgn.Find("p2"); !ok || l.Attrs["wave_id"] != "2020-05" {
+		t.Errorf("p2 not imported - got %+v", l)
+	}
+
+	// the same import again - existing users are skipped
+	vals.Set("token", formToken(t, page))
+	page = get(client.PostForm(srv.URL, vals))
+	if !strings.Contains(page, "<h3>0 logins created</h3>") || !strings.Contains(page, "Existing users skipped: p1, p2") {
+		t.Errorf("repeated import must skip\n%v", page)
+	}
+}
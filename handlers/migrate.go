@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/lgn"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/sessx"
+	"github.com/zew/go-questionnaire/store"
+)
+
+// migrations records the last migration per survey;
+// questionnaires in sessions loaded before are stale
+var migrations = struct {
+	sync.Mutex
+	at map[string]time.Time
+}{at: map[string]time.Time{}}
+
+func migratedSince(surveyID string, t time.Time) bool {
+	migrations.Lock()
+	defer migrations.Unlock()
+	at, ok := migrations.at[surveyID]
+	return ok && !at.Before(t)
+}
+
+// migrateResultT is the outcome for one response file
+type migrateResultT struct {
+	Key     string
+	UserID  string
+	ByName  bool
+	Lost    []string
+	Skipped bool   // responses would be lost - not confirmed
+	Backup  string // copy of the response file before saving
+	Err     error
+}
+
+// MigrateH joins the split response files of a survey wave
+// onto the current template - and saves them back.
+// Where the structure of the template has changed,
+// responses are matched by input name; responses without counterpart are reported.
+//
+// Parameters survey_id, wave_id; GET requests are a dry run;
+// the result page contains a form to apply the migration - by POST with form token.
+// Files with responses not carried over are only saved, if confirmed per file in this form.
+// Each file is backed up before saving.
+func MigrateH(w http.ResponseWriter, r *http.Request) {
+
+	apply := r.Method == "POST"
+	if apply {
+		if err := checkFormToken(r); err != nil {
+			helper(w, r, err, "Invalid request.")
+			return
+		}
+	}
+
+	sess := sessx.New(w, r)
+
+	surveyID, ok := sess.ReqParam("survey_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a survey_id parameter.")
+		return
+	}
+	waveID, ok := sess.ReqParam("wave_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a wave_id parameter.")
+		return
+	}
+
+	dir := path.Join(qst.BasePath(), surveyID, waveID)
+	entries, err := store.Get().List(dir)
+	if err != nil {
+		helper(w, r, err, fmt.Sprintf("Could not read directory %v.", dir))
+		return
+	}
+
+	confirmed := map[string]bool{}
+	if apply {
+		for _, key := range r.PostForm["confirm"] {
+			confirmed[key] = true
+		}
+	}
+
+	results := []migrateResultT{}
+	for _, info := range entries {
+		res := migrate1(surveyID, info.Key, apply, confirmed[info.Key])
+		if res.Err != nil {
+			log.Printf("migrate %v: %v", info.Key, res.Err)
+		}
+		results = append(results, res)
+	}
+
+	if apply {
+		migrations.Lock()
+		migrations.at[surveyID] = time.Now()
+		migrations.Unlock()
+	}
+
+	b := &bytes.Buffer{}
+	if apply {
+		fmt.Fprintf(b, "<h3>Migration of %v %v</h3>\n", html.EscapeString(surveyID), html.EscapeString(waveID))
+	} else {
+		fmt.Fprintf(b, "<h3>Migration of %v %v - dry run</h3>\n", html.EscapeString(surveyID), html.EscapeString(waveID))
+		fmt.Fprintf(b, "<form method='post' action='%v' id='frm-migrate'>\n", cfg.Pref("/migrate"))
+		fmt.Fprintf(b, "<input type='hidden' name='survey_id' value='%v'>\n", html.EscapeString(surveyID))
+		fmt.Fprintf(b, "<input type='hidden' name='wave_id' value='%v'>\n", html.EscapeString(waveID))
+		fmt.Fprintf(b, "<input type='hidden' name='token' value='%v'>\n", lgn.FormToken(r))
+		fmt.Fprint(b, "<p>Nothing was saved. Files losing responses are only saved, if confirmed below. <button type='submit'>apply migration</button></p>\n</form>\n")
+	}
+	cntrByName, cntrLost, cntrSkipped, cntrErr := 0, 0, 0, 0
+	fmt.Fprint(b, "<table>\n<tr><th>User</th><th>Join</th><th>Responses not carried over</th><th></th></tr>\n")
+	for _, res := range results {
+		mode := "unchanged structure"
+		if res.ByName {
+			mode = "by name"
+			cntrByName++
+		}
+		lost := html.EscapeString(strings.Join(res.Lost, ", "))
+		action := ""
+		switch {
+		case res.Err != nil:
+			mode = "error"
+			lost = html.EscapeString(res.Err.Error())
+			cntrErr++
+		case !apply && len(res.Lost) > 0:
+			// checkboxes belong to the form above - by form attribute
+			action = fmt.Sprintf("<label><input type='checkbox' name='confirm' value='%v' form='frm-migrate'> migrate anyway</label>",
+				html.EscapeString(res.Key))
+		case res.Skipped:
+			action = "skipped - not confirmed"
+			cntrSkipped++
+		case res.Backup != "":
+			action = "backup " + html.EscapeString(res.Backup)
+		}
+		cntrLost += len(res.Lost)
+		fmt.Fprintf(b, "<tr><td>%v</td><td>%v</td><td>%v</td><td>%v</td></tr>\n", html.EscapeString(res.UserID), mode, lost, action)
+	}
+	fmt.Fprint(b, "</table>\n")
+	fmt.Fprintf(b, "<p>%v files; %v joined by name; %v responses not carried over; %v files skipped; %v errors</p>\n",
+		len(results), cntrByName, cntrLost, cntrSkipped, cntrErr)
+
+	adminPage(w, "Migration", b.String())
+}
+
+// migrateBackupPath returns the file name for a copy of response file pth;
+// i.e. responses-backup/fmt/2019-06/10001-2020-05-01-120000.json
+func migrateBackupPath(pth string, t time.Time) string {
+	rel := strings.TrimPrefix(pth, qst.BasePath()+"/")
+	rel = strings.TrimSuffix(rel, ".json")
+	return path.Join(qst.BasePath()+"-backup", fmt.Sprintf("%v-%v.json", rel, t.Format("2006-01-02-150405")))
+}
+
+// migrate1 joins response file pth onto a fresh copy of its template;
+// files with lost responses are only saved if confirmLost;
+// the previous file is backed up before saving
+func migrate1(surveyID, pth string, apply, confirmLost bool) (res migrateResultT) {
+
+	res.Key = pth
+
+	qSplit, err := qst.Load1(pth)
+	if err != nil {
+		res.Err = err
+		return
+	}
+	res.UserID = qSplit.UserID
+
+	pthBase := path.Join(qst.BasePath(), surveyID+".json")
+	if qSplit.Survey.Variant != "" {
+		pthBase = path.Join(qst.BasePath(), surveyID+"-"+qSplit.Survey.Variant+".json")
+	}
+	q, err := qst.Load1(pthBase)
+	if err != nil {
+		res.Err = fmt.Errorf("loading template %v: %v", pthBase, err)
+		return
+	}
+
+	res.ByName, res.Lost = q.Migrate(qSplit)
+	if err := q.Validate(); err != nil {
+		res.Err = fmt.Errorf("validation after migration: %v", err)
+		return
+	}
+	if !apply || !res.ByName {
+		return // unchanged structure - responses remain valid
+	}
+	if len(res.Lost) > 0 && !confirmLost {
+		res.Skipped = true
+		return
+	}
+
+	q2, err := q.Split()
+	if err != nil {
+		res.Err = fmt.Errorf("splitting after migration: %v", err)
+		return
+	}
+
+	bts, err := store.Get().Read(pth)
+	if err != nil {
+		res.Err = fmt.Errorf("reading for backup: %v", err)
+		return
+	}
+	pthBackup := migrateBackupPath(pth, time.Now())
+	if err := store.Get().Write(pthBackup, bts); err != nil {
+		res.Err = fmt.Errorf("writing backup %v: %v", pthBackup, err)
+		return
+	}
+	res.Backup = pthBackup
+
+	res.Err = q2.Save1(pth)
+	return
+}
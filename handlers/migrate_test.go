@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/generators/pat"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/store"
+)
+
+func TestMigrate1(t *testing.T) {
+
+	cfg.LoadExample()
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+
+	tpl, err := pat.Create(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tpl.Save1Unconditionally(qst.TemplatePath("pat")); err != nil {
+		t.Fatal(err)
+	}
+
+	// response files of a previous template version - with an additional input
+	dir := path.Join(qst.BasePath(), "pat", "2020-05")
+	save := func(userID, response string) string {
+		t.Helper()
+		q, err := tpl.Split()
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.UserID = userID
+		inp := q.Pages[0].Groups[0].AddInput()
+		inp.Type = "text"
+		inp.Name = "removed_input"
+		inp.Response = response
+		pth := path.Join(dir, userID+".json")
+		if err := q.Save1Unconditionally(pth); err != nil {
+			t.Fatal(err)
+		}
+		return pth
+	}
+	pthLost := save("10001", "some answer")
+	pthNoLoss := save("10002", "")
+
+	// dry run
+	res := migrate1("pat", pthLost, false, false)
+	if res.Err != nil || !res.ByName || len(res.Lost) != 1 || res.Lost[0] != "removed_input=some answer" {
+		t.Errorf("dry run: got %+v", res)
+	}
+
+	// losing responses requires confirmation
+	res = migrate1("pat", pthLost, true, false)
+	if res.Err != nil || !res.Skipped || res.Backup != "" {
+		t.Errorf("unconfirmed: got %+v", res)
+	}
+	if q, err := qst.Load1(pthLost); err != nil || q.ByName("removed_input") == nil {
+		t.Errorf("unconfirmed: file must remain unchanged - %v", err)
+	}
+
+	for _, pth := range []string{pthNoLoss, pthLost} {
+		before, err := store.Get().Read(pth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = migrate1("pat", pth, true, pth == pthLost) // no confirmation needed without loss
+		if res.Err != nil || res.Skipped || !strings.HasPrefix(res.Backup, "responses-backup/pat/2020-05/") {
+			t.Errorf("%v: got %+v", pth, res)
+			continue
+		}
+		backup, err := store.Get().Read(res.Backup)
+		if err != nil || string(backup) != string(before) {
+			t.Errorf("%v: backup %v differs from previous file - %v", pth, res.Backup, err)
+		}
+		if q, err := qst.Load1(pth); err != nil || q.ByName("removed_input") != nil {
+			t.Errorf("%v: want migrated file - %v", pth, err)
+		}
+	}
+}
+
+func TestMigrateBackupPath(t *testing.T) {
+	tm := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	got := migrateBackupPath("responses/fmt/2019-06/10001.json", tm)
+	if want := "responses-backup/fmt/2019-06/10001-2020-05-01-120000.json"; got != want {
+		t.Errorf("want %v - got %v", want, got)
+	}
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/generators"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/tpl"
+)
+
+// previewParams are passed from the preview page on to its frame
+var previewParams = []string{"name", "lang", "page", "mobile", "user", "p", "attrs"}
+
+// previewQuestionnaire loads template name for the preview;
+// participant data is faked from URL parameters:
+// user - a numeric user ID - for composite funcs;
+// p - a login profile - see cfg.Profiles;
+// attrs - key=value lines - overriding the profile;
+// mobile - recorded into q.Mobile as MainH would - see mobileOf().
+// Nothing is stored - neither in the session nor as response file.
+func previewQuestionnaire(r *http.Request) (*qst.QuestionnaireT, error) {
+
+	name, err := templateName(r)
+	if err != nil {
+		return nil, err
+	}
+	q, err := qst.Load1(qst.TemplatePath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	lc := r.Form.Get("lang")
+	if lc == "" && len(q.LangCodes) > 0 {
+		lc = q.LangCodes[0]
+	}
+	if err := q.SetLangCode(lc); err != nil {
+		return nil, err
+	}
+
+	if u := r.Form.Get("user"); u != "" {
+		if _, err := strconv.Atoi(u); err != nil {
+			return nil, fmt.Errorf("user ID %q must be numeric", u)
+		}
+		q.UserID = u
+	}
+
+	q.Attrs = map[string]string{}
+	if p := r.Form.Get("p"); p != "" {
+		prof, ok := cfg.Get().Profiles[q.Survey.Type+p]
+		if !ok {
+			return nil, fmt.Errorf("profile %v not found in config", q.Survey.Type+p)
+		}
+		for k, v := range prof {
+			q.Attrs[k] = v
+		}
+	}
+	for _, line := range strings.Split(r.Form.Get("attrs"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("attribute %q must be key=value", line)
+		}
+		q.Attrs[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+
+	q.Mobile = mobileOf(q.Mobile, r.Form.Get("mobile"), false)
+
+	if pg := r.Form.Get("page"); pg != "" {
+		q.CurrPage, err = strconv.Atoi(pg)
+		if err != nil || q.CurrPage < 0 || q.CurrPage > len(q.Pages)-1 {
+			return nil, fmt.Errorf("page %q out of %v pages", pg, len(q.Pages))
+		}
+	}
+
+	return q, nil
+}
+
+// previewURL returns the preview URL with the current parameters - and overrides
+func previewURL(r *http.Request, overrides ...string) string {
+	vals := url.Values{}
+	for _, key := range previewParams {
+		if v := r.Form.Get(key); v != "" {
+			vals.Set(key, v)
+		}
+	}
+	for i := 0; i+1 < len(overrides); i += 2 {
+		vals.Set(overrides[i], overrides[i+1])
+	}
+	return cfg.Pref("/preview") + "?" + vals.Encode()
+}
+
+// previewFrame renders the current page as the participant sees it -
+// with the CSS of the survey; submitting is suppressed,
+// since MainH would create a response file for the admin login.
+func previewFrame(w http.ResponseWriter, r *http.Request, q *qst.QuestionnaireT) {
+
+	if err := q.ComputeDynamicContent(q.CurrPage); err != nil {
+		helper(w, r, err, "Computing dynamic content failed.")
+		return
+	}
+
+	mp := map[string]interface{}{
+		"LangCode":  q.LangCode,
+		"Site":      q.Survey.Type,
+		"CSSSite":   cfg.Get().CSSVarsSite[q.Survey.Type],
+		"HTMLTitle": fmt.Sprintf("Preview %v - page %v", q.Survey.Type, q.CurrPage),
+		"LogoTitle": q.Survey.TemplateLogoText(q.LangCode),
+		"Q":         q,
+		"Content":   "",
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	w1 := &strings.Builder{}
+	tpl.Exec(w1, r, mp, "quest.html")
+	fmt.Fprint(w1, `
+<script>
+	document.forms.frmMain.addEventListener("submit", function (e) { e.preventDefault(); });
+	document.forms.frmMain.submit = function () { console.log("preview - not submitted"); };
+</script>
+`)
+	mp["Content"] = w1.String()
+
+	tpl.Exec(w, r, mp, "layout.html")
+}
+
+// PreviewH shows any page of a questionnaire template
+// in any language, for mobile or desktop, with fake user ID and attributes;
+// to exercise dyn-textblock and dyn-composite inputs.
+// Unlike logging in as participant, no response file is created.
+//
+// URL parameter frame renders the page itself - embedded as iframe.
+// Mobile mode merely narrows the iframe to 375px - so that the mobile CSS media queries apply;
+// the user agent remains that of the admin's browser.
+func PreviewH(w http.ResponseWriter, r *http.Request) {
+
+	if err := r.ParseForm(); err != nil {
+		helper(w, r, err)
+		return
+	}
+
+	b := &bytes.Buffer{}
+	fmt.Fprint(b, "<h3>Preview questionnaire template</h3>\n")
+
+	var q *qst.QuestionnaireT
+	var err error
+	if r.Form.Get("name") != "" {
+		q, err = previewQuestionnaire(r)
+		if err != nil {
+			if r.Form.Get("frame") != "" {
+				helper(w, r, err)
+				return
+			}
+			fmt.Fprintf(b, "<p style='color:red'>%v</p>\n", html.EscapeString(err.Error()))
+		}
+	}
+
+	if q != nil && r.Form.Get("frame") != "" {
+		previewFrame(w, r, q)
+		return
+	}
+
+	names := []string{}
+	for key := range generators.Get() {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(b, "<form method='get' action='%v'>\n", cfg.Pref("/preview"))
+	fmt.Fprintf(b, "Template <input name='name' list='templates' value='%v'>\n", html.EscapeString(r.Form.Get("name")))
+	fmt.Fprint(b, "<datalist id='templates'>\n")
+	for _, name := range names {
+		fmt.Fprintf(b, "<option value='%v'>\n", name)
+	}
+	fmt.Fprint(b, "</datalist>\n")
+	if q != nil {
+		fmt.Fprint(b, "Language <select name='lang'>\n")
+		for _, lc := range q.LangCodes {
+			sel := ""
+			if lc == q.LangCode {
+				sel = " selected"
+			}
+			fmt.Fprintf(b, "<option value='%v'%v>%v</option>\n", lc, sel, lc)
+		}
+		fmt.Fprint(b, "</select>\n")
+		fmt.Fprintf(b, "<input type='hidden' name='page' value='%v'>\n", q.CurrPage)
+	}
+	mobile := r.Form.Get("mobile") != ""
+	checked := ""
+	if mobile {
+		checked = " checked"
+	}
+	fmt.Fprintf(b, "<label><input type='checkbox' name='mobile' value='1'%v> mobile</label><br>\n", checked)
+	fmt.Fprintf(b, "User ID <input name='user' size='8' value='%v'>\n", html.EscapeString(r.Form.Get("user")))
+	fmt.Fprintf(b, "Profile <input name='p' size='4' value='%v'> - see config profiles<br>\n", html.EscapeString(r.Form.Get("p")))
+	fmt.Fprintf(b, "Attributes<br>\n<textarea name='attrs' rows='4' cols='40' placeholder='country=DE&#10;euro-member=true'>%v</textarea><br>\n",
+		html.EscapeString(r.Form.Get("attrs")))
+	fmt.Fprint(b, "<button type='submit'>show</button>\n</form>\n")
+
+	if q == nil {
+		adminPage(w, "Preview", b.String())
+		return
+	}
+
+	// jump-to-page menu
+	fmt.Fprint(b, "<p>\n")
+	for i, p := range q.Pages {
+		lbl := p.Label.TrSilent(q.LangCode)
+		if lbl == "" {
+			lbl = p.Section.TrSilent(q.LangCode)
+		}
+		lbl = strings.ReplaceAll(lbl, "&shy;", "")
+		lbl = fmt.Sprintf("%v %v", i, html.EscapeString(lbl))
+		if !q.IsPageVisible(i) {
+			lbl += " (hidden)"
+		}
+		if p.NoNavigation {
+			lbl += " (no nav)"
+		}
+		if i == q.CurrPage {
+			fmt.Fprintf(b, "<b>%v</b><br>\n", lbl)
+			continue
+		}
+		fmt.Fprintf(b, "<a href='%v'>%v</a><br>\n", html.EscapeString(previewURL(r, "page", strconv.Itoa(i))), lbl)
+	}
+	fmt.Fprint(b, "</p>\n")
+
+	width := "100%"
+	if mobile {
+		width = "375px"
+	}
+	fmt.Fprintf(b, "<iframe src='%v' style='width:%v; height:900px; border:1px solid #888'></iframe>\n",
+		html.EscapeString(previewURL(r, "frame", "1")), width)
+
+	adminPage(w, "Preview "+q.Survey.Type, b.String())
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/generators/pat"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/sessx"
+	"github.com/zew/go-questionnaire/store"
+)
+
+// savePreviewTemplate publishes the pat template to the mem:// bucket
+func savePreviewTemplate(t *testing.T) *qst.QuestionnaireT {
+	t.Helper()
+	q, err := pat.Create(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Save1Unconditionally(qst.TemplatePath("pat")); err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestPreviewQuestionnaire(t *testing.T) {
+
+	cfg.LoadExample()
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+	tplQ := savePreviewTemplate(t)
+
+	form := func(vals url.Values) *http.Request {
+		r := httptest.NewRequest("GET", "/preview?"+vals.Encode(), nil)
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	q, err := previewQuestionnaire(form(url.Values{
+		"name": {"pat"}, "lang": {"de"}, "page": {"1"}, "mobile": {"1"},
+		"user": {"10001"}, "attrs": {" country = DE \n\nrole=x=y"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.LangCode != "de" || q.CurrPage != 1 || q.UserID != "10001" || q.Mobile != 2 {
+		t.Errorf("got lang %v, page %v, user %v, mobile %v", q.LangCode, q.CurrPage, q.UserID, q.Mobile)
+	}
+	if q.Attrs["country"] != "DE" || q.Attrs["role"] != "x=y" {
+		t.Errorf("attributes: got %v", q.Attrs)
+	}
+
+	q, err = previewQuestionnaire(form(url.Values{"name": {"pat"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.LangCode != tplQ.LangCodes[0] || q.CurrPage != 0 || q.Mobile != 1 {
+		t.Errorf("defaults: got lang %v, page %v, mobile %v", q.LangCode, q.CurrPage, q.Mobile)
+	}
+
+	for _, vals := range []url.Values{
+		{"name": {"../pat"}},
+		{"name": {"nosuch"}},
+		{"name": {"pat"}, "user": {"abc"}},
+		{"name": {"pat"}, "page": {"99"}},
+		{"name": {"pat"}, "p": {"99"}},
+		{"name": {"pat"}, "attrs": {"country"}},
+		{"name": {"pat"}, "lang": {"xx"}},
+	} {
+		if _, err := previewQuestionnaire(form(vals)); err == nil {
+			t.Errorf("%v: want error", vals.Encode())
+		}
+	}
+}
+
+func TestPreviewH(t *testing.T) {
+
+	cfg.LoadExample()
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+	q := savePreviewTemplate(t)
+
+	inSession := false
+	srv, client := sessionServer(t, func(w http.ResponseWriter, r *http.Request) {
+		PreviewH(w, r)
+		_, inSession = sessx.New(w, r).EffectiveObj("questionnaire")
+	})
+	defer srv.Close()
+
+	vals := url.Values{"name": {"pat"}, "page": {"1"}, "mobile": {"1"}, "user": {"10001"}}
+	resp, err := client.Get(srv.URL + "?" + vals.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	bts, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(bts)
+	for _, want := range []string{"<iframe src=", "frame=1", "width:375px", "<b>1 "} {
+		if !strings.Contains(page, want) {
+			t.Errorf("preview page lacks %q\n%v", want, page)
+		}
+	}
+
+	if inSession {
+		t.Errorf("preview must not put the questionnaire into the session")
+	}
+	entries, err := store.Get().List(path.Join(qst.BasePath(), q.Survey.Type, q.Survey.WaveID()))
+	if err == nil && len(entries) > 0 {
+		t.Errorf("preview must not create response files - got %v", entries)
+	}
+	if _, err := store.Get().Read(path.Join(qst.BasePath(), q.Survey.Type, q.Survey.WaveID(), "10001.json")); !cloudio.IsNotExist(err) {
+		t.Errorf("preview must not create a response file for user 10001 - got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/zew/go-questionnaire/export"
+	"github.com/zew/go-questionnaire/sessx"
+	"github.com/zew/go-questionnaire/tpl"
+)
+
+// StatusH shows the fieldwork status of a survey wave -
+// one row per response file - with totals;
+// parameters survey_id, wave_id, format=html|csv.
+func StatusH(w http.ResponseWriter, r *http.Request) {
+
+	sess := sessx.New(w, r)
+
+	surveyID, ok := sess.ReqParam("survey_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a survey_id parameter.")
+		return
+	}
+	waveID, ok := sess.ReqParam("wave_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a wave_id parameter.")
+		return
+	}
+	format, _ := sess.ReqParam("format")
+	if format == "" {
+		format = "html"
+	}
+
+	st, err := export.WaveStatus(surveyID, waveID)
+	if err != nil {
+		helper(w, r, err, "Could not read the response files.")
+		return
+	}
+	header, rows := st.Matrix()
+
+	switch format {
+	case "csv":
+		baseName := fmt.Sprintf("status-%v-%v", surveyID, waveID)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", baseName+".csv"))
+		err = export.WriteCSV(w, header, rows)
+		if err != nil {
+			log.Printf("status %v as csv failed: %v", baseName, err)
+		}
+		return
+	case "html":
+	default:
+		helper(w, r, nil, fmt.Sprintf("Unknown format %q - use html or csv.", format))
+		return
+	}
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "<h3>Fieldwork status %v %v</h3>\n", html.EscapeString(surveyID), html.EscapeString(waveID))
+
+	fmt.Fprintf(b, "<p>Started: %v &nbsp; Finished: %v &nbsp; Unfinished: %v</p>\n",
+		st.Started, st.Finished, st.Started-st.Finished)
+	if len(st.Abandoned) > 0 {
+		fmt.Fprint(b, "<table>\n<tr><th>Unfinished on page</th><th>Participants</th></tr>\n")
+		for _, pg := range st.AbandonedPages() {
+			fmt.Fprintf(b, "<tr><td>%v</td><td>%v</td></tr>\n", pg+1, st.Abandoned[pg])
+		}
+		fmt.Fprint(b, "</table>\n")
+	}
+
+	vals := url.Values{}
+	vals.Set("survey_id", surveyID)
+	vals.Set("wave_id", waveID)
+	vals.Set("format", "csv")
+	fmt.Fprintf(b, "<p><a href='?%v'>Download as CSV</a></p>\n", html.EscapeString(vals.Encode()))
+
+	fmt.Fprint(b, "<table>\n<tr>")
+	for _, col := range header {
+		fmt.Fprintf(b, "<th>%v</th>", html.EscapeString(col))
+	}
+	fmt.Fprint(b, "</tr>\n")
+	for _, row := range rows {
+		fmt.Fprint(b, "<tr>")
+		for _, cell := range row {
+			fmt.Fprintf(b, "<td>%v</td>", html.EscapeString(cell))
+		}
+		fmt.Fprint(b, "</tr>\n")
+	}
+	fmt.Fprint(b, "</table>\n")
+
+	tpl.ExecContent(w, r, b.String(), "layout.html")
+}
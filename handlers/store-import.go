@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"path"
+
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/sessx"
+	"github.com/zew/go-questionnaire/store"
+	"github.com/zew/go-questionnaire/tpl"
+)
+
+// StoreImportH copies the response files of a survey wave
+// from the bucket into the database; see cfg database_url;
+// responses already in the database are kept.
+func StoreImportH(w http.ResponseWriter, r *http.Request) {
+
+	sess := sessx.New(w, r)
+
+	surveyID, ok := sess.ReqParam("survey_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a survey_id parameter.")
+		return
+	}
+	waveID, ok := sess.ReqParam("wave_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a wave_id parameter.")
+		return
+	}
+
+	if _, isFiles := store.Get().(store.FilesT); isFiles {
+		helper(w, r, nil, "No database configured; responses are stored in the bucket already.")
+		return
+	}
+
+	dir := path.Join(qst.BasePath(), surveyID, waveID)
+	imported, err := store.Import(store.Get(), store.FilesT{}, dir)
+	if err != nil {
+		helper(w, r, err, fmt.Sprintf("Import of %v stopped after %v responses.", dir, imported))
+		return
+	}
+
+	msg := fmt.Sprintf("<p>%v responses of %v imported into the database.</p>\n", imported, html.EscapeString(dir))
+	tpl.ExecContent(w, r, msg, "layout.html")
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/generators"
+	"github.com/zew/go-questionnaire/generators/dsl"
+	"github.com/zew/go-questionnaire/lgn"
+	"github.com/zew/go-questionnaire/qst"
+)
+
+// isTemplateJSON distinguishes a questionnaire template
+// from a questionnaire definition in JSON format - see package dsl
+func isTemplateJSON(fn string, src []byte) bool {
+	if strings.ToLower(path.Ext(fn)) != ".json" {
+		return false
+	}
+	keys := map[string]json.RawMessage{}
+	if err := json.Unmarshal(src, &keys); err != nil {
+		return false
+	}
+	_, ok := keys["survey"]
+	return ok
+}
+
+// uploadedTemplate creates the questionnaire template of surveyType
+// from an uploaded template or definition in file fn;
+// the template passes the same checks as generated ones;
+// definitions keep the wave - year, month, deadline, params - of the current template.
+func uploadedTemplate(surveyType, fn string, src []byte) (q *qst.QuestionnaireT, isDefinition bool, err error) {
+
+	if !qst.Mustaz09Underscore(surveyType) {
+		return nil, false, fmt.Errorf("survey type %q must consist of lower case letters, digits, underscore", surveyType)
+	}
+
+	if isTemplateJSON(fn, src) {
+		q = &qst.QuestionnaireT{}
+		if err := json.Unmarshal(src, q); err != nil {
+			return nil, false, err
+		}
+		if q.Survey.Type != surveyType {
+			return nil, false, fmt.Errorf("template is of survey type %q - not %q", q.Survey.Type, surveyType)
+		}
+		q.Hyphenize()
+		q.ComputeMaxGroups()
+		if err := q.TranslationCompleteness(); err != nil {
+			return nil, false, err
+		}
+		if err := q.Validate(); err != nil {
+			return nil, false, err
+		}
+		return q, false, nil
+	}
+
+	d, err := dsl.Parse(fn, src)
+	if err != nil {
+		return nil, true, err
+	}
+	q, err = d.Questionnaire(surveyType, nil)
+	if err != nil {
+		return nil, true, err
+	}
+	if prev, err := qst.Load1(qst.TemplatePath(surveyType)); err == nil {
+		org, name := q.Survey.Org, q.Survey.Name
+		q.Survey = prev.Survey
+		q.Survey.Org, q.Survey.Name = org, name
+	}
+	return q, true, nil
+}
+
+// templateSource returns file name and contents
+// of an uploaded file - or of the hidden fields of the preview form
+func templateSource(r *http.Request) (fn string, src []byte, err error) {
+	f, hdr, err := r.FormFile("file")
+	if err == nil {
+		defer f.Close()
+		src, err = ioutil.ReadAll(f)
+		return hdr.Filename, src, err
+	}
+	if err != http.ErrMissingFile && err != http.ErrNotMultipart {
+		return "", nil, err
+	}
+	fn = r.PostForm.Get("fn")
+	src = []byte(r.PostForm.Get("src"))
+	if fn == "" || len(src) == 0 {
+		return "", nil, fmt.Errorf("no file uploaded")
+	}
+	return fn, src, nil
+}
+
+// publishTemplate saves q as template - and as new version;
+// an uploaded definition replaces the definition in dsl.Dir
+func publishTemplate(b *bytes.Buffer, q *qst.QuestionnaireT, isDefinition bool, fn string, src []byte) error {
+
+	surveyType := q.Survey.Type
+	hash, err := q.PublishTemplate(surveyType, "uploaded "+path.Base(fn))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(b, "<p>Template %v published as version %v; <a href='%v?name=%v'>all versions</a></p>\n",
+		qst.TemplatePath(surveyType), qst.ShortHash(hash), cfg.Pref("/templates/versions"), surveyType)
+
+	if isDefinition {
+		ext := strings.ToLower(path.Ext(fn))
+		for _, e := range dsl.Extensions {
+			if e == ext {
+				continue
+			}
+			if err := cloudio.Delete(path.Join(dsl.Dir, surveyType+e)); err != nil {
+				return err
+			}
+		}
+		pth := path.Join(dsl.Dir, surveyType+ext)
+		if err := cloudio.WriteFile(pth, bytes.NewReader(src), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "<p>Definition saved to %v</p>\n", pth)
+	}
+
+	generators.CreateSurveyCSS(b, surveyType)
+	return nil
+}
+
+// TemplatesUploadH takes an uploaded questionnaire template - JSON -
+// or a questionnaire definition - YAML or JSON - see package dsl;
+// it is validated and every page is shown as preview;
+// only the preview form publishes it to responses/[type].json.
+func TemplatesUploadH(w http.ResponseWriter, r *http.Request) {
+
+	b := &bytes.Buffer{}
+
+	if r.Method == "POST" {
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			if err := r.ParseMultipartForm(cfg.Get().MaxPostSize); err != nil {
+				helper(w, r, err, "Invalid upload.")
+				return
+			}
+		}
+		if err := checkFormToken(r); err != nil {
+			helper(w, r, err, "Invalid request.")
+			return
+		}
+		fn, src, err := templateSource(r)
+		if err != nil {
+			helper(w, r, err)
+			return
+		}
+		surveyType := strings.TrimSpace(r.PostForm.Get("type"))
+		if surveyType == "" {
+			surveyType = strings.TrimSuffix(path.Base(fn), path.Ext(fn))
+		}
+		q, isDefinition, err := uploadedTemplate(surveyType, fn, src)
+		if err != nil {
+			helper(w, r, err, fmt.Sprintf("%v is invalid - nothing was published.", fn))
+			return
+		}
+
+		if r.PostForm.Get("action") == "publish" {
+			if err := publishTemplate(b, q, isDefinition, fn, src); err != nil {
+				helper(w, r, err, fmt.Sprintf("Publishing %v failed.", surveyType))
+				return
+			}
+			fmt.Fprintf(b, "<p><a href='%v'>Upload another</a></p>\n", cfg.Pref("/templates-upload"))
+			adminPage(w, "Upload questionnaire template", b.String())
+			return
+		}
+
+		// preview
+		fmt.Fprintf(b, "<h3>Preview of %v - %v pages</h3>\n", html.EscapeString(surveyType), len(q.Pages))
+		fmt.Fprintf(b, "<form method='post' action='%v'>\n", cfg.Pref("/templates-upload"))
+		fmt.Fprintf(b, "<input type='hidden' name='token' value='%v'>\n", lgn.FormToken(r))
+		fmt.Fprint(b, "<input type='hidden' name='action' value='publish'>\n")
+		fmt.Fprintf(b, "<input type='hidden' name='type' value='%v'>\n", html.EscapeString(surveyType))
+		fmt.Fprintf(b, "<input type='hidden' name='fn' value='%v'>\n", html.EscapeString(fn))
+		fmt.Fprintf(b, "<textarea name='src' style='display:none'>%v</textarea>\n", html.EscapeString(string(src)))
+		fmt.Fprintf(b, "<button type='submit'>publish to %v</button>\n</form>\n", qst.TemplatePath(surveyType))
+
+		if len(q.LangCodes) > 0 {
+			q.LangCode = q.LangCodes[0]
+		}
+		q.Attrs = map[string]string{}
+		for i := range q.Pages {
+			q.CurrPage = i
+			fmt.Fprintf(b, "<hr>\n<h4>Page %v</h4>\n", i)
+			if err := q.ComputeDynamicContent(i); err != nil {
+				fmt.Fprintf(b, "<p>%v</p>\n", html.EscapeString(err.Error()))
+			}
+			pageHTML, err := q.PageHTML(i)
+			if err != nil {
+				fmt.Fprintf(b, "<p>%v</p>\n", html.EscapeString(err.Error()))
+				continue
+			}
+			fmt.Fprint(b, pageHTML)
+		}
+		adminPage(w, "Upload questionnaire template", b.String())
+		return
+	}
+
+	fmt.Fprint(b, "<h3>Upload questionnaire template</h3>\n")
+	fmt.Fprint(b, "<p>A questionnaire template <code>[type].json</code> - or a definition <code>[type].yaml</code>;<br>\n")
+	fmt.Fprint(b, "it is validated and previewed before publishing.</p>\n")
+	fmt.Fprintf(b, "<form method='post' action='%v' enctype='multipart/form-data'>\n", cfg.Pref("/templates-upload"))
+	fmt.Fprintf(b, "<input type='hidden' name='token' value='%v'>\n", lgn.FormToken(r))
+	fmt.Fprint(b, "<label>Survey type <input name='type' size='20' placeholder='from file name'></label><br>\n")
+	fmt.Fprint(b, "<input type='file' name='file' accept='.json,.yaml,.yml'><br>\n")
+	fmt.Fprint(b, "<button type='submit'>validate and preview</button>\n</form>\n")
+
+	adminPage(w, "Upload questionnaire template", b.String())
+}
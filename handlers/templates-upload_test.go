@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/generators/pat"
+	"github.com/zew/go-questionnaire/lgn"
+	"github.com/zew/go-questionnaire/qst"
+	"github.com/zew/go-questionnaire/store"
+)
+
+func TestIsTemplateJSON(t *testing.T) {
+	tests := []struct {
+		fn   string
+		src  string
+		want bool
+	}{
+		{"pat.json", `{"survey": {"type": "pat"}, "pages": []}`, true},
+		{"PAT.JSON", `{"survey": {}}`, true},
+		{"pat.json", `{"lang_codes": ["en"], "pages": []}`, false}, // definition
+		{"pat.yaml", `{"survey": {}}`, false},
+		{"pat.json", `[1, 2]`, false},
+		{"pat.json", `{"survey": `, false},
+	}
+	for _, tc := range tests {
+		if got := isTemplateJSON(tc.fn, []byte(tc.src)); got != tc.want {
+			t.Errorf("isTemplateJSON(%v, %v) = %v - want %v", tc.fn, tc.src, got, tc.want)
+		}
+	}
+}
+
+// multipartRequest posts a file upload - plus form values vals
+func multipartRequest(t *testing.T, target, fn string, src []byte, vals url.Values) *http.Request {
+	t.Helper()
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for key := range vals {
+		if err := mw.WriteField(key, vals.Get(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fw, err := mw.CreateFormFile("file", fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(src); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("POST", target, body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestTemplateSource(t *testing.T) {
+
+	// file upload
+	r := multipartRequest(t, "/templates-upload", "demo.yaml", []byte("lang_codes: [en]\n"), nil)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	fn, src, err := templateSource(r)
+	if err != nil || fn != "demo.yaml" || string(src) != "lang_codes: [en]\n" {
+		t.Errorf("upload: got %v %q %v", fn, src, err)
+	}
+
+	// hidden fields of the preview form
+	vals := url.Values{"fn": {"demo.json"}, "src": {`{"lang_codes": ["en"]}`}}
+	r = httptest.NewRequest("POST", "/templates-upload", strings.NewReader(vals.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := r.ParseForm(); err != nil {
+		t.Fatal(err)
+	}
+	fn, src, err = templateSource(r)
+	if err != nil || fn != "demo.json" || string(src) != `{"lang_codes": ["en"]}` {
+		t.Errorf("preview form: got %v %q %v", fn, src, err)
+	}
+
+	// neither
+	r = httptest.NewRequest("POST", "/templates-upload", strings.NewReader("fn=demo.json"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := r.ParseForm(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := templateSource(r); err == nil || err.Error() != "no file uploaded" {
+		t.Errorf("want no file uploaded - got %v", err)
+	}
+}
+
+func TestUploadedTemplate(t *testing.T) {
+
+	cfg.LoadExample()
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+
+	q, err := pat.Create(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bts, err := json.Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q2, isDefinition, err := uploadedTemplate("pat", "pat.json", bts)
+	if err != nil || isDefinition || q2.Survey.Type != "pat" {
+		t.Fatalf("valid template: got %v %v", isDefinition, err)
+	}
+	if _, _, err := uploadedTemplate("fmt", "pat.json", bts); err == nil || !strings.Contains(err.Error(), `not "fmt"`) {
+		t.Errorf("survey type mismatch: got %v", err)
+	}
+	if _, _, err := uploadedTemplate("../pat", "pat.json", bts); err == nil {
+		t.Errorf("invalid survey type must be rejected")
+	}
+	noLangs := `{"survey": {"type": "demo"}, "pages": [{"groups": []}]}`
+	if _, _, err := uploadedTemplate("demo", "demo.json", []byte(noLangs)); err == nil || !strings.Contains(err.Error(), "at least one language") {
+		t.Errorf("template without lang codes: got %v", err)
+	}
+
+	// malformed composite func names are errors - no panics
+	for _, dynFunc := range []string{"Foo", "PoliticalFoundations__x__0", "PoliticalFoundations__0", "PoliticalFoundations__99__0"} {
+		q, err := pat.Create(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, p := range q.Pages {
+			for _, gr := range p.Groups {
+				if len(gr.Inputs) > 0 && gr.Inputs[0].Type == "dyn-composite" && !found {
+					gr.Inputs[0].DynamicFunc = dynFunc
+					found = true
+				}
+			}
+		}
+		if !found {
+			t.Fatal("pat template lacks composite inputs")
+		}
+		bts, err := json.Marshal(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := uploadedTemplate("pat", "pat.json", bts); err == nil {
+			t.Errorf("%v: want validation error", dynFunc)
+		}
+	}
+
+	// a definition keeps the wave of the current template
+	prev := &qst.QuestionnaireT{}
+	prev.Survey = qst.NewSurvey("demo")
+	prev.Survey.Year, prev.Survey.Month = 2019, time.March
+	prev.Survey.Params = []qst.ParamT{{Name: "rate", Val: "3.2%"}}
+	if err := prev.Save1Unconditionally(qst.TemplatePath("demo")); err != nil {
+		t.Fatal(err)
+	}
+	def := `{"lang_codes": ["en"], "pages": [{"groups": [{"columns": 2, "inputs": [
+		{"name": "q1", "type": "text", "max_chars": 10, "col_span_label": 1, "col_span_control": 1, "label": {"en": "Name"}}
+	]}]}]}`
+	q3, isDefinition, err := uploadedTemplate("demo", "demo.json", []byte(def))
+	if err != nil || !isDefinition {
+		t.Fatalf("definition: got %v %v", isDefinition, err)
+	}
+	if q3.Survey.Year != 2019 || q3.Survey.Month != time.March || len(q3.Survey.Params) != 1 || q3.ByName("q1") == nil {
+		t.Errorf("definition must keep the wave of the current template - got %+v", q3.Survey)
+	}
+}
+
+func TestTemplatesUploadH(t *testing.T) {
+
+	cfg.LoadExample()
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+	if err := store.Get().Write(lgn.LgnsPath, []byte(`{"salt": "salt-handlers-test", "logins": []}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := lgn.LoadFromStore(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, client := sessionServer(t, TemplatesUploadH)
+	defer srv.Close()
+
+	read := func(resp *http.Response, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bts, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(bts)
+	}
+
+	page := read(client.Get(srv.URL))
+	def := "lang_codes: [en]\npages:\n  - groups:\n    - columns: 2\n      inputs:\n" +
+		"        - {name: q1, type: text, max_chars: 10, col_span_label: 1, col_span_control: 1, label: {en: Name}}\n"
+	r := multipartRequest(t, srv.URL, "demo.yaml", []byte(def), url.Values{"token": {formToken(t, page)}})
+	r.RequestURI = ""
+	page = read(client.Do(r))
+	if !strings.Contains(page, "<h3>Preview of demo - 1 pages</h3>") || !strings.Contains(page, "name='action' value='publish'") {
+		t.Fatalf("preview expected\n%v", page)
+	}
+	if _, err := store.Get().Read(qst.TemplatePath("demo")); !cloudio.IsNotExist(err) {
+		t.Errorf("preview must not publish - got %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/lgn"
+	"github.com/zew/go-questionnaire/qst"
+)
+
+var hexHash = regexp.MustCompile(`^[0-9a-f]{64}$`) // see qst.md5Str() - which is sha256
+
+// templateName checks URL parameter name - it becomes part of file paths
+func templateName(r *http.Request) (string, error) {
+	name := r.Form.Get("name")
+	if name == "" || strings.ContainsAny(name, `/\.`) {
+		return "", fmt.Errorf("invalid template name %q", name)
+	}
+	return name, nil
+}
+
+// TemplateVersionsH lists the published versions of template name - newest first;
+// POST parameter rollback makes the version with this hash the current template again;
+// see qst.PublishTemplate() and qst.RollbackTemplate().
+func TemplateVersionsH(w http.ResponseWriter, r *http.Request) {
+
+	if err := r.ParseForm(); err != nil {
+		helper(w, r, err)
+		return
+	}
+	name, err := templateName(r)
+	if err != nil {
+		helper(w, r, err)
+		return
+	}
+
+	b := &bytes.Buffer{}
+	self := cfg.Pref("/templates/versions")
+
+	if r.Method == "POST" {
+		if err := checkFormToken(r); err != nil {
+			helper(w, r, err, "Invalid request.")
+			return
+		}
+		hash := r.PostForm.Get("rollback")
+		if !hexHash.MatchString(hash) {
+			helper(w, r, fmt.Errorf("invalid version %q", hash))
+			return
+		}
+		if err := qst.RollbackTemplate(name, hash); err != nil {
+			helper(w, r, err, fmt.Sprintf("Rollback of %v to %v failed.", name, qst.ShortHash(hash)))
+			return
+		}
+		fmt.Fprintf(b, "<p>%v rolled back to version %v</p>\n", html.EscapeString(name), qst.ShortHash(hash))
+	}
+
+	vs, err := qst.TemplateVersions(name)
+	if err != nil {
+		helper(w, r, err)
+		return
+	}
+	cur, err := qst.CurrentTemplateHash(name)
+	if err != nil {
+		helper(w, r, err, fmt.Sprintf("Could not read current template %v.", name))
+		return
+	}
+
+	token := lgn.FormToken(r)
+	fmt.Fprintf(b, "<h3>Versions of %v</h3>\n", html.EscapeString(name))
+	if len(vs) == 0 {
+		fmt.Fprint(b, "<p>No versions yet - they are recorded on publishing.</p>\n")
+	}
+	fmt.Fprint(b, "<table>\n<tr><th>Version</th><th>Published</th><th>Note</th><th></th><th></th></tr>\n")
+	for i := len(vs) - 1; i > -1; i-- {
+		v := vs[i]
+		fmt.Fprintf(b, "<tr><td><code>%v</code></td><td>%v</td><td>%v</td>",
+			v.Short(), v.Published.Format("2006-01-02 15:04"), html.EscapeString(v.Note))
+		if i > 0 {
+			fmt.Fprintf(b, "<td><a href='%v?name=%v&a=%v&b=%v'>diff to previous</a></td>",
+				cfg.Pref("/templates/diff"), url.QueryEscape(name), vs[i-1].Hash, v.Hash)
+		} else {
+			fmt.Fprint(b, "<td></td>")
+		}
+		if v.Hash == cur {
+			fmt.Fprint(b, "<td><b>current</b></td>")
+		} else {
+			fmt.Fprintf(b, "<td><a href='%v?name=%v&a=%v'>diff to current</a> ",
+				cfg.Pref("/templates/diff"), url.QueryEscape(name), v.Hash)
+			fmt.Fprintf(b, `<form method="post" action="%v?name=%v" style="display:inline">`, self, url.QueryEscape(name))
+			fmt.Fprintf(b, `<input type="hidden" name="token" value="%v">`, token)
+			fmt.Fprintf(b, `<input type="hidden" name="rollback" value="%v">`, v.Hash)
+			fmt.Fprint(b, `<button type="submit">rollback</button></form></td>`)
+		}
+		fmt.Fprint(b, "</tr>\n")
+	}
+	fmt.Fprint(b, "</table>\n")
+
+	adminPage(w, "Template versions", b.String())
+}
+
+// TemplateDiffH shows the differences between versions a and b of template name;
+// empty b means the current template.
+func TemplateDiffH(w http.ResponseWriter, r *http.Request) {
+
+	if err := r.ParseForm(); err != nil {
+		helper(w, r, err)
+		return
+	}
+	name, err := templateName(r)
+	if err != nil {
+		helper(w, r, err)
+		return
+	}
+
+	load := func(hash string) (*qst.QuestionnaireT, string, error) {
+		if hash == "" {
+			q, err := qst.Load1(qst.TemplatePath(name))
+			return q, "current", err
+		}
+		if !hexHash.MatchString(hash) {
+			return nil, "", fmt.Errorf("invalid version %q", hash)
+		}
+		q, err := qst.LoadTemplateVersion(name, hash)
+		return q, qst.ShortHash(hash), err
+	}
+	qa, la, err := load(r.Form.Get("a"))
+	if err != nil {
+		helper(w, r, err)
+		return
+	}
+	qb, lb, err := load(r.Form.Get("b"))
+	if err != nil {
+		helper(w, r, err)
+		return
+	}
+
+	diffs := qst.DiffTemplates(qa, qb)
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "<h3>%v: %v => %v</h3>\n", html.EscapeString(name), la, lb)
+	fmt.Fprintf(b, "<p><a href='%v?name=%v'>all versions</a></p>\n", cfg.Pref("/templates/versions"), url.QueryEscape(name))
+	if len(diffs) == 0 {
+		fmt.Fprint(b, "<p>No differences in labels or inputs.</p>\n")
+	}
+	fmt.Fprint(b, "<table>\n<tr><th>Page</th><th></th><th>Element</th><th>Field</th><th>Old</th><th>New</th></tr>\n")
+	for _, d := range diffs {
+		fmt.Fprintf(b, "<tr><td>%v</td><td>%v</td><td>%v</td><td>%v</td><td>%v</td><td>%v</td></tr>\n",
+			d.Page, d.Kind, html.EscapeString(d.Element), d.Field,
+			html.EscapeString(d.Old), html.EscapeString(d.New),
+		)
+	}
+	fmt.Fprint(b, "</table>\n")
+
+	adminPage(w, "Template diff", b.String())
+}
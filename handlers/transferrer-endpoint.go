@@ -9,18 +9,52 @@ import (
 	"path"
 	"time"
 
-	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/export"
 	"github.com/zew/go-questionnaire/qst"
 
 	"github.com/zew/go-questionnaire/lgn"
 	"github.com/zew/go-questionnaire/sessx"
+	"github.com/zew/go-questionnaire/store"
 )
 
-// TransferrerEndpointH responds with finished questionnaires from the filesystem.
-func TransferrerEndpointH(w http.ResponseWriter, r *http.Request) {
+// changedSince returns the keys of the entries modified at or after since,
+// the new cursor - the most recent modification time - and the number of skipped entries.
+// Entries modified exactly at since are returned again;
+// file stores with coarse timestamps could otherwise miss concurrent writes.
+func changedSince(entries []store.EntryT, since time.Time) (keys []string, cursor time.Time, unchanged int) {
+	cursor = since
+	keys = []string{}
+	for i, info := range entries {
+		if info.ModTime.After(cursor) {
+			cursor = info.ModTime
+		}
+		if info.ModTime.Before(since) {
+			unchanged++
+			continue
+		}
+		if i < 10 || i%50 == 0 {
+			log.Printf("iter %3v: Name: %v, Size: %v", i, info.Key, info.Size)
+		}
+		keys = append(keys, info.Key)
+	}
+	if !since.IsZero() {
+		log.Printf("%v questionnaires unchanged since %v; new cursor %v", unchanged, since, cursor)
+	}
+	return keys, cursor, unchanged
+}
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.Header().Set("Content-Encoding", "gzip")
+// TransferrerEndpointH responds with finished questionnaires from the store.
+//
+// Parameter since restricts the response to questionnaires
+// modified at or after since (RFC3339 with nanoseconds).
+// Response header X-Cursor contains the most recent modification time
+// of all questionnaires of the wave; to be sent as since on the next request.
+//
+// Parameter format=ndjson yields one compact questionnaire per line;
+// errors are reported in-band as error records;
+// a summary record concludes the response; see export.NDJSONWriterT.
+// Otherwise the response is a JSON array of questionnaires.
+func TransferrerEndpointH(w http.ResponseWriter, r *http.Request) {
 
 	// w.Header().Set("Content-Length", fmt.Sprintf("%v", len(byts)))  // do not set, if response is gzipped !
 	// w.Write(byts)
@@ -45,6 +79,7 @@ func TransferrerEndpointH(w http.ResponseWriter, r *http.Request) {
 	}
 
 	fetchAll, _ := sess.ReqParam("fetch_all")
+	format, _ := sess.ReqParam("format")
 
 	surveyID, ok := sess.ReqParam("survey_id")
 	if !ok {
@@ -56,30 +91,71 @@ func TransferrerEndpointH(w http.ResponseWriter, r *http.Request) {
 		helper(w, r, nil, "You need to specify a wave_id parameter.")
 		return
 	}
+	since := time.Time{}
+	if sinceStr, _ := sess.ReqParam("since"); sinceStr != "" {
+		since, err = time.Parse(time.RFC3339Nano, sinceStr)
+		if err != nil {
+			helper(w, r, err, "Parameter since must be formatted as RFC3339.")
+			return
+		}
+	}
 	pth := path.Join(qst.BasePath(), surveyID, waveID)
 
 	log.Printf("transferrer-endpoint-reading-directory %v", pth)
-	infos, err := cloudio.ReadDir(pth)
-	// infos, err := dir.Readdir(-1)
+	entries, err := store.Get().List(pth)
 	if err != nil {
 		helper(w, r, err, "Could not read directory.")
 		return
 	}
 
-	cntr := 0
-	btsCtr := 0
+	keys, cursor, cntrUnchanged := changedSince(entries, since)
+
+	// the response is under way; helper() calls beyond this point corrupt it;
+	// ndjson mode reports errors in-band instead
+	w.Header().Set("X-Cursor", cursor.Format(time.RFC3339Nano))
+	w.Header().Set("Content-Encoding", "gzip")
 	gz := gzip.NewWriter(w)
 	defer gz.Close()
 
-	gz.Write([]byte("["))
-	for i, info := range *infos {
-		if !info.IsDir {
-			if i < 10 || i%50 == 0 {
-				log.Printf("iter %3v: Name: %v, Size: %v", i, info.Key, info.Size)
+	if format == "ndjson" {
+		w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+		nw := export.NewNDJSONWriter(gz)
+		nw.Summary.Unchanged = cntrUnchanged
+		nw.Summary.Cursor = cursor
+		for i, key := range keys {
+			q, err := qst.Load1(key)
+			if err != nil {
+				err = nw.Error(key, fmt.Errorf("iter %3v: loading failed: %v", i, err))
+			} else if err = q.Validate(); err != nil {
+				err = nw.Error(key, fmt.Errorf("iter %3v: questionnaire validation caused error: %v", i, err))
+			} else if q.ClosingTime.IsZero() && fetchAll == "" && time.Now().Before(q.Survey.Deadline) {
+				log.Printf("%v unfinished and not yet past global deadline => skipping", key)
+				nw.Summary.SkippedUnfinished++
+			} else {
+				err = nw.Questionnaire(key, q)
 			}
+			if err != nil {
+				log.Printf("iter %3v: writing to response failed - aborting: %v", i, err)
+				return
+			}
+		}
+		if err := nw.Close(); err != nil {
+			log.Printf("writing summary failed: %v", err)
+			return
 		}
+		log.Printf("%+v", nw.Summary)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	cntr := 0
+	btsCtr := 0
+
+	gz.Write([]byte("["))
+	for i, key := range keys {
 		// pth := path.Join(qst.BasePath(), surveyID, waveID, info.Key)
-		pth := info.Key
+		pth := key
 		// var q = &qst.QuestionnaireT{}
 		q, err := qst.Load1(pth)
 		if err != nil {
@@ -91,9 +167,9 @@ func TransferrerEndpointH(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if q.ClosingTime.IsZero() && fetchAll == "" {
-			log.Printf("%v unfinished yet; %v", info.Key, q.ClosingTime)
+			log.Printf("%v unfinished yet; %v", key, q.ClosingTime)
 			if time.Now().Before(q.Survey.Deadline) {
-				log.Printf("%v not yet past global deadline => skipping", info.Key)
+				log.Printf("%v not yet past global deadline => skipping", key)
 				continue
 			}
 		}
@@ -122,3 +198,29 @@ func TransferrerEndpointH(w http.ResponseWriter, r *http.Request) {
 	log.Printf("%v questionnaires to http response written - gzipped %v", cntr, sz1)
 
 }
+
+// TransferrerWavesH responds with the wave IDs of a survey as JSON array;
+// so that the transferrer can fetch all waves of a survey.
+func TransferrerWavesH(w http.ResponseWriter, r *http.Request) {
+
+	sess := sessx.New(w, r)
+	surveyID, ok := sess.ReqParam("survey_id")
+	if !ok {
+		helper(w, r, nil, "You need to specify a survey_id parameter.")
+		return
+	}
+
+	pth := path.Join(qst.BasePath(), surveyID)
+	waveIDs, err := store.Get().Subdirs(pth)
+	if err != nil {
+		helper(w, r, err, "Could not read directory.")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err = json.NewEncoder(w).Encode(waveIDs)
+	if err != nil {
+		log.Printf("transferrer-waves: %v", err)
+	}
+
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zew/go-questionnaire/store"
+)
+
+func TestChangedSince(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "transferrer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := store.OpenSQLite(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	pth := "responses/fmt/2020-05"
+	write := func(user string) {
+		time.Sleep(2 * time.Millisecond) // distinct modification times
+		if err := s.Write(pth+"/"+user+".json", []byte(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sync := func(since time.Time) ([]string, time.Time, int) {
+		entries, err := s.List(pth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys, cursor, unchanged := changedSince(entries, since)
+		// round trip through the X-Cursor header
+		cursor, err = time.Parse(time.RFC3339Nano, cursor.Format(time.RFC3339Nano))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return keys, cursor, unchanged
+	}
+
+	write("10001")
+	write("10002")
+
+	keys, cursor, unchanged := sync(time.Time{})
+	if want := []string{pth + "/10001.json", pth + "/10002.json"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("first sync: want %v - got %v", want, keys)
+	}
+	if unchanged != 0 {
+		t.Errorf("first sync: want 0 unchanged - got %v", unchanged)
+	}
+
+	write("10003")
+	write("10001")
+
+	// 10002 was modified at the cursor exactly - and is returned again
+	keys, cursor2, unchanged := sync(cursor)
+	if want := []string{pth + "/10001.json", pth + "/10002.json", pth + "/10003.json"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("second sync: want %v - got %v", want, keys)
+	}
+	if unchanged != 0 {
+		t.Errorf("second sync: want 0 unchanged - got %v", unchanged)
+	}
+	if !cursor2.After(cursor) {
+		t.Errorf("second sync: cursor %v must advance beyond %v", cursor2, cursor)
+	}
+
+	// nothing written since - only the entry at the cursor is returned
+	keys, cursor3, unchanged := sync(cursor2)
+	if want := []string{pth + "/10001.json"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("third sync: want %v - got %v", want, keys)
+	}
+	if unchanged != 2 {
+		t.Errorf("third sync: want 2 unchanged - got %v", unchanged)
+	}
+	if !cursor3.Equal(cursor2) {
+		t.Errorf("third sync: cursor must stay at %v - got %v", cursor2, cursor3)
+	}
+
+	write("10004")
+	keys, _, unchanged = sync(cursor3)
+	if want := []string{pth + "/10001.json", pth + "/10004.json"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("fourth sync: want %v - got %v", want, keys)
+	}
+	if unchanged != 2 {
+		t.Errorf("fourth sync: want 2 unchanged - got %v", unchanged)
+	}
+}
@@ -0,0 +1,351 @@
+package lgn
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/store"
+)
+
+var errLoginDisabled = fmt.Errorf("Login disabled")
+
+// modifyMtx serializes modifications by the admin functions below
+var modifyMtx sync.Mutex
+
+// copyLogins returns a deep copy - to be modified
+// without affecting concurrent readers of Get()
+func (l *loginsT) copyLogins() *loginsT {
+	cp := &loginsT{Salt: l.Salt, Logins: make([]LoginT, len(l.Logins))}
+	for i, lg := range l.Logins {
+		lg.Roles = copyMap(lg.Roles)
+		lg.Attrs = copyMap(lg.Attrs)
+		cp.Logins[i] = lg
+	}
+	return cp
+}
+
+func copyMap(m map[string]string) map[string]string {
+	cp := make(map[string]string, len(m))
+	for k, v := range m {
+		cp[k] = v
+	}
+	return cp
+}
+
+func (l *loginsT) index(user string) int {
+	user = strings.ToLower(strings.TrimSpace(user))
+	for i := range l.Logins {
+		if strings.ToLower(l.Logins[i].User) == user {
+			return i
+		}
+	}
+	return -1
+}
+
+// BackupPath returns the file name for a backup of the logins file
+func BackupPath(t time.Time) string {
+	dir, fn := path.Split(LgnsPath)
+	fn = strings.TrimSuffix(fn, ".json")
+	return path.Join(dir, "logins-backup", fmt.Sprintf("%v-%v.json", fn, t.Format("2006-01-02-150405")))
+}
+
+// modify applies fn to a copy of the logins;
+// the previous logins file is backed up;
+// the copy is saved and then replaces the logins in one go.
+func modify(fn func(ls *loginsT) error) error {
+	return update(true, fn)
+}
+
+// updateAttempts limits the repetitions of update()
+// if the logins file was changed by another app instance
+const updateAttempts = 5
+
+// update is modify() - backup optional;
+// password changes by the users themselves are not backed up
+func update(keepBackup bool, fn func(ls *loginsT) error) (err error) {
+	modifyMtx.Lock()
+	defer modifyMtx.Unlock()
+
+	for i := 0; i < updateAttempts; i++ {
+		err = update1(keepBackup, fn)
+		if err != cloudio.ErrConflict {
+			return err
+		}
+		log.Printf("%v was changed meanwhile - repeating update", LgnsPath)
+	}
+	return err
+}
+
+// update1 applies fn to the logins file as stored -
+// not to Get(), since other app instances might have changed the file;
+// writing is rejected with ErrConflict, if the file changed after reading.
+func update1(keepBackup bool, fn func(ls *loginsT) error) error {
+
+	prev, err := store.Get().Read(LgnsPath)
+	if err != nil && !cloudio.IsNotExist(err) {
+		return err
+	}
+	var cp *loginsT
+	if prev == nil {
+		if Get() == nil {
+			return fmt.Errorf("no logins loaded")
+		}
+		cp = Get().copyLogins()
+	} else {
+		cp, err = parse(bytes.NewReader(prev))
+		if err != nil {
+			return fmt.Errorf("reading %v: %v", LgnsPath, err)
+		}
+	}
+
+	if err := fn(cp); err != nil {
+		return err
+	}
+	if keepBackup && prev != nil {
+		pth := BackupPath(time.Now())
+		if err := store.Get().Write(pth, prev); err != nil {
+			return fmt.Errorf("logins backup failed: %v", err)
+		}
+		log.Printf("logins backed up to %v", pth)
+	}
+
+	bts, err := cp.marshal()
+	if err != nil {
+		return err
+	}
+	err = store.Get().WriteIf(LgnsPath, bts, func(current []byte) error {
+		if !bytes.Equal(current, prev) {
+			return cloudio.ErrConflict
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	lgns = cp
+	return nil
+}
+
+// derive sets the fields not saved to JSON - as Load() does
+func (l *LoginT) derive() {
+	els := strings.Split(l.Email, "@")
+	l.Group = els[0]
+	if len(els) > 1 {
+		l.Group = els[1]
+	}
+	l.Provider = "JSON"
+	if l.Roles == nil {
+		l.Roles = map[string]string{}
+	}
+	if l.Attrs == nil {
+		l.Attrs = map[string]string{}
+	}
+}
+
+// Sorted returns a copy of all logins ordered by user name
+func Sorted() []LoginT {
+	cp := Get().copyLogins()
+	sort.Slice(cp.Logins, func(i, j int) bool {
+		return strings.ToLower(cp.Logins[i].User) < strings.ToLower(cp.Logins[j].User)
+	})
+	return cp.Logins
+}
+
+// Find returns a copy of login user - disabled logins included
+func Find(user string) (LoginT, bool) {
+	cp := Get().copyLogins()
+	idx := cp.index(user)
+	if idx < 0 {
+		return LoginT{}, false
+	}
+	return cp.Logins[idx], true
+}
+
+// CreateLogin adds a new login with a generated init password;
+// only the returned login contains the init password in cleartext.
+func CreateLogin(l LoginT) (LoginT, error) {
+	l.User = strings.TrimSpace(l.User)
+	if l.User == "" {
+		return l, fmt.Errorf("user name is empty")
+	}
+	err := modify(func(ls *loginsT) error {
+		if ls.index(l.User) > -1 {
+			return fmt.Errorf("login %v exists already", l.User)
+		}
+		l.derive()
+		l.PassHash = ""
+		l.PassMd5 = ""
+		l.PassInitial = ""
+		l.IsInitPassword = true
+		pw := l.SetInitPW()
+		ls.Logins = append(ls.Logins, l)
+		l.PassInitial = pw // returned only
+		return nil
+	})
+	return l, err
+}
+
+// UpdateLogin changes email, roles, attributes and the disabled flag of an existing login;
+// passwords remain unchanged.
+func UpdateLogin(l LoginT) error {
+	return modify(func(ls *loginsT) error {
+		idx := ls.index(l.User)
+		if idx < 0 {
+			return errLoginNotFound
+		}
+		prev := ls.Logins[idx]
+		prev.Email = l.Email
+		prev.Roles = l.Roles
+		prev.Attrs = l.Attrs
+		prev.Disabled = l.Disabled
+		prev.derive()
+		ls.Logins[idx] = prev
+		return nil
+	})
+}
+
+// ResetInitPW replaces the password of a login by a new init password;
+// the user has to change it on next login; the init password is returned -
+// only its hash is stored.
+func ResetInitPW(user string) (passInitial string, err error) {
+	err = modify(func(ls *loginsT) error {
+		idx := ls.index(user)
+		if idx < 0 {
+			return errLoginNotFound
+		}
+		ls.Logins[idx].IsInitPassword = true
+		ls.Logins[idx].PassHash = ""
+		ls.Logins[idx].PassMd5 = ""
+		ls.Logins[idx].PassInitial = ""
+		passInitial = ls.Logins[idx].SetInitPW()
+		return nil
+	})
+	return
+}
+
+// SetDisabled disables or enables a login;
+// disabled logins cannot log in with password; see FindAndCheck()
+func SetDisabled(user string, disabled bool) error {
+	return modify(func(ls *loginsT) error {
+		idx := ls.index(user)
+		if idx < 0 {
+			return errLoginNotFound
+		}
+		ls.Logins[idx].Disabled = disabled
+		return nil
+	})
+}
+
+// DeleteLogin removes a login
+func DeleteLogin(user string) error {
+	return modify(func(ls *loginsT) error {
+		idx := ls.index(user)
+		if idx < 0 {
+			return errLoginNotFound
+		}
+		ls.Logins = append(ls.Logins[:idx], ls.Logins[idx+1:]...)
+		return nil
+	})
+}
+
+// ImportCSV creates participant logins from CSV with header row;
+// columns user, email, survey_id, wave_id, profile - further columns become attributes;
+// profile is a key into cfg profiles - prefixed by survey_id as in LoginByHash();
+// separator is semicolon or comma.
+// Existing users are skipped. Any invalid row aborts the entire import.
+func ImportCSV(r io.Reader) (created []LoginT, skipped []string, err error) {
+
+	bts, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	content := string(bts)
+	rdr := csv.NewReader(strings.NewReader(content))
+	firstLine := strings.SplitN(content, "\n", 2)[0]
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		rdr.Comma = ';'
+	}
+	rdr.TrimLeadingSpace = true
+	rows, err := rdr.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) < 2 {
+		return nil, nil, fmt.Errorf("need a header row and at least one participant")
+	}
+
+	header := rows[0]
+	cols := map[string]int{}
+	for i, col := range header {
+		cols[strings.ToLower(strings.TrimSpace(col))] = i
+	}
+	if _, ok := cols["user"]; !ok {
+		return nil, nil, fmt.Errorf("column user is missing in header %v", header)
+	}
+	val := func(row []string, col string) string {
+		if i, ok := cols[col]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	err = modify(func(ls *loginsT) error {
+		for lineNo, row := range rows[1:] {
+			l := LoginT{
+				User:  val(row, "user"),
+				Email: val(row, "email"),
+				Attrs: map[string]string{},
+				Roles: map[string]string{},
+			}
+			if l.User == "" {
+				return fmt.Errorf("row %v: user is empty", lineNo+2)
+			}
+			if ls.index(l.User) > -1 {
+				skipped = append(skipped, l.User)
+				continue
+			}
+			for i, col := range header {
+				col = strings.TrimSpace(col)
+				switch strings.ToLower(col) {
+				case "user", "email", "profile":
+				default:
+					if i < len(row) && strings.TrimSpace(row[i]) != "" {
+						l.Attrs[col] = strings.TrimSpace(row[i])
+					}
+				}
+			}
+			if p := val(row, "profile"); p != "" {
+				profileKey := l.Attrs["survey_id"] + p
+				prof, ok := cfg.Get().Profiles[profileKey]
+				if !ok {
+					return fmt.Errorf("row %v: profile %v not found in config", lineNo+2, profileKey)
+				}
+				for pk, pv := range prof {
+					l.Attrs[pk] = pv
+				}
+			}
+			l.derive()
+			l.IsInitPassword = true
+			pw := l.SetInitPW()
+			ls.Logins = append(ls.Logins, l)
+			l.PassInitial = pw // returned only
+			created = append(created, l)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return created, skipped, nil
+}
@@ -0,0 +1,339 @@
+package lgn
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/sessx"
+	"github.com/zew/go-questionnaire/store"
+)
+
+// failingStoreT fails writing the logins file - backups succeed
+type failingStoreT struct {
+	store.FilesT
+}
+
+func (failingStoreT) Write(key string, bts []byte) error {
+	if key == LgnsPath {
+		return errors.New("disk full")
+	}
+	return store.FilesT{}.Write(key, bts)
+}
+
+func (failingStoreT) WriteIf(key string, bts []byte, check func(current []byte) error) error {
+	if key == LgnsPath {
+		return errors.New("disk full")
+	}
+	return store.FilesT{}.WriteIf(key, bts, check)
+}
+
+// racingStoreT simulates another app instance,
+// changing the logins file between reading and writing - once
+type racingStoreT struct {
+	store.FilesT
+	raced *bool
+}
+
+func (s racingStoreT) WriteIf(key string, bts []byte, check func(current []byte) error) error {
+	if key == LgnsPath && !*s.raced {
+		*s.raced = true
+		other, err := parse(strings.NewReader(mustRead(key)))
+		if err != nil {
+			return err
+		}
+		other.Logins = append(other.Logins, LoginT{User: "other-instance"})
+		if err := save(other); err != nil {
+			return err
+		}
+	}
+	return store.FilesT{}.WriteIf(key, bts, check)
+}
+
+func mustRead(key string) string {
+	bts, err := store.FilesT{}.Read(key)
+	if err != nil {
+		panic(err)
+	}
+	return string(bts)
+}
+
+func adminTestSetup(t *testing.T) {
+	t.Helper()
+	cfg.LoadExample()
+	cloudio.SetStorageURL("mem://")
+	lgns = &loginsT{Salt: "salt-admin-test", Logins: []LoginT{
+		{User: "anna", Email: "anna@zew.de", Roles: map[string]string{"admin": "yes"}, Attrs: map[string]string{"survey_id": "fmt"}},
+	}}
+	if err := Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestModifyRollback(t *testing.T) {
+
+	adminTestSetup(t)
+	defer cloudio.SetStorageURL("")
+
+	store.Set(failingStoreT{})
+	defer store.Set(store.FilesT{})
+
+	prev := Get()
+	tests := []struct {
+		desc string
+		fn   func() error
+	}{
+		{"update", func() error {
+			return UpdateLogin(LoginT{User: "anna", Email: "new@zew.de", Attrs: map[string]string{"survey_id": "pat"}})
+		}},
+		{"create", func() error {
+			_, err := CreateLogin(LoginT{User: "bert"})
+			return err
+		}},
+		{"disable", func() error { return SetDisabled("anna", true) }},
+		{"delete", func() error { return DeleteLogin("anna") }},
+		{"reset", func() error {
+			_, err := ResetInitPW("anna")
+			return err
+		}},
+		{"own password - no backup", func() error {
+			return update(false, func(ls *loginsT) error {
+				ls.Logins[0].PassHash = "changed"
+				return nil
+			})
+		}},
+	}
+	for _, tc := range tests {
+		err := tc.fn()
+		if err == nil || !strings.Contains(err.Error(), "disk full") {
+			t.Errorf("%v: want save error - got %v", tc.desc, err)
+		}
+		if Get() != prev {
+			t.Errorf("%v: logins must not be replaced after failed save", tc.desc)
+		}
+	}
+	want := LoginT{User: "anna", Email: "anna@zew.de", Roles: map[string]string{"admin": "yes"}, Attrs: map[string]string{"survey_id": "fmt"}}
+	if got := Get().Logins; len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("logins changed despite failed saves: %+v", got)
+	}
+
+	// a failing fn leaves everything untouched as well - and saves nothing
+	store.Set(store.FilesT{})
+	err := modify(func(ls *loginsT) error {
+		ls.Logins = nil
+		return errors.New("invalid")
+	})
+	if err == nil || Get() != prev {
+		t.Errorf("failing fn: want error and unchanged logins - got %v", err)
+	}
+
+	// succeeding modification
+	if err := SetDisabled("anna", true); err != nil {
+		t.Fatal(err)
+	}
+	if l, ok := Find("anna"); !ok || !l.Disabled {
+		t.Errorf("anna must be disabled - got %+v", l)
+	}
+	if prev.Logins[0].Disabled {
+		t.Errorf("previous logins must not be modified in place")
+	}
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+
+	adminTestSetup(t)
+	defer cloudio.SetStorageURL("")
+
+	// another instance saved a login - unknown to this instance
+	other := Get().copyLogins()
+	other.Logins = append(other.Logins, LoginT{User: "bert"})
+	if err := save(other); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Find("bert"); ok {
+		t.Fatal("bert must not be loaded yet")
+	}
+	if err := SetDisabled("anna", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Find("bert"); !ok {
+		t.Errorf("login of the other instance must be kept")
+	}
+
+	// the other instance writes between reading and writing
+	raced := false
+	store.Set(racingStoreT{raced: &raced})
+	defer store.Set(store.FilesT{})
+	if err := SetDisabled("anna", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadFromStore(); err != nil {
+		t.Fatal(err)
+	}
+	anna, _ := Find("anna")
+	_, okBert := Find("bert")
+	_, okOther := Find("other-instance")
+	if !raced || anna.Disabled || !okBert || !okOther {
+		t.Errorf("want update repeated on the changed file - got raced %v, anna disabled %v, bert %v, other %v",
+			raced, anna.Disabled, okBert, okOther)
+	}
+}
+
+func TestFromSessionDisabled(t *testing.T) {
+
+	adminTestSetup(t)
+	defer cloudio.SetStorageURL("")
+
+	if err := LoadFromStore(); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Get().FindAndCheck("anna")
+	if err != nil || l.Provider != "JSON" {
+		t.Fatalf("%+v %v", l, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		sessx.New(w, r).PutObject("login", l)
+	})
+	mux.HandleFunc("/check", func(w http.ResponseWriter, r *http.Request) {
+		_, loggedIn, err := LoggedInCheck(w, r)
+		fmt.Fprintf(w, "%v %v", loggedIn, err)
+	})
+	srv := httptest.NewServer(sessx.Mgr().LoadAndSave(mux))
+	defer srv.Close()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Jar: jar}
+	get := func(pth string) string {
+		t.Helper()
+		resp, err := client.Get(srv.URL + pth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		bts, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(bts)
+	}
+
+	get("/login")
+	if got := get("/check"); got != "true <nil>" {
+		t.Errorf("want logged in - got %v", got)
+	}
+	if err := SetDisabled("anna", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := get("/check"); got != "false <nil>" {
+		t.Errorf("disabled: want logged out - got %v", got)
+	}
+	if err := SetDisabled("anna", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteLogin("anna"); err != nil {
+		t.Fatal(err)
+	}
+	if got := get("/check"); got != "false <nil>" {
+		t.Errorf("deleted: want logged out - got %v", got)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+
+	adminTestSetup(t)
+	defer cloudio.SetStorageURL("")
+
+	tests := []struct {
+		desc    string
+		csv     string
+		created []string
+		skipped []string
+		err     string
+	}{
+		{
+			"semicolon, profile, extra column, existing user",
+			"user;email;survey_id;wave_id;profile;country\n" +
+				"p1; p1@x.de ;fmt;2020-05;1;DE\n" +
+				"ANNA;anna@zew.de;fmt;2020-05;;\n" +
+				"p2;;fmt;2020-05;;\n",
+			[]string{"p1", "p2"},
+			[]string{"ANNA"},
+			"",
+		},
+		{
+			"comma, quoted",
+			"User,Email,survey_id\n\"p3\",\"p3@x.de\",\"fmt\"\n",
+			[]string{"p3"},
+			nil,
+			"",
+		},
+		{"no user column", "email;survey_id\nx@y.de;fmt\n", nil, nil, "column user is missing"},
+		{"header only", "user;email\n", nil, nil, "at least one participant"},
+		{"empty user", "user;email\np4;a@b.de\n;c@d.de\n", nil, nil, "row 3: user is empty"},
+		{"unknown profile", "user;survey_id;profile\np5;fmt;9\n", nil, nil, "row 2: profile fmt9 not found"},
+		{"ragged rows", "user;email\np6;a@b.de;extra\n", nil, nil, "wrong number of fields"},
+	}
+
+	for _, tc := range tests {
+		before := len(Get().Logins)
+		created, skipped, err := ImportCSV(strings.NewReader(tc.csv))
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%v: want error %q - got %v", tc.desc, tc.err, err)
+			}
+			if len(Get().Logins) != before {
+				t.Errorf("%v: an invalid row must abort the entire import", tc.desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tc.desc, err)
+			continue
+		}
+		users := []string{}
+		for _, l := range created {
+			users = append(users, l.User)
+			if l.PassInitial == "" || !l.IsInitPassword {
+				t.Errorf("%v: %v must be returned with init password", tc.desc, l.User)
+			}
+			stored, ok := Find(l.User)
+			if !ok || stored.PassInitial != "" || stored.PassHash == "" {
+				t.Errorf("%v: %v must be stored with hash only - got %+v", tc.desc, l.User, stored)
+			}
+		}
+		if !reflect.DeepEqual(users, tc.created) || !reflect.DeepEqual(skipped, tc.skipped) {
+			t.Errorf("%v: want created %v, skipped %v - got %v, %v", tc.desc, tc.created, tc.skipped, users, skipped)
+		}
+	}
+
+	p1, _ := Find("p1")
+	wantAttrs := map[string]string{
+		"survey_id": "fmt", "wave_id": "2020-05", "country": "DE",
+		"lang_code": "de", "main_refinance_rate_ecb": "3.5", // profile fmt1
+	}
+	if p1.Email != "p1@x.de" || !reflect.DeepEqual(p1.Attrs, wantAttrs) {
+		t.Errorf("p1: got %v %v", p1.Email, p1.Attrs)
+	}
+	if p3, _ := Find("p3"); p3.Email != "p3@x.de" || p3.Attrs["survey_id"] != "fmt" {
+		t.Errorf("p3: got %+v", p3)
+	}
+
+	// persisted
+	if err := LoadFromStore(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Find("p2"); !ok || len(Get().Logins) != 4 {
+		t.Errorf("imported logins must be saved - got %v logins", len(Get().Logins))
+	}
+}
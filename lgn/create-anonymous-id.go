@@ -100,7 +100,7 @@ func createAnonymousID(w http.ResponseWriter, r *http.Request, outerHTML bool) {
 
 	_, ok := r.PostForm["token"]
 	if ok {
-		err = ValidateFormToken(r.PostForm.Get("token"))
+		err = ValidateFormToken(r, r.PostForm.Get("token"))
 		if err != nil {
 			fmt.Fprintf(w, "Invalid request token: %v", err)
 		}
@@ -204,7 +204,7 @@ func createAnonymousID(w http.ResponseWriter, r *http.Request, outerHTML bool) {
 		msg,
 		err,
 		// structform.HTML(frm),
-		FormToken(),
+		FormToken(r),
 		frm.MotherFirstNameFirstLetter,
 		frm.FatherFirstNameFirstLetter,
 		frm.BirthdayDaySecondDigit,
@@ -1,66 +1,89 @@
 package lgn
 
 import (
+	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"io"
-	"log"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/sessx"
 )
 
-var tokenSaltNotWorking = GeneratePassword(22) // not interoperational between multiple instances of go-qu--estionnaire, transferrer, generator
+// sessionKeyFormToken is the session key for a random secret,
+// to which all form tokens of the session are bound
+const sessionKeyFormToken = "form-token-key"
 
-// set timezone to a constant - this is important for client-server talks, e.g. appengine frankfurt runs on different zone
-var fixedLocation = time.FixedZone("UTC_-2", -2*60*60)
+// clockSkew tolerates tokens issued slightly in the future
+// by another instance of the app
+const clockSkew = 2 * time.Minute
 
-// tok rounds time to hours
-// and computes a hash from it
-func tok(hoursOffset int) string {
-	hasher := sha256.New()
-	_, err := io.WriteString(hasher, lgns.Salt)
-	if err != nil {
-		log.Printf("Error writing salt to hasher: %v", err)
+// formTokenKey returns the secret of the session - created on first use;
+// logging in or out clears the session - and thus invalidates previous tokens
+func formTokenKey(r *http.Request) string {
+	sess := sessx.New(nil, r)
+	key := sess.GetString(r.Context(), sessionKeyFormToken)
+	if key == "" {
+		key = GeneratePassword(32)
+		sess.Put(r.Context(), sessionKeyFormToken, key)
+	}
+	return key
+}
+
+// formTokenMAC signs session key and issue time with the logins salt;
+// the salt is shared by all instances of the app
+func formTokenMAC(sessKey string, issued int64) []byte {
+	mac := hmac.New(sha256.New, []byte(lgns.Salt))
+	fmt.Fprintf(mac, "%v|%v", sessKey, issued)
+	return mac.Sum(nil)
+}
+
+// formToken returns [issue time as unix seconds].[hex HMAC]
+func formToken(sessKey string, issued time.Time) string {
+	unix := issued.Unix()
+	return fmt.Sprintf("%v.%v", unix, hex.EncodeToString(formTokenMAC(sessKey, unix)))
+}
+
+// checkFormToken verifies signature and age of token
+func checkFormToken(sessKey, token string, now time.Time, maxAge time.Duration) error {
+	els := strings.SplitN(token, ".", 2)
+	if len(els) != 2 {
+		return fmt.Errorf("form token malformed")
 	}
-	t := time.Now().In(fixedLocation)
-	if hoursOffset != 0 {
-		t = t.Add(time.Duration(hoursOffset) * time.Hour)
+	unix, err := strconv.ParseInt(els[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("form token issue time malformed")
 	}
-	// log.Printf("token time: %v", t.Format("02.01.2006 15"))
-	_, err = io.WriteString(hasher, t.Format("02.01.2006 15"))
+	sig, err := hex.DecodeString(els[1])
 	if err != nil {
-		log.Printf("Error writing date-hour to hasher: %v", err)
+		return fmt.Errorf("form token signature malformed")
+	}
+	if !hmac.Equal(sig, formTokenMAC(sessKey, unix)) {
+		return fmt.Errorf("form token was not issued for this session. \nPlease re-login")
+	}
+	issued := time.Unix(unix, 0)
+	if issued.After(now.Add(clockSkew)) {
+		return fmt.Errorf("form token issued in the future")
 	}
-	hash := hasher.Sum(nil)
-	return hex.EncodeToString(hash)
+	if now.Sub(issued) > maxAge {
+		return fmt.Errorf("form token was not issued within the last %v. \nPlease re-login", maxAge)
+	}
+	return nil
 }
 
-// FormToken returns a form token.
-// User independent.
-// Should we add the user name into the hashed base?
-func FormToken() string {
-	return tok(0)
+// FormToken returns a form token - bound to the session of r
+// and valid for cfg FormTimeout hours.
+func FormToken(r *http.Request) string {
+	return formToken(formTokenKey(r), time.Now())
 }
 
-// ValidateFormToken checks tokens
-// against current hour - back to n previous hours.
-// Plus one more for bounding glitches / border crossing
-// when the rounding jumps from 12:59 to 13:00.
-// i.e.
-// FormTimeout := 2
-// lower bound := -4
-// => Checking token against current hour, previous hour, second previous hour, third previous hour
-func ValidateFormToken(arg string) error {
-	lowerBound := cfg.Get().FormTimeout*-1 - 1
-	for i := 0; i >= lowerBound; i-- {
-		if arg == tok(i) {
-			return nil
-		}
-	}
-	if arg == tok(1) {
-		return nil
-	}
-	return fmt.Errorf("form token was not issued within the last two hours. \nPlease re-login")
+// ValidateFormToken checks a token from FormToken()
+// against the session of r and its issue time.
+func ValidateFormToken(r *http.Request, token string) error {
+	maxAge := time.Duration(cfg.Get().FormTimeout) * time.Hour
+	return checkFormToken(formTokenKey(r), token, time.Now(), maxAge)
 }
@@ -0,0 +1,39 @@
+package lgn
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckFormToken(t *testing.T) {
+
+	lgns = &loginsT{Salt: "salt"}
+	now := time.Now()
+	maxAge := 2 * time.Hour
+
+	tok := formToken("session-1", now.Add(-time.Hour))
+	if formToken("session-2", now.Add(-time.Hour)) == tok {
+		t.Errorf("tokens of different sessions must differ")
+	}
+
+	tests := []struct {
+		sessKey string
+		token   string
+		ok      bool
+	}{
+		{"session-1", tok, true},
+		{"session-2", tok, false},
+		{"session-1", formToken("session-1", now.Add(-3*time.Hour)), false},
+		{"session-1", formToken("session-1", now.Add(time.Hour)), false},
+		{"session-1", strings.Replace(tok, ".", "0.", 1), false}, // issue time tampered
+		{"session-1", tok[:len(tok)-2], false},
+		{"session-1", "", false},
+	}
+	for i, tc := range tests {
+		err := checkFormToken(tc.sessKey, tc.token, now, maxAge)
+		if (err == nil) != tc.ok {
+			t.Errorf("test %v: %q - got error %v - want ok %v", i, tc.token, err, tc.ok)
+		}
+	}
+}
@@ -18,7 +18,7 @@ func ShufflesToCSV(w http.ResponseWriter, r *http.Request) {
 
 	_, ok := r.PostForm["token"]
 	if ok {
-		err := ValidateFormToken(r.PostForm.Get("token"))
+		err := ValidateFormToken(r, r.PostForm.Get("token"))
 		if err != nil {
 			errMsg += fmt.Sprintf("Invalid request token: %v\n", err)
 		}
@@ -128,7 +128,7 @@ func ShufflesToCSV(w http.ResponseWriter, r *http.Request) {
 	}
 	data := dataT{
 		SelfURL: r.URL.Path,
-		Token:   FormToken(),
+		Token:   FormToken(r),
 		ErrMsg:  errMsg,
 		DL:      fe,
 		List:    list,
@@ -12,7 +12,6 @@ import (
 
 	"github.com/go-playground/form"
 	"github.com/zew/go-questionnaire/cfg"
-	"github.com/zew/go-questionnaire/cloudio"
 	"github.com/zew/go-questionnaire/sessx"
 	"github.com/zew/util"
 )
@@ -86,7 +85,7 @@ func ValidateAndLogin(w http.ResponseWriter, r *http.Request) error {
 
 	_, ok := r.PostForm["token"]
 	if ok {
-		err = ValidateFormToken(r.PostForm.Get("token"))
+		err = ValidateFormToken(r, r.PostForm.Get("token"))
 		if err != nil {
 			return fmt.Errorf("Invalid request token: %v", err)
 		}
@@ -97,7 +96,7 @@ func ValidateAndLogin(w http.ResponseWriter, r *http.Request) error {
 	u := r.PostForm.Get("username")
 	u = html.EscapeString(u)        // XSS prevention
 	p := r.PostForm.Get("password") // unencrypted
-	log.Printf("trying login1 '%v' '%v'  -  %v", u, p, r.URL)
+	log.Printf("trying login1 '%v'  -  %v", u, r.URL)
 
 	l, err := Get().FindAndCheck(u, p)
 	if err != nil {
@@ -143,7 +142,7 @@ func ChangePassword(w http.ResponseWriter, r *http.Request) (string, error) {
 	{
 		_, ok := r.PostForm["token"]
 		if ok {
-			err = ValidateFormToken(r.PostForm.Get("token"))
+			err = ValidateFormToken(r, r.PostForm.Get("token"))
 			if err != nil {
 				return "", fmt.Errorf("Invalid request token: %v", err)
 			}
@@ -186,54 +185,37 @@ func ChangePassword(w http.ResponseWriter, r *http.Request) (string, error) {
 		}
 	}
 
-	log.Printf("Trying password change '%v' '%v' '%v'  -  %v", u, o, n, r.URL)
+	log.Printf("Trying password change '%v'  -  %v", u, r.URL)
 
-	passEncr := ComputeMD5Password(l.User, o, Get().Salt)
-
-	logins := Get().Logins
-	for idx, v := range logins {
-		if v.User == u {
-			log.Printf("Found user %v", u)
-			match := false
-			if v.IsInitPassword && v.PassInitial == o {
-				match = true
-				log.Printf("Matching init pw")
-			} else if v.PassMd5 == passEncr {
-				match = true
-				log.Printf("Matching encr pw")
-			}
-			if match {
-
-				if n != n2 {
-					return "", fmt.Errorf("new passwords did not match")
-				}
-
-				// Change user database (json file)
-				newPassEncr := ComputeMD5Password(l.User, n, Get().Salt)
-				logins[idx].PassInitial = ""
-				logins[idx].IsInitPassword = false
-				logins[idx].PassMd5 = newPassEncr
-				err = cloudio.MarshalWriteFile(Get(), LgnsPath)
-				if err != nil {
-					return "", fmt.Errorf("could not save logins file: %v", err)
-				}
-
-				// Change user in session
-				// (is this necessary?)
-				l.PassInitial = ""
-				l.IsInitPassword = false
-				l.PassMd5 = newPassEncr
-				sess := sessx.New(w, r)
-				sess.PutObject("login", *l)
-
-				// SUCCESS
-				return "Password changed successfully.", nil
-
-			}
+	_, err = Get().FindAndCheck(u, o)
+	if err != nil {
+		if IsFound(err) {
 			return "", fmt.Errorf("Neither init nor encrypted password did match for user %v", u)
 		}
+		return "", fmt.Errorf("old password incorrect (or username not found)")
+	}
+	if n != n2 {
+		return "", fmt.Errorf("new passwords did not match")
+	}
+
+	// Change user database (json file)
+	err = SetPassword(u, n)
+	if err != nil {
+		return "", fmt.Errorf("could not save logins file: %v", err)
 	}
-	return "", fmt.Errorf("old password incorrect (or username not found)")
+
+	// Change user in session
+	if changed, ok := Find(u); ok {
+		l.PassHash = changed.PassHash
+	}
+	l.PassInitial = ""
+	l.IsInitPassword = false
+	l.PassMd5 = ""
+	sess := sessx.New(w, r)
+	sess.PutObject("login", *l)
+
+	// SUCCESS
+	return "Password changed successfully.", nil
 }
 
 // GenerateHashesH is a admin UI to create login hashes for specific survey and user profile.
@@ -244,7 +226,7 @@ func GenerateHashesH(w http.ResponseWriter, r *http.Request) {
 
 	_, ok := r.PostForm["token"]
 	if ok {
-		err := ValidateFormToken(r.PostForm.Get("token"))
+		err := ValidateFormToken(r, r.PostForm.Get("token"))
 		if err != nil {
 			errMsg += fmt.Sprintf("Invalid request token: %v\n", err)
 		}
@@ -317,7 +299,7 @@ func GenerateHashesH(w http.ResponseWriter, r *http.Request) {
 		List  string        `json:"list"`
 	}
 	fe := formEntryT{}
-	fe.Token = FormToken()
+	fe.Token = FormToken(r)
 	for len(fe.Attrs) < 3+1 {
 		fe.Attrs = append(fe.Attrs, "")
 	}
@@ -408,6 +390,45 @@ func LoginPrimitiveCoreH(w http.ResponseWriter, r *http.Request) {
 	loginPrimitive(w, r, false)
 }
 
+// APILoginH logs in non-browser clients such as cmd/transferrer;
+// credentials are taken from the HTTP basic auth header - instead of a form;
+// thus no form token is required.
+// Browsers never send the header to this app on their own,
+// since the app never challenges for basic auth;
+// cross site requests cannot set it; forged requests fail.
+// Responds with "Logged in as [user]" - and the session cookie.
+func APILoginH(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != "POST" {
+		http.Error(w, "API login requires method POST", http.StatusMethodNotAllowed)
+		return
+	}
+	u, p, ok := r.BasicAuth()
+	if !ok {
+		http.Error(w, "API login requires basic auth credentials", http.StatusUnauthorized)
+		return
+	}
+
+	l, err := Get().FindAndCheck(u, p)
+	if err != nil {
+		// the reason stays in the log - it would reveal existing user names
+		log.Printf("API login of %q failed: %v", u, err)
+		time.Sleep(200 * time.Millisecond) // Brute force prevention
+		http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	err = LogoutH(w, r) // remove all previous session info
+	if err != nil {
+		log.Printf("logout error %v", err)
+	}
+	sess := sessx.New(w, r)
+	sess.PutObject("login", l)
+	log.Printf("logged in as %v via API", l.User)
+
+	fmt.Fprintf(w, "Logged in as %v\n", l.User)
+}
+
 // loginPrimitive is a primitive handler for http form based login by username and password.
 // It serves as pattern for an application specific login.
 // It also serves as real handler for applications having only a few admin users.
@@ -464,7 +485,7 @@ func loginPrimitive(w http.ResponseWriter, r *http.Request, outerHTML bool) {
 	data := dataT{
 		SelfURL: r.URL.Path,
 		Content: msg,
-		Token:   FormToken(),
+		Token:   FormToken(r),
 		L:       l,
 	}
 
@@ -549,7 +570,7 @@ func changePasswordPrimitive(w http.ResponseWriter, r *http.Request, outerHTML b
 	data := dataT{
 		SelfURL: r.URL.Path,
 		Content: msg,
-		Token:   FormToken(),
+		Token:   FormToken(r),
 		L:       l,
 	}
 
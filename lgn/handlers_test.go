@@ -0,0 +1,53 @@
+package lgn
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/sessx"
+)
+
+func TestAPILoginH(t *testing.T) {
+
+	adminTestSetup(t)
+	defer cloudio.SetStorageURL("")
+
+	lgns.Logins[0].PassHash = HashPassword("secret")
+	lgns.Logins = append(lgns.Logins, LoginT{User: "bert", PassHash: HashPassword("secret"), Disabled: true})
+
+	srv := httptest.NewServer(sessx.Mgr().LoadAndSave(http.HandlerFunc(APILoginH)))
+	defer srv.Close()
+
+	tests := []struct {
+		user, pw string
+		code     int
+		body     string
+	}{
+		{"anna", "secret", http.StatusOK, "Logged in as anna\n"},
+		{"anna", "wrong", http.StatusUnauthorized, "invalid credentials\n"},
+		{"nobody", "secret", http.StatusUnauthorized, "invalid credentials\n"},
+		{"bert", "secret", http.StatusUnauthorized, "invalid credentials\n"},
+	}
+	for _, tc := range tests {
+		req, err := http.NewRequest("POST", srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth(tc.user, tc.pw)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bts, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.code || string(bts) != tc.body {
+			t.Errorf("%v/%v: want %v %q - got %v %q", tc.user, tc.pw, tc.code, tc.body, resp.StatusCode, bts)
+		}
+	}
+}
@@ -1,5 +1,5 @@
 // Package lgn implements an internal login database;
-// users are stored in a JSON file;
+// users are stored in a JSON file - or in the database, see package store;
 // contains convenience handlers for user retrieval and password change;
 // contains login by hashed URL and login by hash ID;
 // contains profiles - groups of attributes for users.
@@ -9,6 +9,7 @@
 package lgn
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
@@ -25,6 +26,7 @@ import (
 	"github.com/zew/go-questionnaire/cfg"
 	"github.com/zew/go-questionnaire/cloudio"
 	"github.com/zew/go-questionnaire/sessx"
+	"github.com/zew/go-questionnaire/store"
 	"github.com/zew/util"
 )
 
@@ -83,9 +85,12 @@ type LoginT struct {
 	Roles    map[string]string `json:"roles"` // i.e. admin: true, can only be set via JSON config; therefore safe
 	Attrs    map[string]string `json:"attrs"` // i.e. country: Poland, gender: female, height: 188, can be overridden by URL params, therefore unsafe.
 
-	PassInitial    string `json:"pass_initial"`       // For first login - unencrypted - grants restricted access to change password only
-	IsInitPassword bool   `json:"is_init_password"`   // Indicates authentication against PassInitial
-	PassMd5        string `json:"pass_md5,omitempty"` // Encrypted password, created from login, permanent password, salt
+	PassInitial    string `json:"pass_initial,omitempty"` // For first login - unencrypted - hashed into PassHash on loading
+	IsInitPassword bool   `json:"is_init_password"`       // Forces a password change - grants restricted access to change password only
+	PassHash       string `json:"pass_hash,omitempty"`    // argon2id - see HashPassword()
+	PassMd5        string `json:"pass_md5,omitempty"`     // Legacy - salted MD5 - replaced by PassHash on next login
+
+	Disabled bool `json:"disabled,omitempty"` // no login with password - see admin functions
 }
 
 // We need to register all types who are saved into a session
@@ -111,6 +116,15 @@ func FromSession(w io.Writer, r *http.Request) (*LoginT, bool, error) {
 		return &LoginT{}, false, fmt.Errorf("key %v for LoginT{} does not point to lgn.LoginT{} - but to %T", key, loginIntf)
 	}
 
+	// logins from the logins file might have been disabled or deleted meanwhile
+	if l.Provider == "JSON" && Get() != nil {
+		idx := Get().index(l.User)
+		if idx < 0 || Get().Logins[idx].Disabled {
+			log.Printf("login %v from session is disabled or deleted", l.User)
+			return &LoginT{}, false, nil
+		}
+	}
+
 	return &l, true, nil
 }
 
@@ -155,24 +169,33 @@ func ComputeMD5Password(u, p, salt string) string {
 	return Md5Str([]byte(hashBase))
 }
 
-// SetInitPW sets an init password
-func (l *LoginT) SetInitPW(salt string) {
-	if l.IsInitPassword && l.PassInitial == "" {
+// SetInitPW hashes the cleartext init password into PassHash -
+// generating one, if neither init password nor hash exist;
+// the cleartext is removed from the login and returned.
+func (l *LoginT) SetInitPW() (passInitial string) {
+	if !l.IsInitPassword {
+		return ""
+	}
+	if l.PassInitial == "" && l.PassHash == "" {
 		l.PassInitial = GeneratePassword(8)
-		log.Printf("\tNew pw is %v", l.PassInitial)
-		hashBase := l.User + l.PassInitial + salt
-		pfx := cfg.Get().URLPathPrefix
-		if pfx == "taxkit" || pfx == "eta" {
-			hashBase = l.PassInitial + salt
-		}
-		l.PassMd5 = Md5Str([]byte(hashBase))
+		log.Printf("\tNew pw for %v is %v", l.User, l.PassInitial)
 	}
+	if l.PassInitial == "" {
+		return ""
+	}
+	passInitial = l.PassInitial
+	l.PassHash = HashPassword(passInitial)
+	l.PassMd5 = ""
+	l.PassInitial = ""
+	return passInitial
 }
 
 type loginsT struct {
 	// sync.Mutex
 	Salt   string   `json:"salt"`
 	Logins []LoginT `json:"logins"`
+
+	cleartext int // init passwords found in cleartext on loading
 }
 
 // LgnsPath is obtained by ENV variable or command line flag in main package.
@@ -207,6 +230,7 @@ func AddTestLogin() {
 		PassInitial:    "systemtest",
 		IsInitPassword: true,
 	}
+	systest.SetInitPW()
 	lgns.Logins = append(lgns.Logins, systest)
 }
 
@@ -215,43 +239,50 @@ func AddTestLogin() {
 // We could only *copy*:  *c = *newCfg
 func Load(r io.Reader) {
 
-	decoder := json.NewDecoder(r)
-	tmpLogins := loginsT{} // Important, to avoid inconsistent reads from other goroutines
-	err := decoder.Decode(&tmpLogins)
+	tmpLogins, err := parse(r) // Important, to avoid inconsistent reads from other goroutines
 	if err != nil {
 		log.Fatal(err)
 	}
+	log.Printf("Decode from JSON successful. Found %v logins", len(tmpLogins.Logins))
 
-	if len(tmpLogins.Salt) < 5 {
-		log.Fatal("Your logins config must contain a salt of at least five characters.")
+	log.Printf("\n%s", util.IndentedDump(tmpLogins))
+	lgns = tmpLogins // replace pointer in one go - should be threadsafe
+}
+
+// parse decodes logins - hashing init passwords set in cleartext
+// and deriving the fields not saved to JSON
+func parse(r io.Reader) (*loginsT, error) {
+
+	decoder := json.NewDecoder(r)
+	tmpLogins := &loginsT{}
+	err := decoder.Decode(tmpLogins)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("Decode from JSON successful. Found %v logins", len(tmpLogins.Logins))
+	if len(tmpLogins.Salt) < 5 {
+		return nil, fmt.Errorf("Your logins config must contain a salt of at least five characters.")
+	}
 
-	// Initiallize MD5 hashes from passwords
-	// explicitly set.
+	// Hash init passwords set in cleartext - or generate them;
+	// the cleartext is gone, once the logins are saved - see LoadFromStore()
 	for i := 0; i < len(tmpLogins.Logins); i++ {
-		tmpLogins.Logins[i].SetInitPW(tmpLogins.Salt)
+		if tmpLogins.Logins[i].SetInitPW() != "" {
+			tmpLogins.cleartext++
+		}
 	}
 
 	// Compute group - i.e. domain
 	for i := 0; i < len(tmpLogins.Logins); i++ {
-		els := strings.Split(tmpLogins.Logins[i].Email, "@")
-		group := els[0]
-		if len(els) > 1 {
-			group = els[1]
-		}
-		tmpLogins.Logins[i].Group = group
-		tmpLogins.Logins[i].Provider = "JSON"
+		tmpLogins.Logins[i].derive()
 	}
-
-	log.Printf("\n%s", util.IndentedDump(tmpLogins))
-	lgns = &tmpLogins // replace pointer in one go - should be threadsafe
+	return tmpLogins, nil
 }
 
 // FindAndCheck takes a username and password
 // and scans for matching users in the internal JSON database.
-// If optPw is given, a check for matching password is also made
+// If optPw is given, a check for matching password is also made;
+// legacy and outdated password hashes are then upgraded.
 func (l *loginsT) FindAndCheck(u string, optPw ...string) (LoginT, error) {
 
 	u = strings.ToLower(u)
@@ -259,28 +290,26 @@ func (l *loginsT) FindAndCheck(u string, optPw ...string) (LoginT, error) {
 
 	checkPassword := false
 	passUnencr := ""
-	passEncr := ""
 	if len(optPw) > 0 {
 		checkPassword = true
 		passUnencr = optPw[0]
-		passEncr = ComputeMD5Password(u, passUnencr, l.Salt)
 	}
 
 	for idx := 0; idx < len(l.Logins); idx++ {
 		if u == strings.ToLower(l.Logins[idx].User) {
+			if l.Logins[idx].Disabled {
+				return LoginT{}, errLoginDisabled
+			}
 			// log.Printf("found user %v", util.IndentedDump(l.Logins[idx]))
 			if checkPassword {
-				pw := l.Logins[idx].PassMd5
-				if passEncr == pw {
-					return l.Logins[idx], nil
+				ok, upgrade := l.Logins[idx].checkPassword(passUnencr, l.Salt)
+				if !ok {
+					return LoginT{}, errFoundButWrongPassword
 				}
-				if l.Logins[idx].IsInitPassword {
-					if l.Logins[idx].PassInitial == passUnencr {
-						return l.Logins[idx], nil
-					}
+				if upgrade {
+					upgradeHash(l.Logins[idx].User, passUnencr)
 				}
-				return LoginT{}, errFoundButWrongPassword
-
+				return l.Logins[idx], nil
 			}
 			return l.Logins[idx], nil
 		}
@@ -297,31 +326,64 @@ func IsFound(err error) bool {
 	return false
 }
 
+// LoadFromStore reads the logins from LgnsPath - see package store;
+// only if the file contains init passwords in cleartext, it is saved -
+// and only if no other app instance saved it meanwhile
+func LoadFromStore() error {
+	bts, err := store.Get().Read(LgnsPath)
+	if err != nil {
+		return err
+	}
+	Load(bytes.NewReader(bts))
+	if lgns.cleartext == 0 {
+		return nil
+	}
+	log.Printf("%v init passwords hashed - saving logins without cleartext", lgns.cleartext)
+	hashed, err := lgns.marshal()
+	if err != nil {
+		return err
+	}
+	err = store.Get().WriteIf(LgnsPath, hashed, func(current []byte) error {
+		if !bytes.Equal(current, bts) {
+			return cloudio.ErrConflict
+		}
+		return nil
+	})
+	if err == cloudio.ErrConflict {
+		log.Printf("%v was saved by another instance - loading anew", LgnsPath)
+		return LoadFromStore()
+	}
+	return err
+}
+
+// Save writes the logins to LgnsPath - see package store
+func Save() error {
+	return save(lgns)
+}
+
+func save(ls *loginsT) error {
+	bts, err := ls.marshal()
+	if err != nil {
+		return err
+	}
+	return store.Get().Write(LgnsPath, bts)
+}
+
+func (l *loginsT) marshal() ([]byte, error) {
+	firstColLeftMostPrefix := " "
+	return json.MarshalIndent(l, firstColLeftMostPrefix, "\t")
+}
+
 // LoadH is a convenience func to reload logins via http request.
 // It reloads logins from json file
 // and checks for a specific login
 func LoadH(w http.ResponseWriter, req *http.Request) {
 
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	fileName := LgnsPath
-	r, bucketClose, err := cloudio.Open(fileName)
+	err := LoadFromStore()
 	if err != nil {
-		log.Panicf("Error opening writer to %v: %v", fileName, err)
+		log.Panicf("Error reading %v: %v", LgnsPath, err)
 	}
-	defer func() {
-		err := r.Close()
-		if err != nil {
-			log.Printf("Error closing writer to bucket to %v: %v", fileName, err)
-		}
-	}()
-	defer func() {
-		err := bucketClose()
-		if err != nil {
-			log.Printf("Error closing bucket of writer to %v: %v", fileName, err)
-		}
-	}()
-	log.Printf("Opened reader to cloud config %v", fileName)
-	Load(r)
 
 	fmt.Fprint(w, "Login json file reloaded successfully. \n\n")
 	fmt.Fprint(w, "Check for specific user with ?u=[loginname] \n\n")
@@ -339,6 +401,7 @@ func LoadH(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	l.PassHash = "xxxx"
 	l.PassMd5 = "xxxx"
 	l.PassInitial = "xxxx"
 	str := fmt.Sprintf("Found %v => %v \n", u, util.IndentedDump(l))
@@ -348,7 +411,7 @@ func LoadH(w http.ResponseWriter, req *http.Request) {
 // SaveH is a convenience func to save logins file via http request.
 func SaveH(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	err := cloudio.MarshalWriteFile(lgns, LgnsPath)
+	err := Save()
 	if err != nil {
 		fmt.Fprintf(w, "error writing logins file: %v", err)
 		return
@@ -466,7 +529,7 @@ func Example() *loginsT {
 				Email:          "myUser@example.com",
 				Roles:          map[string]string{"admin": "yes"},
 				Attrs:          map[string]string{"country": "Sweden", "height": "174"},
-				PassInitial:    "Keep empty - have it set during startup - then call /logins-save",
+				PassInitial:    "Keep empty - generated during startup, see log - stored as hash only",
 				IsInitPassword: true,
 			},
 		},
@@ -0,0 +1,145 @@
+package lgn
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Password hashes are stored in PHC string format -
+// identifying algorithm, version and parameters:
+//
+//	$argon2id$v=19$m=19456,t=2,p=1$[salt]$[key]
+//
+// Thus the parameters can be raised later;
+// hashes with outdated parameters - and legacy MD5 hashes in PassMd5 -
+// are replaced on the next successful login; see FindAndCheck().
+const (
+	argonTime    = 2
+	argonMemory  = 19 * 1024 // KiB
+	argonThreads = 1
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+var b64 = base64.RawStdEncoding
+
+// HashPassword returns an argon2id hash of pw with a random salt
+func HashPassword(pw string) string {
+	salt := make([]byte, argonSaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		panic(err)
+	}
+	key := argon2.IDKey([]byte(pw), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads, b64.EncodeToString(salt), b64.EncodeToString(key))
+}
+
+// checkHash compares pw against a hash from HashPassword();
+// outdated reports a hash with parameters differing from the current ones
+func checkHash(encoded, pw string) (ok, outdated bool, err error) {
+	els := strings.Split(encoded, "$")
+	if len(els) != 6 || els[1] != "argon2id" {
+		return false, false, fmt.Errorf("unknown password hash format")
+	}
+	var version int
+	if _, err := fmt.Sscanf(els[2], "v=%d", &version); err != nil {
+		return false, false, fmt.Errorf("password hash version: %v", err)
+	}
+	if version != argon2.Version {
+		return false, false, fmt.Errorf("password hash version %v unsupported", version)
+	}
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(els[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false, false, fmt.Errorf("password hash parameters: %v", err)
+	}
+	salt, err := b64.DecodeString(els[4])
+	if err != nil {
+		return false, false, err
+	}
+	key, err := b64.DecodeString(els[5])
+	if err != nil {
+		return false, false, err
+	}
+	got := argon2.IDKey([]byte(pw), salt, iterations, memory, threads, uint32(len(key)))
+	ok = subtle.ConstantTimeCompare(got, key) == 1
+	outdated = memory != argonMemory || iterations != argonTime || threads != argonThreads || len(key) != argonKeyLen
+	return ok, outdated, nil
+}
+
+// checkPassword compares pw against the stored hash;
+// upgrade reports a match against a legacy or outdated hash
+func (l *LoginT) checkPassword(pw, salt string) (ok, upgrade bool) {
+	if l.PassHash != "" {
+		ok, outdated, err := checkHash(l.PassHash, pw)
+		if err != nil {
+			log.Printf("password hash of %v: %v", l.User, err)
+		}
+		return ok, ok && outdated
+	}
+	// legacy - cleartext init password, salted MD5
+	if l.IsInitPassword && l.PassInitial != "" {
+		ok = subtle.ConstantTimeCompare([]byte(l.PassInitial), []byte(pw)) == 1
+	}
+	if !ok && l.PassMd5 != "" {
+		for _, u := range []string{l.User, strings.ToLower(l.User)} {
+			if ComputeMD5Password(u, pw, salt) == l.PassMd5 {
+				ok = true
+			}
+		}
+	}
+	return ok, ok
+}
+
+// errUpToDate - the stored login needs no upgrade
+var errUpToDate = fmt.Errorf("password hash is up to date")
+
+// upgradeHash replaces a legacy or outdated hash after successful login;
+// the stored login is checked again - another app instance
+// might have upgraded or changed the password meanwhile
+func upgradeHash(user, pw string) {
+	err := update(false, func(ls *loginsT) error {
+		idx := ls.index(user)
+		if idx < 0 {
+			return errLoginNotFound
+		}
+		if ok, upgrade := ls.Logins[idx].checkPassword(pw, ls.Salt); !ok || !upgrade {
+			return errUpToDate
+		}
+		ls.Logins[idx].PassHash = HashPassword(pw)
+		ls.Logins[idx].PassMd5 = ""
+		ls.Logins[idx].PassInitial = ""
+		return nil
+	})
+	if err == errUpToDate {
+		return
+	}
+	if err != nil {
+		log.Printf("upgrading password hash of %v failed: %v", user, err)
+		return
+	}
+	log.Printf("password hash of %v upgraded", user)
+}
+
+// SetPassword replaces the password of user
+// and removes the forced change of an init password
+func SetPassword(user, pw string) error {
+	return update(false, func(ls *loginsT) error {
+		idx := ls.index(user)
+		if idx < 0 {
+			return errLoginNotFound
+		}
+		ls.Logins[idx].PassHash = HashPassword(pw)
+		ls.Logins[idx].PassMd5 = ""
+		ls.Logins[idx].PassInitial = ""
+		ls.Logins[idx].IsInitPassword = false
+		return nil
+	})
+}
@@ -0,0 +1,140 @@
+package lgn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/store"
+)
+
+func TestCheckPassword(t *testing.T) {
+
+	hsh := HashPassword("secret")
+	if !strings.HasPrefix(hsh, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("unexpected hash format %v", hsh)
+	}
+	if hsh == HashPassword("secret") {
+		t.Errorf("hashes must differ by salt")
+	}
+
+	tests := []struct {
+		l       LoginT
+		pw      string
+		ok      bool
+		upgrade bool
+	}{
+		{LoginT{User: "a", PassHash: hsh}, "secret", true, false},
+		{LoginT{User: "a", PassHash: hsh}, "wrong", false, false},
+		{LoginT{User: "a", PassHash: strings.Replace(hsh, "t=2", "t=1", 1)}, "wrong", false, false},
+		{LoginT{User: "a", PassMd5: ComputeMD5Password("a", "secret", "salt")}, "secret", true, true},
+		{LoginT{User: "A", PassMd5: ComputeMD5Password("a", "secret", "salt")}, "secret", true, true},
+		{LoginT{User: "a", PassMd5: ComputeMD5Password("a", "secret", "salt")}, "wrong", false, false},
+		{LoginT{User: "a", IsInitPassword: true, PassInitial: "secret"}, "secret", true, true},
+		{LoginT{User: "a", PassInitial: "secret"}, "secret", false, false},
+	}
+	for i, tc := range tests {
+		ok, upgrade := tc.l.checkPassword(tc.pw, "salt")
+		if ok != tc.ok || upgrade != tc.upgrade {
+			t.Errorf("test %v: got %v %v - want %v %v", i, ok, upgrade, tc.ok, tc.upgrade)
+		}
+	}
+
+	// outdated parameters
+	old := "$argon2id$v=19$m=1024,t=1,p=1$" + strings.Join(strings.Split(hsh, "$")[4:], "$")
+	if _, outdated, err := checkHash(old, "secret"); err != nil || !outdated {
+		t.Errorf("want outdated - got %v %v", outdated, err)
+	}
+
+	// init password is hashed and removed
+	l := LoginT{User: "a", IsInitPassword: true, PassInitial: "secret"}
+	if pw := l.SetInitPW(); pw != "secret" || l.PassInitial != "" {
+		t.Errorf("got %q - cleartext %q", pw, l.PassInitial)
+	}
+	if ok, _ := l.checkPassword("secret", "salt"); !ok || !l.IsInitPassword {
+		t.Errorf("hashed init password must match and still force a change")
+	}
+}
+
+// countingStoreT counts writes of the logins file
+type countingStoreT struct {
+	store.FilesT
+	writes *int
+}
+
+func (s countingStoreT) Write(key string, bts []byte) error {
+	if key == LgnsPath {
+		*s.writes++
+	}
+	return store.FilesT{}.Write(key, bts)
+}
+
+func (s countingStoreT) WriteIf(key string, bts []byte, check func(current []byte) error) error {
+	if key == LgnsPath {
+		*s.writes++
+	}
+	return store.FilesT{}.WriteIf(key, bts, check)
+}
+
+func TestLoadFromStoreHashing(t *testing.T) {
+
+	adminTestSetup(t)
+	defer cloudio.SetStorageURL("")
+
+	lgns.Logins = append(lgns.Logins, LoginT{User: "init", IsInitPassword: true, PassInitial: "secret"})
+	if err := Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	writes := 0
+	store.Set(countingStoreT{writes: &writes})
+	defer store.Set(store.FilesT{})
+
+	// cleartext in the file - saved once
+	if err := LoadFromStore(); err != nil {
+		t.Fatal(err)
+	}
+	if writes != 1 || strings.Contains(mustRead(LgnsPath), "secret") {
+		t.Errorf("want cleartext removed by one write - got %v writes", writes)
+	}
+	if _, err := Get().FindAndCheck("init", "secret"); err != nil {
+		t.Errorf("hashed init password must match: %v", err)
+	}
+
+	// no cleartext - no save on loading
+	if err := LoadFromStore(); err != nil {
+		t.Fatal(err)
+	}
+	if writes != 1 {
+		t.Errorf("want no write without cleartext - got %v writes", writes)
+	}
+}
+
+func TestUpgradeHash(t *testing.T) {
+
+	adminTestSetup(t)
+	defer cloudio.SetStorageURL("")
+
+	lgns.Logins[0].PassMd5 = ComputeMD5Password("anna", "secret", lgns.Salt)
+	if err := Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	writes := 0
+	store.Set(countingStoreT{writes: &writes})
+	defer store.Set(store.FilesT{})
+
+	if _, err := Get().FindAndCheck("anna", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := Find("anna")
+	if writes != 1 || stored.PassMd5 != "" || !strings.HasPrefix(stored.PassHash, "$argon2id$") {
+		t.Errorf("want legacy hash upgraded by one write - got %v writes, %+v", writes, stored)
+	}
+
+	// another instance upgraded meanwhile - nothing to write
+	upgradeHash("anna", "secret")
+	if writes != 1 {
+		t.Errorf("want no write for an upgraded login - got %v writes", writes)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/zew/go-questionnaire/cloudio"
 	"github.com/zew/go-questionnaire/ctr"
 	"github.com/zew/go-questionnaire/sessx"
+	"github.com/zew/go-questionnaire/store"
 )
 
 var ltCounter = ctr.New()
@@ -388,7 +389,7 @@ func (l *LoginT) DeleteFiles() {
 	pths := []string{pth3}
 
 	for _, pth := range pths {
-		err := cloudio.Delete(pth)
+		err := store.Get().Delete(pth)
 		if err != nil {
 			if !cloudio.IsNotExist(err) {
 				log.Printf("Error deleting questionnaire file: %v", err)
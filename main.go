@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io/ioutil"
@@ -8,12 +9,16 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/zew/go-questionnaire/bootstrap"
 	"github.com/zew/go-questionnaire/cfg"
+	"github.com/zew/go-questionnaire/cloudio"
 	"github.com/zew/go-questionnaire/handlers"
 	"github.com/zew/go-questionnaire/sessx"
+	"github.com/zew/go-questionnaire/store"
 	"github.com/zew/go-questionnaire/tpl"
 	"github.com/zew/go-questionnaire/wrap"
 	"golang.org/x/crypto/acme/autocert"
@@ -134,7 +139,11 @@ func main() {
 		if cfg.Get().LetsEncrypt {
 			fallbackSrv.Handler = certManager.HTTPHandler(nil)
 		}
-		go func() { log.Fatal(fallbackSrv.ListenAndServe()) }()
+		go func() {
+			if err := fallbackSrv.ListenAndServe(); err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
 
 		//
 		tlsCfg := &tls.Config{
@@ -178,13 +187,51 @@ func main() {
 			TLSNextProto:      make(map[string]func(*http.Server, *tls.Conn, http.Handler), 0),
 			Handler:           mux4,
 		}
+		done := shutdownOnSignal(srv, fallbackSrv)
+		var err error
 		if cfg.Get().LetsEncrypt {
-			log.Fatal(srv.ListenAndServeTLS("", "")) // "", "" => empty key and cert files; key+cert come from Let's Encrypt
+			err = srv.ListenAndServeTLS("", "") // "", "" => empty key and cert files; key+cert come from Let's Encrypt
 		} else {
-			log.Fatal(srv.ListenAndServeTLS("server.pem", "server.key"))
+			err = srv.ListenAndServeTLS("server.pem", "server.key")
 		}
+		if err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+		<-done
 	} else {
-		log.Fatal(http.ListenAndServe(IPPort, mux4))
+		srv := &http.Server{Addr: IPPort, Handler: mux4}
+		done := shutdownOnSignal(srv)
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+		<-done
 	}
 
 }
+
+// shutdownOnSignal stops the servers gracefully on interrupt or terminate;
+// then closes the store and the storage bucket; done is closed thereafter
+func shutdownOnSignal(srvs ...*http.Server) (done chan struct{}) {
+	done = make(chan struct{})
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		log.Printf("received %v - shutting down", <-sig)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		for _, srv := range srvs {
+			if err := srv.Shutdown(ctx); err != nil {
+				log.Printf("server shutdown: %v", err)
+			}
+		}
+		if err := store.Close(); err != nil {
+			log.Printf("store shutdown: %v", err)
+		}
+		if err := cloudio.Shutdown(); err != nil {
+			log.Printf("storage shutdown: %v", err)
+		}
+		close(done)
+	}()
+	return
+}
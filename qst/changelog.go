@@ -0,0 +1,71 @@
+package qst
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/zew/go-questionnaire/cloudio"
+)
+
+// ChangeT is a changed answer of a participant;
+// an entry of the change log - see AppendChanges()
+type ChangeT struct {
+	Time     time.Time `json:"time"`
+	Page     int       `json:"page"` // zero based
+	Name     string    `json:"name"`
+	Old      string    `json:"old"`
+	New      string    `json:"new"`
+	RemoteIP string    `json:"remote_ip,omitempty"`
+}
+
+// ChangeLogPath returns the change log of response file pth;
+// the change logs reside in subdirectory changelog,
+// thus iterating the response files of a wave skips them.
+func ChangeLogPath(pth string) string {
+	dir, fn := path.Split(pth)
+	return path.Join(dir, "changelog", strings.TrimSuffix(fn, ".json")+".ndjson")
+}
+
+// AppendChanges appends changes to the change log of response file pth -
+// one JSON object per line; existing entries are never changed
+func AppendChanges(pth string, changes []ChangeT) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf) // one line per Encode()
+	for _, chg := range changes {
+		if err := enc.Encode(chg); err != nil {
+			return err
+		}
+	}
+	return cloudio.AppendFile(ChangeLogPath(pth), buf.Bytes())
+}
+
+// ReadChanges reads a change log file;
+// the path is the change log itself - not the response file
+func ReadChanges(pthLog string) ([]ChangeT, error) {
+	bts, err := cloudio.ReadFile(pthLog)
+	if err != nil {
+		return nil, err
+	}
+	changes := []ChangeT{}
+	scanner := bufio.NewScanner(bytes.NewReader(bts))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for i := 1; scanner.Scan(); i++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		chg := ChangeT{}
+		if err := json.Unmarshal(scanner.Bytes(), &chg); err != nil {
+			return changes, fmt.Errorf("%v line %v: %v", pthLog, i, err)
+		}
+		changes = append(changes, chg)
+	}
+	return changes, scanner.Err()
+}
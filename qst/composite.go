@@ -23,12 +23,6 @@ package qst
 //   slice of input names
 //   error
 //
+// Composite funcs are registered by RegisterComposite() -
+// survey specific ones from the init() of their package under generators/
 type CompositFuncT func(*QuestionnaireT, int, int) (string, []string, error)
-
-// CompositeFuncs is a lookup map
-var CompositeFuncs = map[string]CompositFuncT{
-	"PoliticalFoundationsPretext": PoliticalFoundationsPretext, // belongs to pat
-	"PoliticalFoundations":        PoliticalFoundations,        // belongs to pat
-	"TimePreferenceSelf":          TimePreferenceSelf,          // belongs to pat
-	"GroupPreferences":            GroupPreferences,            // belongs to pat
-}
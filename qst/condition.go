@@ -0,0 +1,375 @@
+package qst
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+/*
+Conditions are declarative visibility rules for pages, groups and inputs.
+They are stored as plain strings in the JSON template, i.e.
+
+	q3 == 2 and attr country in [DE,AT]
+	not (q5 in [1,2]) or q7 != ''
+
+An operand is either an input name - compared against its response -
+or 'attr' followed by a key of QuestionnaireT.Attrs.
+Operators are == != < <= > >= in, 'not in'.
+Values are compared numerically, if both sides parse as numbers;
+otherwise as trimmed strings.
+Comparisons are combined by and, or, not and parentheses;
+and binds stronger than or.
+*/
+
+// conditionT is a node of a parsed condition
+type conditionT interface {
+	eval(q *QuestionnaireT) bool
+	comparisons() []*comparisonT
+}
+
+type andT struct{ left, right conditionT }
+type orT struct{ left, right conditionT }
+type notT struct{ inner conditionT }
+
+// comparisonT is the leaf of a condition tree
+type comparisonT struct {
+	IsAttr bool     // operand is a user attribute, not an input response
+	Key    string   // input name or attribute key
+	Op     string   // ==, !=, <, <=, >, >=, in, notin
+	Vals   []string // one value - or several for in and notin
+}
+
+func (c andT) eval(q *QuestionnaireT) bool { return c.left.eval(q) && c.right.eval(q) }
+func (c orT) eval(q *QuestionnaireT) bool  { return c.left.eval(q) || c.right.eval(q) }
+func (c notT) eval(q *QuestionnaireT) bool { return !c.inner.eval(q) }
+
+func (c andT) comparisons() []*comparisonT {
+	return append(c.left.comparisons(), c.right.comparisons()...)
+}
+func (c orT) comparisons() []*comparisonT {
+	return append(c.left.comparisons(), c.right.comparisons()...)
+}
+func (c notT) comparisons() []*comparisonT { return c.inner.comparisons() }
+func (c *comparisonT) comparisons() []*comparisonT {
+	return []*comparisonT{c}
+}
+
+// operand returns the current value of the compared input or attribute
+func (c *comparisonT) operand(q *QuestionnaireT) string {
+	if c.IsAttr {
+		return q.Attrs[c.Key]
+	}
+	inp := q.ByName(c.Key)
+	if inp == nil {
+		return ""
+	}
+	return inp.Response
+}
+
+// compareVals returns -1, 0, 1;
+// numerically, if both values are numbers
+func compareVals(a, b string) int {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+func (c *comparisonT) eval(q *QuestionnaireT) bool {
+	v := c.operand(q)
+	switch c.Op {
+	case "in", "notin":
+		found := false
+		for _, val := range c.Vals {
+			if compareVals(v, val) == 0 {
+				found = true
+				break
+			}
+		}
+		return found == (c.Op == "in")
+	case "==":
+		return compareVals(v, c.Vals[0]) == 0
+	case "!=":
+		return compareVals(v, c.Vals[0]) != 0
+	case "<":
+		return compareVals(v, c.Vals[0]) < 0
+	case "<=":
+		return compareVals(v, c.Vals[0]) <= 0
+	case ">":
+		return compareVals(v, c.Vals[0]) > 0
+	case ">=":
+		return compareVals(v, c.Vals[0]) >= 0
+	}
+	return false
+}
+
+// tokenizeCondition splits a condition into
+// brackets, commas, operators, quoted strings and words
+func tokenizeCondition(s string) ([]string, error) {
+	tokens := []string{}
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			i++
+		case strings.ContainsRune("()[],", r):
+			tokens = append(tokens, string(r))
+			i++
+		case strings.ContainsRune("=!<>", r):
+			if i+1 < len(rs) && rs[i+1] == '=' {
+				tokens = append(tokens, string(rs[i:i+2]))
+				i += 2
+				continue
+			}
+			if r == '=' || r == '!' {
+				return nil, fmt.Errorf("position %v: incomplete operator %q", i, string(r))
+			}
+			tokens = append(tokens, string(r))
+			i++
+		case r == '\'' || r == '"':
+			end := i + 1
+			for end < len(rs) && rs[end] != r {
+				end++
+			}
+			if end >= len(rs) {
+				return nil, fmt.Errorf("position %v: unterminated string", i)
+			}
+			tokens = append(tokens, string(rs[i:end+1]))
+			i = end + 1
+		default:
+			end := i
+			for end < len(rs) && !strings.ContainsRune(" \t\n\r()[],=!<>'\"", rs[end]) {
+				end++
+			}
+			tokens = append(tokens, string(rs[i:end]))
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// conditionParserT is a recursive descent parser
+// over the tokens of a condition
+type conditionParserT struct {
+	tokens []string
+	pos    int
+}
+
+func (p *conditionParserT) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *conditionParserT) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *conditionParserT) keyword(t, kw string) bool {
+	return strings.ToLower(t) == kw
+}
+
+// expr := term { or term }
+func (p *conditionParserT) expr() (conditionT, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword(p.peek(), "or") || p.peek() == "||" {
+		p.next()
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = orT{left, right}
+	}
+	return left, nil
+}
+
+// term := factor { and factor }
+func (p *conditionParserT) term() (conditionT, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword(p.peek(), "and") || p.peek() == "&&" {
+		p.next()
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = andT{left, right}
+	}
+	return left, nil
+}
+
+// factor := not factor | ( expr ) | comparison
+func (p *conditionParserT) factor() (conditionT, error) {
+	if p.keyword(p.peek(), "not") {
+		p.next()
+		inner, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return notT{inner}, nil
+	}
+	if p.peek() == "(" {
+		p.next()
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t != ")" {
+			return nil, fmt.Errorf("expected ')' - got %q", t)
+		}
+		return inner, nil
+	}
+	return p.comparison()
+}
+
+// comparison := [attr] key op value | [attr] key [not] in [ value {, value} ]
+func (p *conditionParserT) comparison() (conditionT, error) {
+
+	c := &comparisonT{}
+
+	t := p.next()
+	if p.keyword(t, "attr") {
+		c.IsAttr = true
+		t = p.next()
+	}
+	if t == "" || strings.ContainsAny(t, "()[],=!<>'\"") {
+		return nil, fmt.Errorf("expected input name or attribute key - got %q", t)
+	}
+	c.Key = t
+
+	op := p.next()
+	if p.keyword(op, "not") {
+		if !p.keyword(p.next(), "in") {
+			return nil, fmt.Errorf("%v: expected 'in' after 'not'", c.Key)
+		}
+		op = "notin"
+	}
+	op = strings.ToLower(op)
+
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		c.Op = op
+		v, err := p.value()
+		if err != nil {
+			return nil, fmt.Errorf("%v %v: %v", c.Key, op, err)
+		}
+		c.Vals = []string{v}
+	case "in", "notin":
+		c.Op = op
+		if t := p.next(); t != "[" {
+			return nil, fmt.Errorf("%v %v: expected '[' - got %q", c.Key, op, t)
+		}
+		for {
+			v, err := p.value()
+			if err != nil {
+				return nil, fmt.Errorf("%v %v: %v", c.Key, op, err)
+			}
+			c.Vals = append(c.Vals, v)
+			t := p.next()
+			if t == "]" {
+				break
+			}
+			if t != "," {
+				return nil, fmt.Errorf("%v %v: expected ',' or ']' - got %q", c.Key, op, t)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%v: unknown operator %q", c.Key, op)
+	}
+
+	return c, nil
+}
+
+// value is a word or a quoted string
+func (p *conditionParserT) value() (string, error) {
+	t := p.next()
+	if t == "" {
+		return "", fmt.Errorf("missing value")
+	}
+	if len(t) > 1 && (t[0] == '\'' || t[0] == '"') {
+		return t[1 : len(t)-1], nil
+	}
+	if strings.ContainsAny(t, "()[],=!<>") {
+		return "", fmt.Errorf("expected value - got %q", t)
+	}
+	return t, nil
+}
+
+// parseCondition parses a condition string into a tree
+func parseCondition(s string) (conditionT, error) {
+	tokens, err := tokenizeCondition(s)
+	if err != nil {
+		return nil, fmt.Errorf("condition %q: %v", s, err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("condition %q is empty", s)
+	}
+	p := &conditionParserT{tokens: tokens}
+	c, err := p.expr()
+	if err != nil {
+		return nil, fmt.Errorf("condition %q: %v", s, err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("condition %q: unexpected %q", s, p.peek())
+	}
+	return c, nil
+}
+
+// ConditionTrue evaluates a condition against
+// the responses and the user attributes of q.
+// An empty condition is always true.
+// Malformed conditions are logged and treated as true,
+// so that a faulty template does not hide questions;
+// Validate() catches them at generation time.
+func (q *QuestionnaireT) ConditionTrue(cond string) bool {
+	if strings.TrimSpace(cond) == "" {
+		return true
+	}
+	c, err := parseCondition(cond)
+	if err != nil {
+		log.Print(err)
+		return true
+	}
+	return c.eval(q)
+}
+
+// IsPageVisible checks the page condition
+func (q *QuestionnaireT) IsPageVisible(pageIdx int) bool {
+	return q.ConditionTrue(q.Pages[pageIdx].ShowIf)
+}
+
+// IsGroupVisible checks the conditions of page and group
+func (q *QuestionnaireT) IsGroupVisible(pageIdx, grpIdx int) bool {
+	if !q.IsPageVisible(pageIdx) {
+		return false
+	}
+	return q.ConditionTrue(q.Pages[pageIdx].Groups[grpIdx].ShowIf)
+}
+
+// IsInputVisible checks the conditions of page, group and input
+func (q *QuestionnaireT) IsInputVisible(pageIdx, grpIdx, inpIdx int) bool {
+	if !q.IsGroupVisible(pageIdx, grpIdx) {
+		return false
+	}
+	return q.ConditionTrue(q.Pages[pageIdx].Groups[grpIdx].Inputs[inpIdx].ShowIf)
+}
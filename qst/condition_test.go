@@ -0,0 +1,121 @@
+package qst
+
+import (
+	"testing"
+)
+
+func TestConditionTrue(t *testing.T) {
+
+	q := &QuestionnaireT{}
+	q.Attrs = map[string]string{"country": "DE"}
+	gr := q.AddPage().AddGroup()
+	inp := gr.AddInput()
+	inp.Name = "q3"
+	inp.Type = "text"
+	inp.Response = "2"
+	inp = gr.AddInput()
+	inp.Name = "q4"
+	inp.Type = "text"
+	inp.Response = "yes"
+
+	tests := []struct {
+		cond string
+		want bool
+	}{
+		{"", true},
+		{"q3 == 2", true},
+		{"q3 == 2.0", true},
+		{"q3 != 2", false},
+		{"q3 > 1 and q3 <= 2", true},
+		{"q3 == 2 and attr country in [DE,AT]", true},
+		{"q3 == 2 and attr country in [FR, 'IT']", false},
+		{"attr country not in [FR,IT]", true},
+		{"q4 == 'yes' or q3 == 5", true},
+		{"not (q4 == yes)", false},
+		{"q3 == 1 or q3 == 3 and q4 == yes", false},
+		{"q_unknown == ''", true},
+		{"attr missing == ''", true},
+		{"q3 ==", true}, // malformed - treated as visible
+	}
+	for _, tt := range tests {
+		t.Run(tt.cond, func(t *testing.T) {
+			if got := q.ConditionTrue(tt.cond); got != tt.want {
+				t.Errorf("ConditionTrue(%q) = %v, want %v", tt.cond, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	tests := []string{
+		"q3",
+		"q3 = 2",
+		"q3 in 2",
+		"q3 in [1,2",
+		"(q3 == 2",
+		"q3 == 2 q4 == 3",
+		"q3 == 'open",
+		"attr == 2",
+	}
+	for _, cond := range tests {
+		t.Run(cond, func(t *testing.T) {
+			if _, err := parseCondition(cond); err == nil {
+				t.Errorf("parseCondition(%q) should have failed", cond)
+			}
+		})
+	}
+}
+
+func TestValidateConditions(t *testing.T) {
+
+	build := func(pageCond, grpCond, inpCond string) *QuestionnaireT {
+		q := &QuestionnaireT{}
+		gr := q.AddPage().AddGroup()
+		for _, v := range []string{"1", "2"} {
+			inp := gr.AddInput()
+			inp.Name = "q1"
+			inp.Type = "radio"
+			inp.ValueRadio = v
+		}
+		p2 := q.AddPage()
+		p2.ShowIf = pageCond
+		gr = p2.AddGroup()
+		gr.ShowIf = grpCond
+		inp := gr.AddInput()
+		inp.Name = "q2"
+		inp.Type = "text"
+		inp.ShowIf = inpCond
+		gr = q.AddPage().AddGroup()
+		inp = gr.AddInput()
+		inp.Name = "q3"
+		inp.Type = "text"
+		return q
+	}
+
+	tests := []struct {
+		name                       string
+		pageCond, grpCond, inpCond string
+		wantErr                    bool
+	}{
+		{"valid", "q1 == 2", "q1 in [1,2] and attr country == DE", "q1 != 0", false},
+		{"syntax", "q1 ==", "", "", true},
+		{"unknown input", "q_typo == 2", "", "", true},
+		{"unknown radio value", "q1 == 3", "", "", true},
+		{"unknown radio value in list", "", "q1 in [1,4]", "", true},
+		{"radio value compared numerically", "q1 == 2.0", "q1 in [01, 2]", "", false},
+		{"radio value 2.5", "q1 == 2.5", "", "", true},
+		{"forward reference", "", "q3 == 1", "", true},
+		{"page references own page", "q2 == 1", "", "", true},
+		{"group references own page", "", "q2 == 1", "", false},
+		{"input references itself", "", "", "q2 == 1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := build(tt.pageCond, tt.grpCond, tt.inpCond)
+			err := q.validateConditions()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConditions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -10,14 +10,25 @@ import (
 	"github.com/zew/go-questionnaire/trl"
 )
 
-type dynFuncT func(*QuestionnaireT) (string, error)
-
-var dynFuncs = map[string]dynFuncT{
-	"RepsonseStatistics":             RepsonseStatistics,
-	"PersonalLink":                   PersonalLink,
-	"HasEuroQuestion":                ResponseTextHasEuro,
-	"FederalStateAboveOrBelowMedian": FederalStateAboveOrBelowMedian,
-	"PatLogos":                       PatLogos,
+// DynFuncT computes the label of an input of type dyn-textblock;
+// dynamic funcs are registered by RegisterDynFunc()
+type DynFuncT func(*QuestionnaireT) (string, error)
+
+func init() {
+	RegisterDynFunc("RepsonseStatistics", RepsonseStatistics, FuncMetaT{
+		Desc: "percentage of inputs answered and survey deadline",
+	})
+	RegisterDynFunc("PersonalLink", PersonalLink, FuncMetaT{
+		Desc: "hint to personal link for review - or time of completion",
+	})
+	RegisterDynFunc("HasEuroQuestion", ResponseTextHasEuro, FuncMetaT{
+		Params: []string{"attr euro-member", "attr country"},
+		Desc:   "statement on euro benefits - depending on euro membership of country of residence",
+	})
+	RegisterDynFunc("FederalStateAboveOrBelowMedian", FederalStateAboveOrBelowMedian, FuncMetaT{
+		Params: []string{"attr aboveOrBelowMedian"},
+		Desc:   "'besser' or 'schlechter' - education ranking of federal state",
+	})
 }
 
 // Statistics returns the percentage of
@@ -155,28 +166,3 @@ func FederalStateAboveOrBelowMedian(q *QuestionnaireT) (string, error) {
 	return attr1, nil
 
 }
-
-// PatLogos - only for the img src URLs
-func PatLogos(q *QuestionnaireT) (string, error) {
-
-	return fmt.Sprintf(
-		`
-		<div class="uni-logos  logo-imgs-in-content">
-			<img src="%v"  style="width:61%%;"  alt=""  >
-			<img src="%v"  style="width:33%%;"  alt=""  >
-			<img src="%v"  style="width:50%%;"  alt=""  >
-			<img src="%v"  style="width:44%%;"  alt=""  >
-			<img src="%v"  style="width:28%%;"  alt=""  >
-		</div>
-		
-		<br>
-		
-		`,
-		cfg.Pref("/img/pat/uni-mannheim-wide.png"),
-		cfg.Pref("/img/pat/uni-koeln.png"),
-		cfg.Pref("/img/pat/uni-muenster.png"),
-		cfg.Pref("/img/pat/uni-zurich.png"),
-		cfg.Pref("/img/pat/zew.png"),
-	), nil
-
-}
@@ -0,0 +1,218 @@
+package qst
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FuncMetaT describes a registered composite or dynamic func
+type FuncMetaT struct {
+	Name       string   `json:"name"`                  // set on registration
+	SurveyType string   `json:"survey_type,omitempty"` // empty for funcs of any survey
+	Params     []string `json:"params,omitempty"`      // composite funcs: meaning of sequence idx and param set idx; dynamic funcs: attributes read
+	Desc       string   `json:"description,omitempty"`
+}
+
+type compositeEntryT struct {
+	fn   CompositFuncT
+	meta FuncMetaT
+}
+
+type dynFuncEntryT struct {
+	fn   DynFuncT
+	meta FuncMetaT
+}
+
+// registries - filled from init() funcs - read only afterwards
+var composites = map[string]compositeEntryT{}
+var dynFuncs = map[string]dynFuncEntryT{}
+
+func checkRegistration(kind, name string, isNil, exists bool) {
+	if name == "" || strings.Contains(name, "__") {
+		log.Panicf("%v func name %q must be non-empty and must not contain '__'", kind, name)
+	}
+	if isNil {
+		log.Panicf("%v func %v is nil", kind, name)
+	}
+	if exists {
+		log.Panicf("%v func %v is registered already", kind, name)
+	}
+}
+
+// RegisterComposite makes composite func fn available
+// to inputs of type dyn-composite - as DynamicFunc name__seqIdx__paramSetIdx;
+// call it from init(); registering a name twice panics.
+func RegisterComposite(name string, fn CompositFuncT, meta FuncMetaT) {
+	_, exists := composites[name]
+	checkRegistration("composite", name, fn == nil, exists)
+	meta.Name = name
+	composites[name] = compositeEntryT{fn: fn, meta: meta}
+}
+
+// RegisterDynFunc makes dynamic func fn available
+// to inputs of type dyn-textblock - as DynamicFunc name;
+// call it from init(); registering a name twice panics.
+func RegisterDynFunc(name string, fn DynFuncT, meta FuncMetaT) {
+	_, exists := dynFuncs[name]
+	checkRegistration("dynamic", name, fn == nil, exists)
+	meta.Name = name
+	dynFuncs[name] = dynFuncEntryT{fn: fn, meta: meta}
+}
+
+// Composites returns the registered composite funcs - sorted by name
+func Composites() []FuncMetaT {
+	ret := make([]FuncMetaT, 0, len(composites))
+	for _, e := range composites {
+		ret = append(ret, e.meta)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// DynFuncs returns the registered dynamic funcs - sorted by name
+func DynFuncs() []FuncMetaT {
+	ret := make([]FuncMetaT, 0, len(dynFuncs))
+	for _, e := range dynFuncs {
+		ret = append(ret, e.meta)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// levenshtein distance of a and b - case insensitive
+func levenshtein(a, b string) int {
+	ra := []rune(strings.ToLower(a))
+	rb := []rune(strings.ToLower(b))
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j] + 1 // deletion
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1 // insertion
+			}
+			if prev[j-1]+cost < curr[j] {
+				curr[j] = prev[j-1] + cost // substitution
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// suggest returns up to three of the known names
+// similar to name - closest first
+func suggest(name string, metas []FuncMetaT) []string {
+	type candT struct {
+		name string
+		dist int
+	}
+	maxDist := len(name) / 3
+	if maxDist < 2 {
+		maxDist = 2
+	}
+	cands := []candT{}
+	for _, m := range metas {
+		d := levenshtein(name, m.Name)
+		if d <= maxDist || (name != "" && strings.Contains(strings.ToLower(m.Name), strings.ToLower(name))) {
+			cands = append(cands, candT{m.Name, d})
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].dist < cands[j].dist })
+	ret := []string{}
+	for i := 0; i < len(cands) && i < 3; i++ {
+		ret = append(ret, cands[i].name)
+	}
+	return ret
+}
+
+func unknownFunc(s, kind, name string, metas []FuncMetaT) string {
+	msg := fmt.Sprintf("%v%v func '%v' is not registered", s, kind, name)
+	if sugg := suggest(name, metas); len(sugg) > 0 {
+		msg += fmt.Sprintf(" - did you mean '%v'?", strings.Join(sugg, "', '"))
+	}
+	return msg
+}
+
+// parseComposite splits the DynamicFunc of a dyn-composite input
+// into func name, sequence idx and param set idx - name__seqIdx__paramSetIdx
+func parseComposite(dynFunc string) (name string, seqIdx, paramSetIdx int, err error) {
+	splt := strings.Split(dynFunc, "__")
+	if len(splt) != 3 {
+		return "", 0, 0, fmt.Errorf("composite func %q must be func name '__' sequence idx '__' param set idx", dynFunc)
+	}
+	seqIdx, err = strconv.Atoi(splt[1])
+	if err != nil || seqIdx < 0 {
+		return "", 0, 0, fmt.Errorf("composite func %q: sequence idx %q must be a non-negative integer", dynFunc, splt[1])
+	}
+	paramSetIdx, err = strconv.Atoi(splt[2])
+	if err != nil || paramSetIdx < 0 {
+		return "", 0, 0, fmt.Errorf("composite func %q: param set idx %q must be a non-negative integer", dynFunc, splt[2])
+	}
+	return splt[0], seqIdx, paramSetIdx, nil
+}
+
+// preflightComposite calls cF for Validate() - a panic,
+// i.e. a sequence idx beyond the composite's data, becomes an error
+func preflightComposite(cF CompositFuncT, q *QuestionnaireT, seqIdx, paramSetIdx int) (html string, inputs []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("composite func panicked: %v", r)
+		}
+	}()
+	return cF(q, seqIdx, paramSetIdx)
+}
+
+// validateFuncNames checks the composite and dynamic funcs
+// of all inputs for registration - listing all unknown ones with suggestions;
+// malformed composite func names and groups mixing composite
+// with other inputs are listed as well.
+func (q *QuestionnaireT) validateFuncNames() error {
+	invalid := []string{}
+	for i1 := 0; i1 < len(q.Pages); i1++ {
+		for i2 := 0; i2 < len(q.Pages[i1].Groups); i2++ {
+			cntrComposite, cntrOther := 0, 0
+			for i3, inp := range q.Pages[i1].Groups[i2].Inputs {
+				s := fmt.Sprintf("Page %v - Group %v - Input %v: ", i1, i2, i3)
+				if inp.Type == "dyn-composite" || inp.Type == "dyn-composite-scalar" {
+					cntrComposite++
+				} else {
+					cntrOther++
+				}
+				if inp.Type == "dyn-composite" {
+					name, _, _, err := parseComposite(inp.DynamicFunc)
+					if err != nil {
+						invalid = append(invalid, s+err.Error())
+						continue
+					}
+					if _, ok := composites[name]; !ok {
+						invalid = append(invalid, unknownFunc(s, "composite", name, Composites()))
+					}
+				}
+				if inp.Type == "dyn-textblock" {
+					if _, ok := dynFuncs[inp.DynamicFunc]; !ok {
+						invalid = append(invalid, unknownFunc(s, "dynamic", inp.DynamicFunc, DynFuncs()))
+					}
+				}
+			}
+			if cntrComposite > 0 && cntrOther > 0 {
+				invalid = append(invalid, fmt.Sprintf("Page %v - Group %v: composite inputs must not be mixed with other inputs", i1, i2))
+			}
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("%v invalid funcs:\n%v", len(invalid), strings.Join(invalid, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package qst
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+
+	metas := []FuncMetaT{
+		{Name: "PersonalLink"},
+		{Name: "PoliticalFoundations"},
+		{Name: "PoliticalFoundationsPretext"},
+		{Name: "RepsonseStatistics"},
+	}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"PoliticalFoundatons", []string{"PoliticalFoundations"}},
+		{"Political", []string{"PoliticalFoundations", "PoliticalFoundationsPretext"}},
+		{"personallink", []string{"PersonalLink"}},
+		{"ResponseStatistics", []string{"RepsonseStatistics"}},
+		{"Statistics", []string{"RepsonseStatistics"}},
+		{"Unrelated", []string{}},
+	}
+	for _, tc := range tests {
+		if got := suggest(tc.name, metas); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v: got %v - want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestValidateFuncNames(t *testing.T) {
+
+	q := &QuestionnaireT{}
+	gr := q.AddPage().AddGroup()
+	inp := gr.AddInput()
+	inp.Type = "dyn-textblock"
+	inp.DynamicFunc = "PersonalLink"
+	if err := q.validateFuncNames(); err != nil {
+		t.Fatalf("registered func: %v", err)
+	}
+
+	inp.DynamicFunc = "PersonalLnk"
+	inp2 := q.Pages[0].AddGroup().AddInput()
+	inp2.Type = "dyn-composite"
+	inp2.DynamicFunc = "NoSuchComposite__0__0"
+	err := q.validateFuncNames()
+	if err == nil {
+		t.Fatal("unregistered funcs must be reported")
+	}
+	for _, want := range []string{"2 invalid funcs", "'PersonalLnk' is not registered - did you mean 'PersonalLink'?", "composite func 'NoSuchComposite'"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error lacks %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestValidateFuncNamesMalformed(t *testing.T) {
+
+	tests := []struct {
+		dynFunc string
+		mixed   bool
+		want    string
+	}{
+		{"Foo", false, "must be func name '__' sequence idx '__' param set idx"},
+		{"Foo__0", false, "must be func name '__' sequence idx '__' param set idx"},
+		{"PoliticalFoundations__x__0", false, `sequence idx "x" must be a non-negative integer`},
+		{"PoliticalFoundations__0__-1", false, `param set idx "-1" must be a non-negative integer`},
+		{"NoSuchComposite__0__0", false, "composite func 'NoSuchComposite' is not registered"},
+		{"NoSuchComposite__0__0", true, "composite inputs must not be mixed with other inputs"},
+	}
+	for _, tc := range tests {
+		q := &QuestionnaireT{}
+		gr := q.AddPage().AddGroup()
+		inp := gr.AddInput()
+		inp.Type = "dyn-composite"
+		inp.DynamicFunc = tc.dynFunc
+		if tc.mixed {
+			gr.AddInput().Type = "text"
+		}
+		// Validate() checks before validateComposite() would panic
+		err := q.validateFuncNames()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%v: want error %q - got %v", tc.dynFunc, tc.want, err)
+		}
+	}
+}
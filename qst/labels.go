@@ -0,0 +1,102 @@
+package qst
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/zew/go-questionnaire/trl"
+)
+
+var htmlTags = regexp.MustCompile(`<[^>]*>`)
+var whiteSpaces = regexp.MustCompile(`\s+`)
+
+// PlainText removes HTML tags, entities and soft hyphens;
+// for labels in exports and codebooks
+func PlainText(s string) string {
+	s = strings.Replace(s, "&shy;", "", -1)
+	s = htmlTags.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	s = strings.Replace(s, "\u00ad", "", -1) // soft hyphen
+	s = whiteSpaces.ReplaceAllString(s, " ")
+	return strings.TrimSpace(s)
+}
+
+// InputLabel returns the label of the first input named name,
+// falling back to its description; layout inputs are skipped.
+// For rows of radios, the label is carried by the first radio.
+func (q *QuestionnaireT) InputLabel(name string) trl.S {
+	desc := trl.S{}
+	for _, p := range q.Pages {
+		for _, gr := range p.Groups {
+			for _, inp := range gr.Inputs {
+				if inp.IsLayout() || inp.Name != name {
+					continue
+				}
+				if !inp.Label.Empty() {
+					return inp.Label
+				}
+				if !inp.Desc.Empty() && desc.Empty() {
+					desc = inp.Desc
+				}
+			}
+		}
+	}
+	return desc
+}
+
+// ValueLabels returns the possible values of radio input name
+// in order of appearance, and their labels.
+//
+// Labels are taken from radioT.Label for old style radio groups.
+// For radio inputs created by the GridBuilder, the labels are taken
+// from the column headers of type label-as-input above.
+func (q *QuestionnaireT) ValueLabels(name string) (vals []string, lbls []trl.S) {
+
+	seen := map[string]bool{}
+	add := func(val string, lbl trl.S) {
+		if seen[val] {
+			return
+		}
+		seen[val] = true
+		vals = append(vals, val)
+		lbls = append(lbls, lbl)
+	}
+
+	for _, p := range q.Pages {
+		for _, gr := range p.Groups {
+
+			// column headers by column position
+			headers := map[int]trl.S{}
+			cols := int(gr.Cols)
+			if cols < 1 {
+				cols = 1
+			}
+			col := 0
+			for _, inp := range gr.Inputs {
+				span := int(inp.ColSpan)
+				if span < 1 {
+					span = 1
+				}
+				if inp.Type == "label-as-input" && !inp.Label.Empty() {
+					headers[col] = inp.Label
+				}
+				if inp.Name == name {
+					for _, rad := range inp.Radios {
+						add(rad.Val, rad.Label)
+					}
+					if inp.Type == "radio" {
+						lbl := headers[col]
+						if lbl == nil {
+							lbl = inp.Label // no column header; the label of the radio describes the value
+						}
+						add(inp.ValueRadio, lbl)
+					}
+				}
+				col = (col + span) % cols
+			}
+
+		}
+	}
+	return
+}
@@ -10,10 +10,13 @@ import (
 	"path"
 	"strings"
 
+	"github.com/zew/go-questionnaire/cfg"
 	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/store"
 )
 
-// Load1 loads a questionnaire from a JSON file.
+// Load1 loads a questionnaire from a JSON file - see package store;
+// templates directly under BasePath() are cached - if cfg cache_templates is set.
 func Load1(fn string) (*QuestionnaireT, error) {
 
 	q := QuestionnaireT{}
@@ -24,7 +27,13 @@ func Load1(fn string) (*QuestionnaireT, error) {
 
 	log.Printf("Trying loading qst from: %v", fn)
 
-	bts, err := cloudio.ReadFile(fn)
+	var bts []byte
+	var err error
+	if path.Dir(fn) == BasePath() && cfg.Get() != nil && cfg.Get().CacheTemplates {
+		bts, err = store.ReadCached(fn) // template
+	} else {
+		bts, err = store.Get().Read(fn)
+	}
 	if err != nil {
 		// log.Printf("Could not read file: %v", err)
 		return &q, err
@@ -46,8 +55,30 @@ func Load1(fn string) (*QuestionnaireT, error) {
 	return &q, nil
 }
 
-// Save1 a questionnaire to JSON
+// ErrConflict is returned by Save1(),
+// if the file was saved by someone else since q was loaded;
+// i.e. from another browser window
+var ErrConflict = cloudio.ErrConflict
+
+// Save1 a questionnaire to JSON;
+// increments q.Revision - unless the stored revision differs from q.Revision;
+// then nothing is written and ErrConflict is returned.
 func (q *QuestionnaireT) Save1(fn string) error {
+	return q.save(fn, true)
+}
+
+// Save1Unconditionally overwrites the JSON file retaining q.Revision;
+// for templates and for local copies of questionnaires from remote.
+func (q *QuestionnaireT) Save1Unconditionally(fn string) error {
+	return q.save(fn, false)
+}
+
+func (q *QuestionnaireT) save(fn string, conditional bool) error {
+
+	prevRevision := q.Revision
+	if conditional {
+		q.Revision++
+	}
 
 	q.MD5 = "md5dummy"
 
@@ -96,8 +127,28 @@ func (q *QuestionnaireT) Save1(fn string) error {
 	// 	}
 	// }
 
-	err = cloudio.WriteFile(pthOld, bytes.NewReader(bts), 0644)
+	if !conditional {
+		err = store.Get().Write(pthOld, bts)
+	} else {
+		err = store.Get().WriteIf(pthOld, bts, func(current []byte) error {
+			if current == nil {
+				return nil
+			}
+			stored := struct {
+				Revision int `json:"revision"`
+			}{}
+			if err := json.Unmarshal(current, &stored); err != nil {
+				return err
+			}
+			if stored.Revision != prevRevision {
+				log.Printf("%v has revision %v - questionnaire was loaded at %v", pthOld, stored.Revision, prevRevision)
+				return ErrConflict
+			}
+			return nil
+		})
+	}
 	if err != nil {
+		q.Revision = prevRevision
 		return err
 	}
 	log.Printf("Saved questionnaire file to %v", pthOld)
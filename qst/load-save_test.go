@@ -0,0 +1,138 @@
+package qst
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zew/go-questionnaire/cloudio"
+)
+
+func TestSave1Conflict(t *testing.T) {
+
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+
+	fn := "responses/fmt/2020-05/10001"
+
+	q := &QuestionnaireT{UserID: "10001"}
+	if err := q.Save1(fn); err != nil {
+		t.Fatal(err)
+	}
+
+	// two windows
+	qa, err := Load1(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qb, err := Load1(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := qa.Save1(fn); err != nil {
+		t.Fatalf("first save: %v", err)
+	}
+	if err := qb.Save1(fn); err != ErrConflict {
+		t.Errorf("second save: want ErrConflict - got %v", err)
+	}
+	if qb.Revision != 1 {
+		t.Errorf("revision must remain unchanged on conflict - got %v", qb.Revision)
+	}
+
+	// copies retain their revision
+	if err := qb.Save1Unconditionally(fn); err != nil {
+		t.Fatal(err)
+	}
+	qc, err := Load1(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qc.Revision != 1 {
+		t.Errorf("want revision 1 - got %v", qc.Revision)
+	}
+}
+
+func TestAppendChanges(t *testing.T) {
+
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+
+	pth := "responses/fmt/2020-05/10001.json"
+	if got, want := ChangeLogPath(pth), "responses/fmt/2020-05/changelog/10001.ndjson"; got != want {
+		t.Errorf("got %v - want %v", got, want)
+	}
+
+	err := AppendChanges(pth, []ChangeT{{Name: "q1", Old: "", New: "1"}, {Name: "q2", Old: "", New: "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = AppendChanges(pth, []ChangeT{{Name: "q1", Old: "1", New: "2", Page: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := ReadChanges(ChangeLogPath(pth))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("want 3 changes - got %v", len(changes))
+	}
+	if chg := changes[2]; chg.Name != "q1" || chg.Old != "1" || chg.New != "2" || chg.Page != 1 {
+		t.Errorf("last change: %+v", chg)
+	}
+}
+
+func TestTemplateVersions(t *testing.T) {
+
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+
+	q := &QuestionnaireT{Survey: surveyT{Type: "fmt"}, LangCodes: []string{"en"}}
+	h1, err := q.PublishTemplate("fmt", "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Survey.Variant = "changed"
+	h2, err := q.PublishTemplate("fmt", "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h1 == "" || h1 == h2 {
+		t.Fatalf("hashes must be set and differ: %q %q", h1, h2)
+	}
+	if _, err := q.PublishTemplate("fmt", "unchanged"); err != nil {
+		t.Fatal(err)
+	}
+
+	vs, err := TemplateVersions("fmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vs) != 2 || vs[0].Hash != h1 || vs[1].Hash != h2 {
+		t.Fatalf("unexpected versions %+v", vs)
+	}
+
+	if err := RollbackTemplate("fmt", h1); err != nil {
+		t.Fatal(err)
+	}
+	cur, err := CurrentTemplateHash("fmt")
+	if err != nil || cur != h1 {
+		t.Errorf("after rollback: got %q %v - want %q", cur, err, h1)
+	}
+	vs, _ = TemplateVersions("fmt")
+	if len(vs) != 3 || vs[2].Note != "rollback" {
+		t.Errorf("rollback not recorded: %+v", vs)
+	}
+	old, err := LoadTemplateVersion("fmt", h2)
+	if err != nil || old.Survey.Variant != "changed" {
+		t.Errorf("version %v: %v %v", h2, old, err)
+	}
+
+	q.RecordTemplateVersion(h1)
+	q.RecordTemplateVersion(h1)
+	q.RecordTemplateVersion(h2)
+	if strings.Join(q.TemplateVersions, ",") != h1+","+h2 {
+		t.Errorf("recorded versions %v", q.TemplateVersions)
+	}
+}
@@ -37,7 +37,7 @@ func (q *QuestionnaireT) MenuMobile() string {
 
 	for idx, p := range q.Pages {
 
-		if p.NoNavigation {
+		if p.NoNavigation || !q.IsPageVisible(idx) {
 			continue
 		}
 
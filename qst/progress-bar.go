@@ -43,7 +43,7 @@ func (q *QuestionnaireT) ProgressBar() string {
 
 	for idx, p := range q.Pages {
 
-		if p.NoNavigation {
+		if p.NoNavigation || !q.IsPageVisible(idx) {
 			continue
 		}
 
@@ -131,6 +131,9 @@ func (q QuestionnaireT) GroupHTMLGridBased(pageIdx, grpIdx int) string {
 		if inp.Type == "dyn-composite" {
 			continue
 		}
+		if !q.ConditionTrue(inp.ShowIf) {
+			continue
+		}
 
 		inp.Style = css.NewStylesResponsive(inp.Style)
 
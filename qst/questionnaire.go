@@ -126,6 +126,8 @@ type inputT struct {
 	'composit' =>    first arg paramSetIdx, second arg seqIdx */
 	DynamicFunc string `json:"dynamic_func,omitempty"`
 
+	ShowIf string `json:"show_if,omitempty"` // condition, i.e. "q3 == 2 and attr country in [DE,AT]" - see condition.go
+
 	Style    *css.StylesResponsive `json:"style,omitempty"` // pointer, to avoid empty JSON blocks
 	StyleLbl *css.StylesResponsive `json:"style_label,omitempty"`
 	StyleCtl *css.StylesResponsive `json:"style_control,omitempty"`
@@ -231,6 +233,8 @@ type groupT struct {
 	Inputs             []*inputT `json:"inputs,omitempty"`
 	RandomizationGroup int       `json:"randomization_group,omitempty"` // > 0 => group can be repositioned for randomization
 
+	ShowIf string `json:"show_if,omitempty"` // condition - group is only shown, if true - see condition.go
+
 	Style *css.StylesResponsive `json:"style,omitempty"` // pointer, to avoid empty JSON blocks
 }
 
@@ -288,63 +292,20 @@ func (gr groupT) HasComposit() bool {
 	return hasComposit
 }
 
-// returns the func, the sequence idx, the param set idx
+// returns the func, the sequence idx, the param set idx;
+// panics for invalid names - Validate() reports them as errors
 func validateComposite(
 	pageIdx, grpIdx int, compFuncNameWithParamSet string) (CompositFuncT, int, int) {
 
-	splt := strings.Split(compFuncNameWithParamSet, "__")
-	if len(splt) != 3 {
-		log.Panicf(
-			`page %v group %v: 
-			composite func name %v 
-			must consist of func name '__' param set index '__' sequence idx`,
-			pageIdx,
-			grpIdx,
-			compFuncNameWithParamSet,
-		)
-	}
-
-	compFuncName := splt[0]
-	cF, ok := CompositeFuncs[compFuncName]
-	if !ok {
-		log.Panicf(
-			`page %v group %v: 
-			composite func name %v does not exist`,
-			pageIdx,
-			grpIdx,
-			compFuncName,
-		)
-	}
-
-	seqIdx, err := strconv.Atoi(splt[1])
+	name, seqIdx, paramSetIdx, err := parseComposite(compFuncNameWithParamSet)
 	if err != nil {
-		log.Panicf(
-			`page %v group %v: 
-			third part of composite func name %v 
-			could not be parsed into int
-			%v`,
-			seqIdx,
-			grpIdx,
-			compFuncNameWithParamSet,
-			err,
-		)
+		log.Panicf("page %v group %v: %v", pageIdx, grpIdx, err)
 	}
-
-	paramSetIdx, err := strconv.Atoi(splt[2])
-	if err != nil {
-		log.Panicf(
-			`page %v group %v: 
-			second part of composite func name %v 
-			could not be parsed into int
-			%v`,
-			pageIdx,
-			grpIdx,
-			compFuncNameWithParamSet,
-			err,
-		)
+	entry, ok := composites[name]
+	if !ok {
+		log.Panicf("page %v group %v: composite func name %v does not exist", pageIdx, grpIdx, name)
 	}
-
-	return cF, seqIdx, paramSetIdx
+	return entry.fn, seqIdx, paramSetIdx
 
 }
 
@@ -355,12 +316,13 @@ func (q QuestionnaireT) GroupHTMLTableBased(pageIdx, grpIdx int) string {
 
 // Type page contains groups with inputs
 type pageT struct {
-	Section         trl.S `json:"section,omitempty"`       // extra strong before label in content - summary headline for multiple pages
-	Label           trl.S `json:"label,omitempty"`         // headline, set to "" to prevent rendering
-	Desc            trl.S `json:"description,omitempty"`   // abstract
-	Short           trl.S `json:"short,omitempty"`         // sort version of section/label/description - in progress bar and navigation menu
-	NoNavigation    bool  `json:"no_navigation,omitempty"` // Page will not show up in progress bar
-	NavigationalNum int   `json:"navi_num"`                // The number in Navigation order; based on NoNavigation; computed by q.Validate
+	Section         trl.S  `json:"section,omitempty"`       // extra strong before label in content - summary headline for multiple pages
+	Label           trl.S  `json:"label,omitempty"`         // headline, set to "" to prevent rendering
+	Desc            trl.S  `json:"description,omitempty"`   // abstract
+	Short           trl.S  `json:"short,omitempty"`         // sort version of section/label/description - in progress bar and navigation menu
+	NoNavigation    bool   `json:"no_navigation,omitempty"` // Page will not show up in progress bar
+	NavigationalNum int    `json:"navi_num"`                // The number in Navigation order; based on NoNavigation; computed by q.Validate
+	ShowIf          string `json:"show_if,omitempty"`       // condition - page is skipped in navigation, if false - see condition.go
 
 	Style *css.StylesResponsive `json:"style,omitempty"`
 
@@ -390,8 +352,13 @@ type QuestionnaireT struct {
 	ClosingTime time.Time         `json:"closing_time,omitempty"` // truncated to second
 	RemoteIP    string            `json:"remote_ip,omitempty"`
 	UserAgent   string            `json:"user_agent,omitempty"`
-	Mobile      int               `json:"mobile,omitempty"` // 0 - no preference, 1 - desktop, 2 - mobile
+	Mobile      int               `json:"mobile,omitempty"` // 0 - unknown, 1 - desktop, 2 - mobile; URL parameter mobile - or the user agent
 	MD5         string            `json:"md_5,omitempty"`
+	Revision    int               `json:"revision,omitempty"` // incremented by each Save1() - detecting concurrent changes
+
+	// TemplateVersions are the hashes of the template versions the responses were based on;
+	// the first one is the version the participant started with; see template-versions.go
+	TemplateVersions []string `json:"template_versions,omitempty"`
 
 	LangCodes []string `json:"lang_codes,omitempty"` // default, order and availability - [en, de, ...] or [de, en, ...]
 	LangCode  string   `json:"lang_code,omitempty"`  // current lang code - i.e. 'de' - session key lang_code
@@ -735,6 +702,9 @@ func (q *QuestionnaireT) PageHTML(pageIdx int) (string, error) {
 	compositCntr := -1
 	nonCompositCntr := -1
 	for loopIdx, grpIdx := range grpOrder {
+		if !q.IsGroupVisible(pageIdx, grpIdx) {
+			continue
+		}
 		if page.Groups[grpIdx].HasComposit() {
 			compositCntr++
 			compFuncNameWithParamSet := page.Groups[grpIdx].Inputs[0].DynamicFunc
@@ -812,17 +782,22 @@ func (q *QuestionnaireT) PageHTML(pageIdx int) (string, error) {
 	return ret, nil
 }
 
+// inNavi - page is navigable and its ShowIf condition holds
+func (q *QuestionnaireT) inNavi(pageIdx int) bool {
+	return !q.Pages[pageIdx].NoNavigation && q.IsPageVisible(pageIdx)
+}
+
 // next page to be shown in navigation
 func (q *QuestionnaireT) nextInNavi() (int, bool) {
 	// Find next page in navigation
 	for i := q.CurrPage + 1; i < len(q.Pages); i++ {
-		if !q.Pages[i].NoNavigation {
+		if q.inNavi(i) {
 			return i, true
 		}
 	}
 	// Fallback: Last page in navigation
 	for i := len(q.Pages) - 1; i >= 0; i-- {
-		if !q.Pages[i].NoNavigation {
+		if q.inNavi(i) {
 			return i, false
 		}
 	}
@@ -833,13 +808,13 @@ func (q *QuestionnaireT) nextInNavi() (int, bool) {
 func (q *QuestionnaireT) prevInNavi() (int, bool) {
 	// Find prev page in navigation
 	for i := q.CurrPage - 1; i >= 0; i-- {
-		if !q.Pages[i].NoNavigation {
+		if q.inNavi(i) {
 			return i, true
 		}
 	}
 	// Fallback: First page in navigation
 	for i := 0; i < len(q.Pages); i++ {
-		if !q.Pages[i].NoNavigation {
+		if q.inNavi(i) {
 			return i, false
 		}
 	}
@@ -2,6 +2,7 @@ package qst
 
 import (
 	"fmt"
+	"log"
 )
 
 // Split creates a copy of q containing only the user responses
@@ -68,7 +69,36 @@ func (q *QuestionnaireT) Join(q2 *QuestionnaireT) error {
 		}
 	}
 
+	q.joinMeta(q2)
+
+	for i1 := 0; i1 < len(q.Pages); i1++ {
+		q.Pages[i1].Finished = q2.Pages[i1].Finished
+		// log.Printf("\tSetting q.Pages[%v].Finished to %v", i1, q2.Pages[i1].Finished)
+		for i2 := 0; i2 < len(q.Pages[i1].Groups); i2++ {
+			for i3 := 0; i3 < len(q.Pages[i1].Groups[i2].Inputs); i3++ {
+				// log.Printf("adding p%02v  gr%02v  inp%02v", i1, i2, i3)
+				inp := q.Pages[i1].Groups[i2].Inputs[i3]
+				if inp.IsLayout() {
+					continue
+				}
+				inp2 := q2.Pages[i1].Groups[i2].Inputs[i3]
+				q.Pages[i1].Groups[i2].Inputs[i3].ErrMsg = inp2.ErrMsg
+				q.Pages[i1].Groups[i2].Inputs[i3].Response = inp2.Response
+			}
+		}
+	}
+
+	return nil
+}
+
+// joinMeta copies the participant metadata from q2 onto q;
+// the hash of template q is recorded as template version
+func (q *QuestionnaireT) joinMeta(q2 *QuestionnaireT) {
+	templateHash := q.MD5
+	q.TemplateVersions = append([]string{}, q2.TemplateVersions...)
+	q.RecordTemplateVersion(templateHash)
 	q.CurrPage = q2.CurrPage
+	q.Revision = q2.Revision
 	q.UserID = q2.UserID
 	q.ClosingTime = q2.ClosingTime
 	q.RemoteIP = q2.RemoteIP
@@ -81,23 +111,70 @@ func (q *QuestionnaireT) Join(q2 *QuestionnaireT) error {
 		attrs[k] = v
 	}
 	q.Attrs = attrs
+}
+
+// JoinByName adds user input from q2 onto q - matching inputs by name
+// instead of position; for a q2 based on a different version of the template.
+// Page finishing times are matched by position.
+// Names of non-empty responses without counterpart in q are returned.
+func (q *QuestionnaireT) JoinByName(q2 *QuestionnaireT) (lost []string) {
+
+	q.joinMeta(q2)
+	if q.CurrPage > len(q.Pages)-1 {
+		q.CurrPage = len(q.Pages) - 1
+	}
+
+	responses := map[string]string{}
+	names := []string{} // keeping order for reporting
+	for i1 := 0; i1 < len(q2.Pages); i1++ {
+		if i1 < len(q.Pages) {
+			q.Pages[i1].Finished = q2.Pages[i1].Finished
+		}
+		for i2 := 0; i2 < len(q2.Pages[i1].Groups); i2++ {
+			for _, inp2 := range q2.Pages[i1].Groups[i2].Inputs {
+				if inp2.IsLayout() || inp2.Response == "" {
+					continue
+				}
+				if _, ok := responses[inp2.Name]; ok {
+					continue // scattered radio inputs share name and response
+				}
+				responses[inp2.Name] = inp2.Response
+				names = append(names, inp2.Name)
+			}
+		}
+	}
 
+	carried := map[string]bool{}
 	for i1 := 0; i1 < len(q.Pages); i1++ {
-		q.Pages[i1].Finished = q2.Pages[i1].Finished
-		// log.Printf("\tSetting q.Pages[%v].Finished to %v", i1, q2.Pages[i1].Finished)
 		for i2 := 0; i2 < len(q.Pages[i1].Groups); i2++ {
-			for i3 := 0; i3 < len(q.Pages[i1].Groups[i2].Inputs); i3++ {
-				// log.Printf("adding p%02v  gr%02v  inp%02v", i1, i2, i3)
-				inp := q.Pages[i1].Groups[i2].Inputs[i3]
+			for _, inp := range q.Pages[i1].Groups[i2].Inputs {
 				if inp.IsLayout() {
 					continue
 				}
-				inp2 := q2.Pages[i1].Groups[i2].Inputs[i3]
-				q.Pages[i1].Groups[i2].Inputs[i3].ErrMsg = inp2.ErrMsg
-				q.Pages[i1].Groups[i2].Inputs[i3].Response = inp2.Response
+				if resp, ok := responses[inp.Name]; ok {
+					inp.Response = resp
+					carried[inp.Name] = true
+				}
 			}
 		}
 	}
 
-	return nil
+	for _, name := range names {
+		if !carried[name] {
+			lost = append(lost, fmt.Sprintf("%v=%v", name, responses[name]))
+		}
+	}
+	return
+}
+
+// Migrate adds user input from q2 onto template q;
+// if Join() fails due to differing structures, JoinByName() is used.
+// Responses which could not be carried over are returned as name=value.
+func (q *QuestionnaireT) Migrate(q2 *QuestionnaireT) (byName bool, lost []string) {
+	err := q.Join(q2) // checks all structure before changing anything
+	if err == nil {
+		return false, nil
+	}
+	log.Printf("Join failed - joining by name: %v", err)
+	return true, q.JoinByName(q2)
 }
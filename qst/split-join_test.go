@@ -0,0 +1,66 @@
+package qst
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+
+	// previous template version, filled out
+	q2 := &QuestionnaireT{UserID: "10001", CurrPage: 1}
+	gr := q2.AddPage().AddGroup()
+	for _, nm := range []string{"q1", "q2", "q3"} {
+		inp := gr.AddInput()
+		inp.Name = nm
+		inp.Type = "text"
+		inp.Response = "resp-" + nm
+	}
+	q2.AddPage().AddGroup()
+
+	// unchanged structure
+	q := &QuestionnaireT{}
+	gr = q.AddPage().AddGroup()
+	for _, nm := range []string{"q1", "q2", "q3"} {
+		inp := gr.AddInput()
+		inp.Name = nm
+		inp.Type = "text"
+	}
+	q.AddPage().AddGroup()
+	byName, lost := q.Migrate(q2)
+	if byName || len(lost) > 0 {
+		t.Errorf("unchanged structure: got byName %v, lost %v", byName, lost)
+	}
+
+	// new template version: q2 removed, q3 moved to a new group, q4 added;
+	// only one page
+	q = &QuestionnaireT{}
+	p := q.AddPage()
+	gr = p.AddGroup()
+	inp := gr.AddInput()
+	inp.Name = "q1"
+	inp.Type = "text"
+	gr = p.AddGroup()
+	for _, nm := range []string{"q4", "q3"} {
+		inp := gr.AddInput()
+		inp.Name = nm
+		inp.Type = "text"
+	}
+
+	byName, lost = q.Migrate(q2)
+	if !byName {
+		t.Errorf("changed structure: want join by name")
+	}
+	if want := []string{"q2=resp-q2"}; !reflect.DeepEqual(lost, want) {
+		t.Errorf("lost: got %v, want %v", lost, want)
+	}
+	want := map[string]string{"q1": "resp-q1", "q3": "resp-q3", "q4": ""}
+	for nm, resp := range want {
+		if got := q.ByName(nm).Response; got != resp {
+			t.Errorf("%v: got %q, want %q", nm, got, resp)
+		}
+	}
+	if q.UserID != "10001" || q.CurrPage != 0 {
+		t.Errorf("metadata: got user %v, page %v", q.UserID, q.CurrPage)
+	}
+}
@@ -93,6 +93,7 @@ func (q *QuestionnaireT) TranslationCompleteness() error {
 // 		submit button jump page exists
 // 		validator func exists?
 // 		input names uniqueness?
+// 		show_if conditions valid - no unknown inputs, radio values, forward references?
 //
 // Validate also does some initialization stuff - needed only at JSON creation time
 //		Setting page and group width to 100
@@ -106,6 +107,11 @@ func (q *QuestionnaireT) Validate() error {
 		return fmt.Errorf(s)
 	}
 
+	if len(q.LangCodes) < 1 {
+		s := "LangCodes must contain at least one language code such as 'en' or 'de'"
+		log.Printf(s)
+		return fmt.Errorf(s)
+	}
 	for _, lc := range q.LangCodes {
 		if _, ok := cfg.Get().Mp["lang_"+lc]; !ok {
 			s := fmt.Sprintf("LangCodes val %v is not a key in cfg.Get().Mp['lang_...']", lc)
@@ -227,6 +233,11 @@ func (q *QuestionnaireT) Validate() error {
 		}
 	}
 
+	// composite and dynamic funcs registered?
+	if err := q.validateFuncNames(); err != nil {
+		return err
+	}
+
 	// preflight for composite funcs
 	// make sure, input names are unique
 	names := map[string]int{}
@@ -237,7 +248,7 @@ func (q *QuestionnaireT) Validate() error {
 				compFuncNameWithParamSet := q.Pages[i1].Groups[i2].Inputs[0].DynamicFunc
 				cF, seqIdx, paramSetIdx := validateComposite(i1, i2, compFuncNameWithParamSet)
 				// log.Printf("checking composite func '%v' for page %v, group %v", compFuncNameWithParamSet, i1, i2)
-				_, _, err := cF(q, seqIdx, paramSetIdx)
+				_, _, err := preflightComposite(cF, q, seqIdx, paramSetIdx)
 				if err != nil {
 					return fmt.Errorf(
 						`Page %v - Group %v - Composit func %v
@@ -296,6 +307,137 @@ func (q *QuestionnaireT) Validate() error {
 			return fmt.Errorf(s)
 		}
 	}
+
+	if err := q.validateConditions(); err != nil {
+		log.Print(err)
+		return err
+	}
+
+	return nil
+}
+
+// inputPositions returns the page index of every named input
+// and the possible values of every radio input;
+// radio values from ValueRadio and from the Radios slice
+func (q *QuestionnaireT) inputPositions() (pages map[string]int, radioVals map[string]map[string]bool) {
+	pages = map[string]int{}
+	radioVals = map[string]map[string]bool{}
+	for i1 := 0; i1 < len(q.Pages); i1++ {
+		for i2 := 0; i2 < len(q.Pages[i1].Groups); i2++ {
+			for i3 := 0; i3 < len(q.Pages[i1].Groups[i2].Inputs); i3++ {
+				inp := q.Pages[i1].Groups[i2].Inputs[i3]
+				if inp.IsLayout() || inp.Name == "" {
+					continue
+				}
+				if _, ok := pages[inp.Name]; !ok {
+					pages[inp.Name] = i1
+				}
+				if inp.Type == "radio" {
+					if radioVals[inp.Name] == nil {
+						radioVals[inp.Name] = map[string]bool{}
+					}
+					radioVals[inp.Name][inp.ValueRadio] = true
+				}
+				for _, rad := range inp.Radios {
+					if radioVals[inp.Name] == nil {
+						radioVals[inp.Name] = map[string]bool{}
+					}
+					radioVals[inp.Name][rad.Val] = true
+				}
+			}
+		}
+	}
+	return
+}
+
+// hasRadioVal compares as the evaluation does - see compareVals();
+// i.e. condition value 2.0 matches radio value 2
+func hasRadioVal(vals map[string]bool, v string) bool {
+	for rv := range vals {
+		if compareVals(rv, v) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCondition tests a single condition
+//		syntax
+//		referenced inputs exist
+//		compared radio values exist
+//		referenced inputs are not on a later page;
+//		page conditions must reference previous pages only
+//		input conditions must not reference the input itself
+func (q *QuestionnaireT) checkCondition(s, cond string, pageIdx int, isPageCond bool, self string, pages map[string]int, radioVals map[string]map[string]bool) error {
+
+	if strings.TrimSpace(cond) == "" {
+		return nil
+	}
+
+	c, err := parseCondition(cond)
+	if err != nil {
+		return fmt.Errorf("%v%v", s, err)
+	}
+
+	for _, cmp := range c.comparisons() {
+		if cmp.IsAttr {
+			continue
+		}
+		if self != "" && cmp.Key == self {
+			return fmt.Errorf("%vcondition %q references the input itself", s, cond)
+		}
+		if q.ByName(cmp.Key) == nil {
+			return fmt.Errorf("%vcondition %q references input '%v' - which does not exist", s, cond, cmp.Key)
+		}
+		refPage := pages[cmp.Key]
+		if refPage > pageIdx {
+			return fmt.Errorf("%vcondition %q references input '%v' on later page %v", s, cond, cmp.Key, refPage)
+		}
+		if isPageCond && refPage == pageIdx {
+			return fmt.Errorf("%vpage condition %q references input '%v' on the same page", s, cond, cmp.Key)
+		}
+		if vals, ok := radioVals[cmp.Key]; ok {
+			switch cmp.Op {
+			case "==", "!=", "in", "notin":
+				for _, v := range cmp.Vals {
+					if v == "" || v == valEmpty {
+						continue // no radio selected
+					}
+					if !hasRadioVal(vals, v) {
+						return fmt.Errorf("%vcondition %q compares radio '%v' to value '%v' - which does not exist", s, cond, cmp.Key, v)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateConditions checks all ShowIf conditions
+// of pages, groups and inputs
+func (q *QuestionnaireT) validateConditions() error {
+
+	pages, radioVals := q.inputPositions()
+
+	for i1 := 0; i1 < len(q.Pages); i1++ {
+		s := fmt.Sprintf("Page %v: ", i1)
+		if err := q.checkCondition(s, q.Pages[i1].ShowIf, i1, true, "", pages, radioVals); err != nil {
+			return err
+		}
+		for i2 := 0; i2 < len(q.Pages[i1].Groups); i2++ {
+			s := fmt.Sprintf("Page %v - Group %v: ", i1, i2)
+			if err := q.checkCondition(s, q.Pages[i1].Groups[i2].ShowIf, i1, false, "", pages, radioVals); err != nil {
+				return err
+			}
+			for i3 := 0; i3 < len(q.Pages[i1].Groups[i2].Inputs); i3++ {
+				inp := q.Pages[i1].Groups[i2].Inputs[i3]
+				s := fmt.Sprintf("Page %v - Group %v - Input %v - %8v: ", i1, i2, i3, inp.Name)
+				if err := q.checkCondition(s, inp.ShowIf, i1, false, inp.Name, pages, radioVals); err != nil {
+					return err
+				}
+			}
+		}
+	}
 	return nil
 }
 
@@ -310,10 +452,11 @@ func (q *QuestionnaireT) ComputeDynamicContent(idx int) error {
 			for i3 := 0; i3 < len(q.Pages[i1].Groups[i2].Inputs); i3++ {
 				if q.Pages[i1].Groups[i2].Inputs[i3].Type == "dyn-textblock" {
 					inp := q.Pages[i1].Groups[i2].Inputs[i3]
-					if _, ok := dynFuncs[inp.DynamicFunc]; !ok {
+					entry, ok := dynFuncs[inp.DynamicFunc]
+					if !ok {
 						return fmt.Errorf("'%v' points to dynamic func '%v()' - which does not exist or is not registered", inp.Name, inp.DynamicFunc)
 					}
-					str, err := dynFuncs[inp.DynamicFunc](q)
+					str, err := entry.fn(q)
 					if err != nil {
 						return fmt.Errorf("'%v' points to dynamic func '%v()' - which returned error %v", inp.Name, inp.DynamicFunc, err)
 					}
@@ -0,0 +1,140 @@
+package qst
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/zew/go-questionnaire/trl"
+)
+
+// TemplateDiffT is a difference between two template versions
+type TemplateDiffT struct {
+	Kind    string `json:"kind"`    // added, removed, changed
+	Page    int    `json:"page"`    // page index - in the newer version for added and changed
+	Element string `json:"element"` // page label or input key - see inputKey()
+	Field   string `json:"field,omitempty"`
+	Old     string `json:"old,omitempty"`
+	New     string `json:"new,omitempty"`
+}
+
+func (d TemplateDiffT) String() string {
+	if d.Field == "" {
+		return fmt.Sprintf("page %2v: %-8v %v", d.Page, d.Kind, d.Element)
+	}
+	return fmt.Sprintf("page %2v: %-8v %v %v: %q => %q", d.Page, d.Kind, d.Element, d.Field, d.Old, d.New)
+}
+
+// diffTrl compares the translations of a field;
+// languages are sorted for stable output
+func diffTrl(diffs []TemplateDiffT, page int, element, field string, a, b trl.S) []TemplateDiffT {
+	langs := map[string]bool{}
+	for lc := range a {
+		langs[lc] = true
+	}
+	for lc := range b {
+		langs[lc] = true
+	}
+	lcs := make([]string, 0, len(langs))
+	for lc := range langs {
+		lcs = append(lcs, lc)
+	}
+	sort.Strings(lcs)
+	for _, lc := range lcs {
+		if a[lc] != b[lc] {
+			diffs = append(diffs, TemplateDiffT{
+				Kind: "changed", Page: page, Element: element,
+				Field: field + "." + lc, Old: a[lc], New: b[lc],
+			})
+		}
+	}
+	return diffs
+}
+
+// inputKey identifies an input across versions;
+// named inputs by name - radios additionally by value;
+// unnamed layout inputs - text blocks - by position.
+func inputKey(inp *inputT, pageIdx, grIdx, inpIdx int) string {
+	if inp.Name == "" {
+		return fmt.Sprintf("p%v-g%v-i%v(%v)", pageIdx, grIdx, inpIdx, inp.Type)
+	}
+	if inp.Type == "radio" {
+		return inp.Name + "=" + inp.ValueRadio
+	}
+	return inp.Name
+}
+
+type keyedInputT struct {
+	key  string
+	page int
+	inp  *inputT
+}
+
+func keyedInputs(q *QuestionnaireT) ([]keyedInputT, map[string]keyedInputT) {
+	lst := []keyedInputT{}
+	mp := map[string]keyedInputT{}
+	for i1, p := range q.Pages {
+		for i2, gr := range p.Groups {
+			for i3, inp := range gr.Inputs {
+				ki := keyedInputT{key: inputKey(inp, i1, i2, i3), page: i1, inp: inp}
+				if _, ok := mp[ki.key]; ok {
+					continue // duplicates are prevented by Validate()
+				}
+				lst = append(lst, ki)
+				mp[ki.key] = ki
+			}
+		}
+	}
+	return lst, mp
+}
+
+// DiffTemplates lists the differences from template version a to version b:
+// page labels, sections and descriptions - by page index;
+// inputs added or removed; changed type, labels, descriptions, suffixes, placeholders.
+// Hyphenization is compared as is - templates of the same generator are hyphenized alike.
+func DiffTemplates(a, b *QuestionnaireT) []TemplateDiffT {
+
+	diffs := []TemplateDiffT{}
+
+	for i := 0; i < len(a.Pages) || i < len(b.Pages); i++ {
+		switch {
+		case i >= len(a.Pages):
+			diffs = append(diffs, TemplateDiffT{Kind: "added", Page: i, Element: fmt.Sprintf("page %v %v", i, b.Pages[i].Label.String())})
+		case i >= len(b.Pages):
+			diffs = append(diffs, TemplateDiffT{Kind: "removed", Page: i, Element: fmt.Sprintf("page %v %v", i, a.Pages[i].Label.String())})
+		default:
+			el := fmt.Sprintf("page %v", i)
+			diffs = diffTrl(diffs, i, el, "section", a.Pages[i].Section, b.Pages[i].Section)
+			diffs = diffTrl(diffs, i, el, "label", a.Pages[i].Label, b.Pages[i].Label)
+			diffs = diffTrl(diffs, i, el, "description", a.Pages[i].Desc, b.Pages[i].Desc)
+		}
+	}
+
+	lstA, mpA := keyedInputs(a)
+	lstB, mpB := keyedInputs(b)
+
+	for _, kb := range lstB {
+		ka, ok := mpA[kb.key]
+		if !ok {
+			diffs = append(diffs, TemplateDiffT{Kind: "added", Page: kb.page, Element: kb.key})
+			continue
+		}
+		if ka.inp.Type != kb.inp.Type {
+			diffs = append(diffs, TemplateDiffT{
+				Kind: "changed", Page: kb.page, Element: kb.key,
+				Field: "type", Old: ka.inp.Type, New: kb.inp.Type,
+			})
+		}
+		diffs = diffTrl(diffs, kb.page, kb.key, "label", ka.inp.Label, kb.inp.Label)
+		diffs = diffTrl(diffs, kb.page, kb.key, "description", ka.inp.Desc, kb.inp.Desc)
+		diffs = diffTrl(diffs, kb.page, kb.key, "suffix", ka.inp.Suffix, kb.inp.Suffix)
+		diffs = diffTrl(diffs, kb.page, kb.key, "placeholder", ka.inp.Placeholder, kb.inp.Placeholder)
+	}
+
+	for _, ka := range lstA {
+		if _, ok := mpB[ka.key]; !ok {
+			diffs = append(diffs, TemplateDiffT{Kind: "removed", Page: ka.page, Element: ka.key})
+		}
+	}
+
+	return diffs
+}
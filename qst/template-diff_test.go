@@ -0,0 +1,47 @@
+package qst
+
+import (
+	"testing"
+
+	"github.com/zew/go-questionnaire/trl"
+)
+
+func TestDiffTemplates(t *testing.T) {
+
+	build := func(label string, names ...string) *QuestionnaireT {
+		q := &QuestionnaireT{}
+		p := q.AddPage()
+		p.Label = trl.S{"en": label}
+		gr := p.AddGroup()
+		for _, name := range names {
+			inp := gr.AddInput()
+			inp.Type = "text"
+			inp.Name = name
+			inp.Label = trl.S{"en": name}
+		}
+		return q
+	}
+
+	a := build("Start", "q1", "q2")
+	b := build("Begin", "q2", "q3")
+	b.Pages[0].Groups[0].Inputs[0].Label = trl.S{"en": "q2 new"}
+
+	got := DiffTemplates(a, b)
+	want := []TemplateDiffT{
+		{Kind: "changed", Page: 0, Element: "page 0", Field: "label.en", Old: "Start", New: "Begin"},
+		{Kind: "changed", Page: 0, Element: "q2", Field: "label.en", Old: "q2", New: "q2 new"},
+		{Kind: "added", Page: 0, Element: "q3"},
+		{Kind: "removed", Page: 0, Element: "q1"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v diffs - want %v\n%v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("diff %v\ngot  %v\nwant %v", i, got[i], want[i])
+		}
+	}
+	if len(DiffTemplates(a, a)) != 0 {
+		t.Errorf("identical templates must not differ")
+	}
+}
@@ -0,0 +1,204 @@
+package qst
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/zew/go-questionnaire/cloudio"
+	"github.com/zew/go-questionnaire/store"
+)
+
+// Every published template is kept as an immutable version
+//
+//	template-versions/[name]/[hash].json
+//
+// identified by its content hash - field MD5, computed by save();
+// index.json lists the versions in order of publication.
+// Name is the survey type - or survey type and variant, i.e. fmt or lt2020-03.
+//
+// Responses record the versions they were based on; see TemplateVersions.
+
+// TemplateVersionT is an entry of the version index
+type TemplateVersionT struct {
+	Hash      string    `json:"hash"`
+	Published time.Time `json:"published"`
+	Note      string    `json:"note,omitempty"` // i.e. generated, uploaded, rollback
+}
+
+// Short is an abbreviation of the hash for display
+func (v TemplateVersionT) Short() string {
+	return ShortHash(v.Hash)
+}
+
+// ShortHash abbreviates a version hash for display
+func ShortHash(hash string) string {
+	if len(hash) > 10 {
+		return hash[:10]
+	}
+	return hash
+}
+
+// versionsMtx serializes modifications of the version index
+var versionsMtx sync.Mutex
+
+// TemplatePath returns the file name of the questionnaire template name
+func TemplatePath(name string) string {
+	return path.Join(BasePath(), name+".json")
+}
+
+// TemplateVersionPath returns the file name of a version of template name
+func TemplateVersionPath(name, hash string) string {
+	return path.Join("template-versions", name, hash+".json")
+}
+
+func templateVersionsIndex(name string) string {
+	return path.Join("template-versions", name, "index.json")
+}
+
+// templateHash returns the content hash of a serialized template;
+// as in Load1(), the hash is computed with md5dummy in place of the MD5 field
+func templateHash(bts []byte) (string, error) {
+	meta := struct {
+		MD5 string `json:"md_5"`
+	}{}
+	if err := json.Unmarshal(bts, &meta); err != nil {
+		return "", err
+	}
+	if meta.MD5 == "" {
+		return md5Str(bts), nil
+	}
+	got := md5Str(bytes.Replace(bts, []byte(meta.MD5), []byte("md5dummy"), 1))
+	if got != meta.MD5 {
+		return "", fmt.Errorf("MD5 hashes differ; want - got\n%v\n%v", meta.MD5, got)
+	}
+	return meta.MD5, nil
+}
+
+// TemplateVersions returns the version index of template name - oldest first
+func TemplateVersions(name string) ([]TemplateVersionT, error) {
+	vs := []TemplateVersionT{}
+	bts, err := store.Get().Read(templateVersionsIndex(name))
+	if err != nil {
+		if cloudio.IsNotExist(err) {
+			return vs, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(bts, &vs)
+	return vs, err
+}
+
+// LoadTemplateVersion loads a version of template name
+func LoadTemplateVersion(name, hash string) (*QuestionnaireT, error) {
+	return Load1(TemplateVersionPath(name, hash))
+}
+
+// CurrentTemplateHash returns the hash of the current template name
+func CurrentTemplateHash(name string) (string, error) {
+	bts, err := store.Get().Read(TemplatePath(name))
+	if err != nil {
+		return "", err
+	}
+	return templateHash(bts)
+}
+
+// archive stores bts as version of template name - unless it exists;
+// versions are only added to the index, if they differ from the latest entry
+func archive(name string, bts []byte, note string) (string, error) {
+
+	hash, err := templateHash(bts)
+	if err != nil {
+		return "", err
+	}
+	pth := TemplateVersionPath(name, hash)
+	if _, err := store.Get().Read(pth); err != nil {
+		if !cloudio.IsNotExist(err) {
+			return "", err
+		}
+		if err := store.Get().Write(pth, bts); err != nil {
+			return "", err
+		}
+	}
+
+	vs, err := TemplateVersions(name)
+	if err != nil {
+		return "", err
+	}
+	if len(vs) > 0 && vs[len(vs)-1].Hash == hash {
+		return hash, nil
+	}
+	vs = append(vs, TemplateVersionT{Hash: hash, Published: time.Now().Truncate(time.Second), Note: note})
+	idx, err := json.MarshalIndent(vs, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	return hash, store.Get().Write(templateVersionsIndex(name), idx)
+}
+
+// archiveCurrent keeps the current template as version -
+// before it is replaced; templates deployed by file copy are thus preserved
+func archiveCurrent(name string) error {
+	bts, err := store.Get().Read(TemplatePath(name))
+	if err != nil {
+		if cloudio.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	_, err = archive(name, bts, "previous")
+	return err
+}
+
+// PublishTemplate saves q as template name - and as new version;
+// the replaced template is kept as version too; returns the hash of q.
+func (q *QuestionnaireT) PublishTemplate(name, note string) (string, error) {
+	versionsMtx.Lock()
+	defer versionsMtx.Unlock()
+	if err := archiveCurrent(name); err != nil {
+		return "", fmt.Errorf("archiving current template %v: %v", name, err)
+	}
+	if err := q.Save1Unconditionally(TemplatePath(name)); err != nil {
+		return "", err
+	}
+	bts, err := store.Get().Read(TemplatePath(name))
+	if err != nil {
+		return "", err
+	}
+	return archive(name, bts, note)
+}
+
+// RollbackTemplate makes version hash the current template name again;
+// the version is copied byte by byte; the rollback is recorded in the index.
+// Responses are migrated on their next load - as for any template change.
+func RollbackTemplate(name, hash string) error {
+	versionsMtx.Lock()
+	defer versionsMtx.Unlock()
+	bts, err := store.Get().Read(TemplateVersionPath(name, hash))
+	if err != nil {
+		return err
+	}
+	if err := archiveCurrent(name); err != nil {
+		return fmt.Errorf("archiving current template %v: %v", name, err)
+	}
+	if err := store.Get().Write(TemplatePath(name), bts); err != nil {
+		return err
+	}
+	_, err = archive(name, bts, "rollback")
+	return err
+}
+
+// RecordTemplateVersion appends hash to the template versions
+// the participant has seen - unless it is the latest one already
+func (q *QuestionnaireT) RecordTemplateVersion(hash string) {
+	if hash == "" {
+		return
+	}
+	if n := len(q.TemplateVersions); n > 0 && q.TemplateVersions[n-1] == hash {
+		return
+	}
+	q.TemplateVersions = append(q.TemplateVersions, hash)
+}
@@ -99,6 +99,12 @@ func (q *QuestionnaireT) ValidateResponseData(pageNum int, langCode string) (las
 				// Check input type
 				inp := q.Pages[i1].Groups[i2].Inputs[i3]
 
+				// Hidden by condition - no validation, no 'must'
+				if !q.IsInputVisible(i1, i2, i3) {
+					q.Pages[i1].Groups[i2].Inputs[i3].ErrMsg = nil
+					continue
+				}
+
 				// Validator function exists
 				if inp.Validator != "" {
 					valiKeys := strings.Split(inp.Validator, ";")
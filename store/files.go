@@ -0,0 +1,75 @@
+package store
+
+import (
+	"bytes"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/zew/go-questionnaire/cloudio"
+)
+
+// FilesT stores JSON files in the cloudio bucket
+type FilesT struct{}
+
+// Read a file
+func (FilesT) Read(key string) ([]byte, error) {
+	return cloudio.ReadFile(key)
+}
+
+func (FilesT) readCached(key string) ([]byte, error) {
+	return cloudio.ReadFileCached(key)
+}
+
+// Write a file
+func (FilesT) Write(key string, bts []byte) error {
+	return cloudio.WriteFile(key, bytes.NewReader(bts), 0644)
+}
+
+// WriteIf writes a file - if check() accepts its current contents
+func (FilesT) WriteIf(key string, bts []byte, check func(current []byte) error) error {
+	return cloudio.WriteFileIf(key, bytes.NewReader(bts), check)
+}
+
+// Delete a file
+func (FilesT) Delete(key string) error {
+	return cloudio.Delete(key)
+}
+
+// List lists the JSON files in dir
+func (FilesT) List(dir string) ([]EntryT, error) {
+	infos, err := cloudio.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := []EntryT{}
+	for _, info := range *infos {
+		if info.IsDir || !strings.HasSuffix(info.Key, ".json") {
+			continue
+		}
+		entries = append(entries, EntryT{Key: info.Key, ModTime: info.ModTime, Size: info.Size})
+	}
+	return entries, nil
+}
+
+// Subdirs lists the directories in dir
+func (FilesT) Subdirs(dir string) ([]string, error) {
+	infos, err := cloudio.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, info := range *infos {
+		if !info.IsDir {
+			continue
+		}
+		names = append(names, path.Base(strings.TrimSuffix(info.Key, "/")))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Close is a no-op; see cloudio.Shutdown()
+func (FilesT) Close() error {
+	return nil
+}
@@ -0,0 +1,248 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zew/go-questionnaire/cloudio"
+
+	_ "modernc.org/sqlite" // pure Go - no cgo
+)
+
+// SQLiteT stores questionnaires as rows keyed by survey, wave and user -
+// the JSON document in column doc;
+// all other keys - templates, logins - go into table documents.
+//
+// Documents not yet in the database are taken over from the bucket on first read;
+// thus an existing logins.json and the templates need no import.
+// Existing responses are copied by Import().
+type SQLiteT struct {
+	db *sql.DB
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS responses (
+	survey_id TEXT    NOT NULL,
+	wave_id   TEXT    NOT NULL,
+	user_id   TEXT    NOT NULL,
+	modified  INTEGER NOT NULL,
+	doc       BLOB    NOT NULL,
+	PRIMARY KEY (survey_id, wave_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS responses_modified ON responses (survey_id, wave_id, modified);
+CREATE TABLE IF NOT EXISTS documents (
+	key      TEXT    NOT NULL PRIMARY KEY,
+	modified INTEGER NOT NULL,
+	doc      BLOB    NOT NULL
+);
+`
+
+// OpenSQLite opens or creates the database file fn;
+// :memory: for a transient database
+func OpenSQLite(fn string) (*SQLiteT, error) {
+	if fn != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(fn), 0750); err != nil {
+			return nil, err
+		}
+	}
+	db, err := sql.Open("sqlite", fn)
+	if err != nil {
+		return nil, err
+	}
+	// one connection serializes all statements of this process;
+	// WriteIf() needs no further locking
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA busy_timeout = 5000"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating schema in %v: %v", fn, err)
+	}
+	return &SQLiteT{db: db}, nil
+}
+
+// responseKey splits responses/[survey]/[wave]/[user].json
+func responseKey(key string) (surveyID, waveID, userID string, ok bool) {
+	els := strings.Split(path.Clean(key), "/")
+	if len(els) != 4 || els[0] != responsesDir || !strings.HasSuffix(els[3], ".json") {
+		return "", "", "", false
+	}
+	return els[1], els[2], strings.TrimSuffix(els[3], ".json"), true
+}
+
+func notExist(key string) error {
+	return &os.PathError{Op: "read", Path: key, Err: os.ErrNotExist}
+}
+
+// queryer is either *sql.DB or *sql.Tx
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func read(q queryer, key string) ([]byte, error) {
+	var bts []byte
+	var err error
+	if s, w, u, ok := responseKey(key); ok {
+		err = q.QueryRow(
+			"SELECT doc FROM responses WHERE survey_id = ? AND wave_id = ? AND user_id = ?", s, w, u,
+		).Scan(&bts)
+	} else {
+		err = q.QueryRow("SELECT doc FROM documents WHERE key = ?", path.Clean(key)).Scan(&bts)
+	}
+	if err == sql.ErrNoRows {
+		return nil, notExist(key)
+	}
+	return bts, err
+}
+
+func write(q queryer, key string, bts []byte) error {
+	now := time.Now().UnixNano()
+	var err error
+	if s, w, u, ok := responseKey(key); ok {
+		_, err = q.Exec(
+			`INSERT INTO responses (survey_id, wave_id, user_id, modified, doc) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (survey_id, wave_id, user_id) DO UPDATE SET modified = excluded.modified, doc = excluded.doc`,
+			s, w, u, now, bts,
+		)
+	} else {
+		_, err = q.Exec(
+			`INSERT INTO documents (key, modified, doc) VALUES (?, ?, ?)
+			ON CONFLICT (key) DO UPDATE SET modified = excluded.modified, doc = excluded.doc`,
+			path.Clean(key), now, bts,
+		)
+	}
+	return err
+}
+
+// Read returns the JSON document of key
+func (s *SQLiteT) Read(key string) ([]byte, error) {
+	bts, err := read(s.db, key)
+	if err == nil {
+		return bts, nil
+	}
+	if _, _, _, isResponse := responseKey(key); isResponse || !cloudio.IsNotExist(err) {
+		return nil, err
+	}
+	// take over from bucket
+	bts, err = cloudio.ReadFile(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := write(s.db, key, bts); err != nil {
+		return nil, err
+	}
+	log.Printf("store: %v taken over from bucket into database", key)
+	return bts, nil
+}
+
+// Write inserts or replaces the JSON document of key
+func (s *SQLiteT) Write(key string, bts []byte) error {
+	return write(s.db, key, bts)
+}
+
+// WriteIf writes within a transaction - if check() accepts the current contents
+func (s *SQLiteT) WriteIf(key string, bts []byte, check func(current []byte) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // no-op after commit
+	current, err := read(tx, key)
+	if err != nil && !cloudio.IsNotExist(err) {
+		return err
+	}
+	if err := check(current); err != nil {
+		return err
+	}
+	if err := write(tx, key, bts); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes key
+func (s *SQLiteT) Delete(key string) error {
+	var res sql.Result
+	var err error
+	if sv, w, u, ok := responseKey(key); ok {
+		res, err = s.db.Exec("DELETE FROM responses WHERE survey_id = ? AND wave_id = ? AND user_id = ?", sv, w, u)
+	} else {
+		res, err = s.db.Exec("DELETE FROM documents WHERE key = ?", path.Clean(key))
+	}
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return notExist(key)
+	}
+	return nil
+}
+
+// List queries the questionnaires of dir - responses/[survey]/[wave] -
+// ordered by user; documents are not read
+func (s *SQLiteT) List(dir string) ([]EntryT, error) {
+	els := strings.Split(path.Clean(dir), "/")
+	if len(els) != 3 || els[0] != responsesDir {
+		return nil, fmt.Errorf("%v is no directory of a survey wave", dir)
+	}
+	rows, err := s.db.Query(
+		`SELECT user_id, modified, length(doc) FROM responses
+		WHERE survey_id = ? AND wave_id = ? ORDER BY user_id`, els[1], els[2],
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []EntryT{}
+	for rows.Next() {
+		var userID string
+		var modified, size int64
+		if err := rows.Scan(&userID, &modified, &size); err != nil {
+			return nil, err
+		}
+		entries = append(entries, EntryT{
+			Key:     path.Join(responsesDir, els[1], els[2], userID+".json"),
+			ModTime: time.Unix(0, modified),
+			Size:    size,
+		})
+	}
+	return entries, rows.Err()
+}
+
+// Subdirs queries the waves of dir - responses/[survey]
+func (s *SQLiteT) Subdirs(dir string) ([]string, error) {
+	els := strings.Split(path.Clean(dir), "/")
+	if len(els) != 2 || els[0] != responsesDir {
+		return nil, fmt.Errorf("%v is no directory of a survey", dir)
+	}
+	rows, err := s.db.Query(
+		"SELECT DISTINCT wave_id FROM responses WHERE survey_id = ? ORDER BY wave_id", els[1],
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	names := []string{}
+	for rows.Next() {
+		var waveID string
+		if err := rows.Scan(&waveID); err != nil {
+			return nil, err
+		}
+		names = append(names, waveID)
+	}
+	return names, rows.Err()
+}
+
+// Close closes the database
+func (s *SQLiteT) Close() error {
+	return s.db.Close()
+}
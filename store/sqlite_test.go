@@ -0,0 +1,124 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zew/go-questionnaire/cloudio"
+)
+
+func TestSQLite(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := OpenSQLite(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	key := "responses/fmt/2020-05/10001.json"
+	if _, err := s.Read(key); !cloudio.IsNotExist(err) {
+		t.Errorf("want not exist error - got %v", err)
+	}
+
+	since := time.Now()
+	if err := s.Write(key, []byte(`{"revision": 1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write("responses/fmt/2020-06/10002.json", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	bts, err := s.Read(key)
+	if err != nil || string(bts) != `{"revision": 1}` {
+		t.Errorf("got %s - %v", bts, err)
+	}
+
+	// conditional write
+	errReject := errors.New("rejected")
+	err = s.WriteIf(key, []byte(`{"revision": 2}`), func(current []byte) error {
+		if !bytes.Equal(current, []byte(`{"revision": 1}`)) {
+			t.Errorf("check got %s", current)
+		}
+		return errReject
+	})
+	if err != errReject {
+		t.Errorf("want rejection - got %v", err)
+	}
+	if bts, _ := s.Read(key); string(bts) != `{"revision": 1}` {
+		t.Errorf("rejected write changed contents to %s", bts)
+	}
+
+	entries, err := s.List("responses/fmt/2020-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Key != key || entries[0].ModTime.Before(since) {
+		t.Errorf("got entries %+v", entries)
+	}
+	waves, err := s.Subdirs("responses/fmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(waves) != 2 || waves[0] != "2020-05" || waves[1] != "2020-06" {
+		t.Errorf("got waves %v", waves)
+	}
+
+	if err := s.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(key); !cloudio.IsNotExist(err) {
+		t.Errorf("want not exist error - got %v", err)
+	}
+}
+
+func TestSQLiteTakeOver(t *testing.T) {
+
+	cloudio.SetStorageURL("mem://")
+	defer cloudio.SetStorageURL("")
+
+	s, err := OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	err = cloudio.WriteFile("logins.json", bytes.NewBufferString(`{"salt": "12345"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cloudio.WriteFile("responses/fmt/2020-05/10001.json", bytes.NewBufferString(`{}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// documents are taken over on first read
+	if _, err := s.Read("logins.json"); err != nil {
+		t.Fatal(err)
+	}
+	cloudio.Delete("logins.json")
+	if bts, err := s.Read("logins.json"); err != nil || string(bts) != `{"salt": "12345"}` {
+		t.Errorf("got %s - %v", bts, err)
+	}
+
+	// responses are not
+	if _, err := s.Read("responses/fmt/2020-05/10001.json"); !cloudio.IsNotExist(err) {
+		t.Errorf("want not exist error - got %v", err)
+	}
+	imported, err := Import(s, FilesT{}, "responses/fmt/2020-05")
+	if err != nil || imported != 1 {
+		t.Errorf("imported %v - %v", imported, err)
+	}
+	if imported, _ := Import(s, FilesT{}, "responses/fmt/2020-05"); imported != 0 {
+		t.Errorf("second import must skip existing - imported %v", imported)
+	}
+}
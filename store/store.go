@@ -0,0 +1,140 @@
+// Package store persists questionnaires and logins;
+// by default as JSON files in the cloudio bucket;
+// alternatively as rows of an SQLite database - see cfg database_url.
+//
+// Keys are the slash separated paths of the JSON files,
+// i.e. responses/fmt/2020-05/10001.json or logins.json;
+// thus switching the store changes none of the paths.
+package store
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zew/go-questionnaire/cloudio"
+)
+
+// Interface is implemented by the file store and the SQLite store
+type Interface interface {
+	Read(key string) ([]byte, error)
+	Write(key string, bts []byte) error
+	// WriteIf writes bts - if check() accepts the current contents;
+	// current is nil for a non-existing key; see cloudio.WriteFileIf()
+	WriteIf(key string, bts []byte, check func(current []byte) error) error
+	Delete(key string) error
+	// List returns the questionnaires in directory dir,
+	// i.e. responses/fmt/2020-05 - without reading their contents;
+	// SQLiteT only accepts directories of survey waves - responses/[survey]/[wave];
+	// FilesT lists the JSON files of any directory
+	List(dir string) ([]EntryT, error)
+	// Subdirs returns the names of the directories in dir,
+	// i.e. the waves in responses/fmt
+	Subdirs(dir string) ([]string, error)
+	Close() error
+}
+
+// EntryT is a questionnaire returned by List()
+type EntryT struct {
+	Key     string
+	ModTime time.Time
+	Size    int64
+}
+
+// responsesDir is the root for questionnaire JSON files;
+// duplicate of qst.BasePath() - cyclic dependencies
+const responsesDir = "responses"
+
+var current = struct {
+	sync.RWMutex
+	s Interface
+}{s: FilesT{}}
+
+func init() {
+	if u := os.Getenv("DATABASE_URL"); u != "" {
+		if err := Open(u); err != nil {
+			log.Fatalf("store from env DATABASE_URL: %v", err)
+		}
+	}
+}
+
+// Get returns the current store
+func Get() Interface {
+	current.RLock()
+	defer current.RUnlock()
+	return current.s
+}
+
+// Set replaces the current store; the previous one is closed
+func Set(s Interface) {
+	current.Lock()
+	prev := current.s
+	current.s = s
+	current.Unlock()
+	if err := prev.Close(); err != nil {
+		log.Printf("closing previous store: %v", err)
+	}
+}
+
+// Open sets the store from a URL;
+// empty for JSON files in the cloudio bucket;
+// sqlite://app-bucket/go-questionnaire.db for an SQLite database file.
+func Open(storeURL string) error {
+	switch {
+	case storeURL == "":
+		Set(FilesT{})
+	case strings.HasPrefix(storeURL, "sqlite://"):
+		s, err := OpenSQLite(strings.TrimPrefix(storeURL, "sqlite://"))
+		if err != nil {
+			return err
+		}
+		Set(s)
+	default:
+		return fmt.Errorf("unsupported store URL %q", storeURL)
+	}
+	log.Printf("store set to %q", storeURL)
+	return nil
+}
+
+// Close closes the current store; to be called on server shutdown
+func Close() error {
+	return Get().Close()
+}
+
+// ReadCached reads key through cloudio.ReadFileCached() -
+// if the files store is used; the database needs no cache.
+func ReadCached(key string) ([]byte, error) {
+	s := Get()
+	if fs, ok := s.(FilesT); ok {
+		return fs.readCached(key)
+	}
+	return s.Read(key)
+}
+
+// Import copies the questionnaires of dir - responses/[survey]/[wave] -
+// from src to dst; questionnaires already in dst are kept.
+func Import(dst, src Interface, dir string) (imported int, err error) {
+	entries, err := src.List(dir)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		if _, err := dst.Read(e.Key); err == nil {
+			continue
+		} else if !cloudio.IsNotExist(err) {
+			return imported, err
+		}
+		bts, err := src.Read(e.Key)
+		if err != nil {
+			return imported, err
+		}
+		if err := dst.Write(e.Key, bts); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, nil
+}
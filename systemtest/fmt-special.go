@@ -7,11 +7,10 @@ import (
 	"testing"
 
 	"github.com/zew/go-questionnaire/ctr"
-	"github.com/zew/go-questionnaire/lgn"
 	"github.com/zew/util"
 )
 
-func fmtSpecialTest(t *testing.T, urlMain string, sessCook *http.Cookie) {
+func fmtSpecialTest(t *testing.T, urlMain string, sessCook *http.Cookie, token string) {
 	//
 	//
 	// Post values and check the response
@@ -25,7 +24,7 @@ func fmtSpecialTest(t *testing.T, urlMain string, sessCook *http.Cookie) {
 			t.Logf("Goto first entry page ")
 			t.Logf("==================")
 			vals := url.Values{}
-			vals.Set("token", lgn.FormToken())
+			vals.Set("token", token)
 			vals.Set("page", "1")
 			t.Logf("POST requesting %v?%v", urlReq, vals.Encode())
 			_, err := util.Request("POST", urlReq, vals, []*http.Cookie{sessCook})
@@ -39,7 +38,7 @@ func fmtSpecialTest(t *testing.T, urlMain string, sessCook *http.Cookie) {
 		vals := url.Values{}
 		vals.Set("y0_ez", ctr.IncrementStr()) // Don't forget to reset; otherwise depending on generate.FMT() the result is not deterministic
 		vals.Set("y0_deu", ctr.IncrementStr())
-		vals.Set("token", lgn.FormToken())
+		vals.Set("token", token)
 		t.Logf("POST requesting %v?%v", urlReq, vals.Encode())
 		resp, err := util.Request("POST", urlReq, vals, []*http.Cookie{sessCook})
 		if err != nil {
@@ -86,7 +85,7 @@ func fmtSpecialTest(t *testing.T, urlMain string, sessCook *http.Cookie) {
 		t.Logf("Go back to page 0")
 		t.Logf("==================")
 		vals := url.Values{}
-		vals.Set("token", lgn.FormToken())
+		vals.Set("token", token)
 		vals.Set("page", "0")
 		t.Logf("POST requesting %v?%v", urlReq, vals.Encode())
 		_, err := util.Request("POST", urlReq, vals, []*http.Cookie{sessCook})
@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -28,7 +29,6 @@ import (
 	"github.com/zew/go-questionnaire/cfg"
 	"github.com/zew/go-questionnaire/cloudio"
 	"github.com/zew/go-questionnaire/ctr"
-	"github.com/zew/go-questionnaire/lgn"
 	"github.com/zew/go-questionnaire/qst"
 	"github.com/zew/util"
 )
@@ -75,8 +75,18 @@ func removeSystemtestJSON(t *testing.T, q *qst.QuestionnaireT) {
 	deleteHelper(t, q.FilePath1())
 }
 
+// scrapeFormToken extracts the form token from a served questionnaire page;
+// tokens are bound to the session - see lgn.FormToken()
+func scrapeFormToken(t *testing.T, page []byte) string {
+	m := regexp.MustCompile(`name="token" value="([^"]+)"`).FindSubmatch(page)
+	if m == nil {
+		t.Fatalf("no form token in response page")
+	}
+	return string(m[1])
+}
+
 func clientPageToServer(t *testing.T, clQ *qst.QuestionnaireT, idxPage int,
-	urlMain string, sessCook *http.Cookie) {
+	urlMain string, sessCook *http.Cookie, token string) {
 
 	ctr.Reset()
 
@@ -88,7 +98,7 @@ func clientPageToServer(t *testing.T, clQ *qst.QuestionnaireT, idxPage int,
 		if i1 != idxPage {
 			continue
 		}
-		vals.Set("token", lgn.FormToken())
+		vals.Set("token", token)
 		vals.Set("submitBtn", "next")
 
 		// condense radio inputs
@@ -115,7 +125,7 @@ func clientPageToServer(t *testing.T, clQ *qst.QuestionnaireT, idxPage int,
 			}
 		}
 	}
-	tmp := strings.Replace(vals.Encode(), "submitBtn=next&token="+lgn.FormToken(), "...", -1)
+	tmp := strings.Replace(vals.Encode(), "submitBtn=next&token="+url.QueryEscape(token), "...", -1)
 	t.Logf("POST requesting %v?%v", urlMain, util.UpTo(tmp, 60))
 	t1 := time.Now()
 	resp, err := util.Request("POST", urlMain, vals, []*http.Cookie{sessCook})
@@ -142,7 +152,7 @@ func clientPageToServer(t *testing.T, clQ *qst.QuestionnaireT, idxPage int,
 // qSrc is the basic survey template file for iterating pages and inputs
 // clQ  is a fake  user response file - recording the data requested to the test server
 //
-func FillQuestAndComparesServerResult(t *testing.T, qSrc *qst.QuestionnaireT, urlMain string, sessCook *http.Cookie) {
+func FillQuestAndComparesServerResult(t *testing.T, qSrc *qst.QuestionnaireT, urlMain string, sessCook *http.Cookie, token string) {
 
 	var clQ = &qst.QuestionnaireT{}
 	var err error
@@ -192,7 +202,7 @@ func FillQuestAndComparesServerResult(t *testing.T, qSrc *qst.QuestionnaireT, ur
 	//
 	// Doing load
 	for idx := range clQ.Pages {
-		clientPageToServer(t, clQ, idx, urlMain, sessCook)
+		clientPageToServer(t, clQ, idx, urlMain, sessCook, token)
 	}
 	clQ.CurrPage = len(clQ.Pages) - 2                  // last page does not get requested
 	clQ.Pages[len(clQ.Pages)-1].Finished = time.Time{} // last page finishing time is zero value
@@ -202,7 +212,7 @@ func FillQuestAndComparesServerResult(t *testing.T, qSrc *qst.QuestionnaireT, ur
 	//
 	// Comparing client questionnaire to server questionnaire
 	clQ.Split()
-	clQ.Save1(clientPth)
+	clQ.Save1Unconditionally(clientPth)
 	srvQ, err := qst.Load1(clQ.FilePath1()) // new from template
 	if err != nil {
 		t.Fatalf("Loading questionnaire from file caused error: %v", err)
@@ -239,6 +249,7 @@ func SimulateLoad(t *testing.T, q *qst.QuestionnaireT, loginURI, mobile string)
 	//
 	// Login and save session cookie
 	var sessCook *http.Cookie
+	var token string
 	{
 		t.Logf("\nGetting cookie for %v mobile %v", q.Survey.String(), mobile)
 		t.Logf("================================")
@@ -279,14 +290,15 @@ func SimulateLoad(t *testing.T, q *qst.QuestionnaireT, loginURI, mobile string)
 		} else {
 			t.Logf("Webpage reports: Login successful")
 		}
+		token = scrapeFormToken(t, respBytes)
 	}
 
 	ctr.Reset()
 
 	if q.Survey.Type == "fmt" {
-		fmtSpecialTest(t, urlMain, sessCook)
+		fmtSpecialTest(t, urlMain, sessCook, token)
 	}
 
-	FillQuestAndComparesServerResult(t, q, urlMain, sessCook)
+	FillQuestAndComparesServerResult(t, q, urlMain, sessCook, token)
 
 }
@@ -121,7 +121,7 @@ var fcNav = func(r *http.Request, q *qst.QuestionnaireT, SBookMarkURI string) te
 		}
 		cntr := 0
 		for i, lpP := range q.Pages {
-			if lpP.NoNavigation {
+			if lpP.NoNavigation || !q.IsPageVisible(i) {
 				continue
 			}
 			cntr++
@@ -186,11 +186,16 @@ var fcLogin = func(r *http.Request) template.HTML {
 	return template.HTML(l.User)
 }
 
+// fcFormToken returns a form token bound to the session
+var fcFormToken = func(r *http.Request) template.HTMLAttr {
+	return template.HTMLAttr(lgn.FormToken(r))
+}
+
 // staticTplFuncs cannot refer to funcs with nested in-package funcs - precise reason obscure
 var staticTplFuncs = template.FuncMap{
 	// "toHTML":          func(arg string) template.HTML { return template.HTML("Nogo - gosec violation") },
 	"toHTML":          func(arg string) template.HTML { return template.HTML(arg) },
-	"formToken":       func() template.HTMLAttr { return template.HTMLAttr(lgn.FormToken()) },
+	"formToken":       fcFormToken,                              // template usage {{ formToken .Req }}
 	"cfg":             func() *cfg.ConfigT { return cfg.Get() }, // access to config
 	"byKey":           fcByKey,                                  // template usage {{ index (   byKey "landing-page" ).Urls  0  }} - no prefix applied yet
 	"urlByKey":        fcURLByKey,                               // template usage {{        urlByKey "landing-page"            }} - prefix already applied
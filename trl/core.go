@@ -195,6 +195,14 @@ var coreTranslations = Map{
 		"it": "Per piacere correga gli errori sottostanti.",
 		"pl": "Popraw błędy wyświetlane poniżej",
 	},
+	"answers_updated_elsewhere": {
+		"de": "Ihre Antworten wurden inzwischen in einem anderen Fenster geändert. Die aktuelle Fassung wurde geladen.",
+		"en": "Your answers were updated in another window. The current version has been loaded.",
+		"es": "Sus respuestas fueron actualizadas en otra ventana. Se ha cargado la versión actual.",
+		"fr": "Vos réponses ont été modifiées dans une autre fenêtre. La version actuelle a été chargée.",
+		"it": "Le sue risposte sono state aggiornate in un'altra finestra. La versione attuale è stata caricata.",
+		"pl": "Twoje odpowiedzi zostały zaktualizowane w innym oknie. Załadowano aktualną wersję.",
+	},
 	"not_a_number": {
 		"de": "'%v' keine Zahl",
 		"en": "'%v' not a number",